@@ -1,21 +1,36 @@
 // Package main contains the entry point for running the S3EGO emulator.
 //
 // This file is intended primarily for development and local testing purposes.
-// It initializes the necessary components such as the in-memory database and
+// It initializes the necessary components such as the database and
 // starts the application server on the configured port.
 package main
 
 import (
+	"os"
+
 	"github.com/bonifacio-pedro/s3ego/internal/app"
 	"github.com/bonifacio-pedro/s3ego/internal/config"
 )
 
 // main initializes the database connection, creates the application instance,
 // and starts the HTTP server to handle incoming requests for the S3 emulator.
+//
+// Setting S3EGO_CREDENTIALS ("accessKey:secretKey,...") enables SigV4 authentication, and
+// setting S3EGO_DATA_SOURCE to a file path persists the data on disk instead of in memory,
+// with object data under S3EGO_BLOB_DIR (by default next to the database file).
+// S3EGO_LIFECYCLE_INTERVAL sets how often bucket lifecycle rules are applied (hourly by default).
 func main() {
-	db := config.ConfigDatabase()
+	appConfig := app.DefaultConfig()
+	appConfig.Credentials = config.CredentialsFromEnv()
+	appConfig.DataSource = os.Getenv("S3EGO_DATA_SOURCE")
+	appConfig.BlobDir = os.Getenv("S3EGO_BLOB_DIR")
+	appConfig.LifecycleInterval = config.LifecycleIntervalFromEnv(appConfig.LifecycleInterval)
+
+	db := config.ConfigDatabase(appConfig.DataSource)
 	defer db.Close()
 
-	newApp := app.NewApp(db)
+	blobs := config.ConfigBlobStore(db, appConfig.BlobDirectory())
+
+	newApp := app.NewApp(db, blobs, appConfig)
 	newApp.Run()
 }
@@ -9,23 +9,33 @@ import (
 	"github.com/bonifacio-pedro/s3ego/internal/domain"
 	domainImpl "github.com/bonifacio-pedro/s3ego/internal/domain/impl"
 	repoImpl "github.com/bonifacio-pedro/s3ego/internal/repository/impl"
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
 	"github.com/bonifacio-pedro/s3ego/internal/transport/rest"
 	"github.com/bonifacio-pedro/s3ego/internal/transport/routes"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/s3api"
 	"github.com/gin-gonic/gin"
 )
 
 // App represents the main application instance.
-// It holds the router and core services (BucketService and FileService).
+// It holds the router and core services (BucketService, FileService, MultipartService, LifecycleService and CORSService).
 type App struct {
-	Router        *gin.Engine
-	BucketService domain.BucketService
-	FileService   domain.FileService
+	Router           *gin.Engine
+	BucketService    domain.BucketService
+	FileService      domain.FileService
+	MultipartService domain.MultipartService
+	LifecycleService domain.LifecycleService
+	CORSService      domain.CORSService
+
+	lifecycleScheduler *lifecycleScheduler
 }
 
 // NewApp initializes the application, wiring together dependencies such as
 // repositories, services, handlers, and routes.
+// Metadata is kept in db and object data in blobs; the given Config tunes the behavior of the services.
+// Unless config.LifecycleInterval is not positive, the lifecycle rules of buckets are applied in the
+// background until Close is called.
 // It returns a fully constructed App ready to be run.
-func NewApp(db *sql.DB) *App {
+func NewApp(db *sql.DB, blobs storage.BlobStore, config Config) *App {
 	// Set Gin to Release mode (no debug output)
 	gin.SetMode(gin.ReleaseMode)
 
@@ -35,24 +45,45 @@ func NewApp(db *sql.DB) *App {
 	// Repositories
 	bucketRepository := repoImpl.NewBucketRepository(db)
 	fileRepository := repoImpl.NewFileRepository(db)
+	multipartRepository := repoImpl.NewMultipartRepository(db)
+	lifecycleRepository := repoImpl.NewLifecycleRepository(db)
+	corsRepository := repoImpl.NewCORSRepository(db)
 
 	// Services
-	bucketService := domainImpl.NewBucketService(bucketRepository)
-	fileService := domainImpl.NewFileService(fileRepository, bucketRepository)
+	bucketService := domainImpl.NewBucketService(bucketRepository, blobs, config.Clock)
+	fileService := domainImpl.NewFileService(fileRepository, bucketRepository, blobs, config.Clock)
+	multipartService := domainImpl.NewMultipartService(multipartRepository, fileRepository, bucketRepository, blobs, config.MinPartSize, config.Clock)
+	lifecycleService := domainImpl.NewLifecycleService(lifecycleRepository, bucketRepository, fileService, multipartService, config.Clock)
+	corsService := domainImpl.NewCORSService(corsRepository, bucketRepository)
 
 	// Handlers (transport layer)
 	bucketHandler := rest.NewBucketHandler(bucketService)
 	fileHandler := rest.NewFileHandler(fileService)
+	s3BucketHandler := s3api.NewBucketHandler(bucketService)
+	s3ObjectHandler := s3api.NewObjectHandler(fileService)
+	s3MultipartHandler := s3api.NewMultipartHandler(multipartService)
+	s3LifecycleHandler := s3api.NewLifecycleHandler(lifecycleService)
+	s3TaggingHandler := s3api.NewTaggingHandler(bucketService, fileService)
+	s3CORSHandler := s3api.NewCORSHandler(corsService)
 
 	// Routes
-	router := routes.NewRouter(rg, bucketHandler, fileHandler)
+	router := routes.NewRouter(rg, bucketHandler, fileHandler, s3BucketHandler, s3ObjectHandler, s3MultipartHandler, s3LifecycleHandler, s3TaggingHandler, s3CORSHandler, corsService, config.Credentials, config.Clock)
 	router.RegisterRoutes()
 
-	return &App{
-		Router:        rg,
-		BucketService: bucketService,
-		FileService:   fileService,
+	newApp := &App{
+		Router:           rg,
+		BucketService:    bucketService,
+		FileService:      fileService,
+		MultipartService: multipartService,
+		LifecycleService: lifecycleService,
+		CORSService:      corsService,
+	}
+
+	if config.LifecycleInterval > 0 {
+		newApp.lifecycleScheduler = startLifecycleScheduler(lifecycleService, config.Clock, config.LifecycleInterval)
 	}
+
+	return newApp
 }
 
 // Run starts the HTTP server on port 7777.
@@ -62,3 +93,11 @@ func (a *App) Run() {
 		log.Panic(err)
 	}
 }
+
+// Close stops applying the lifecycle rules of buckets in the background.
+func (a *App) Close() {
+	if a.lifecycleScheduler != nil {
+		a.lifecycleScheduler.Stop()
+		a.lifecycleScheduler = nil
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+)
+
+// Config holds the tunable settings of the application.
+type Config struct {
+	// MinPartSize is the minimum size in bytes of every part of a multipart upload except the last one.
+	MinPartSize int64
+
+	// Credentials maps access key IDs to secret access keys accepted for AWS Signature Version 4
+	// authentication of the S3 API. When empty, requests are not authenticated.
+	Credentials map[string]string
+
+	// DataSource is the path of the SQLite database file passed to config.ConfigDatabase.
+	// When empty, the database is kept in memory.
+	DataSource string
+
+	// BlobDir is the directory holding the data of objects and parts, passed to config.ConfigBlobStore.
+	// When empty, it defaults to a directory next to the DataSource file, or to memory without one.
+	BlobDir string
+
+	// Clock is the source of the current time: it stamps the CreatedAt and LastModified times of buckets,
+	// objects and multipart uploads and the Date header of S3 API responses, checks the expiry of signed
+	// requests, presigned URLs and POST policies, and drives the lifecycle passes and the expiry of their
+	// rules. DefaultConfig uses the wall clock.
+	Clock clock.Clock
+
+	// LifecycleInterval is how often, measured on Clock, the lifecycle rules of every bucket are applied.
+	// When zero or negative, the rules are only applied by calls to LifecycleService.Apply.
+	LifecycleInterval time.Duration
+}
+
+// DefaultConfig returns the configuration matching the limits of Amazon S3.
+func DefaultConfig() Config {
+	return Config{
+		MinPartSize:       5 * 1024 * 1024,
+		Clock:             clock.System(),
+		LifecycleInterval: time.Hour,
+	}
+}
+
+// BlobDirectory returns the directory the blob store keeps its data in: BlobDir if set, otherwise
+// "<DataSource>.blobs" when the database is stored on disk, or "" to keep blobs in memory.
+func (c Config) BlobDirectory() string {
+	if c.BlobDir != "" {
+		return c.BlobDir
+	}
+
+	if c.DataSource == "" || c.DataSource == ":memory:" {
+		return ""
+	}
+
+	return c.DataSource + ".blobs"
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+)
+
+// lifecycleScheduler applies the lifecycle rules of every bucket once every interval measured on its
+// clock. With the system clock the passes run in the background; a fake clock moved forward runs the
+// pass it triggers before Advance returns, so its effects are visible right after.
+type lifecycleScheduler struct {
+	service  domain.LifecycleService
+	clock    clock.Clock
+	interval time.Duration
+
+	mu      sync.Mutex // Held during a pass, so Stop waits for it
+	timer   clock.Timer
+	stopped bool
+}
+
+// startLifecycleScheduler starts applying the lifecycle rules of service every interval of clock.
+// Returns the running scheduler, which must be stopped with Stop.
+func startLifecycleScheduler(service domain.LifecycleService, clock clock.Clock, interval time.Duration) *lifecycleScheduler {
+	scheduler := &lifecycleScheduler{
+		service:  service,
+		clock:    clock,
+		interval: interval,
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	scheduler.timer = clock.AfterFunc(interval, scheduler.run)
+	return scheduler
+}
+
+// run applies the lifecycle rules and schedules the next pass, unless the scheduler is stopped.
+// A failed pass is logged and retried at the next interval.
+func (s *lifecycleScheduler) run() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return
+	}
+
+	if _, err := s.service.Apply(); err != nil {
+		log.Printf("[S3EGO] Warning: Failed to apply lifecycle rules: %s", err)
+	}
+	s.timer = s.clock.AfterFunc(s.interval, s.run)
+}
+
+// Stop stops the scheduler, cancelling its next pass and waiting for a pass in progress to finish.
+func (s *lifecycleScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	s.timer.Stop()
+}
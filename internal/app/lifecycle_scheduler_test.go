@@ -0,0 +1,48 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+// countingLifecycle is a LifecycleService counting the passes applied.
+type countingLifecycle struct {
+	domain.LifecycleService
+	passes int
+}
+
+func (l *countingLifecycle) Apply() (model.LifecycleRun, error) {
+	l.passes++
+	return model.LifecycleRun{}, nil
+}
+
+func TestLifecycleSchedulerRunsOnAdvance(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	service := &countingLifecycle{}
+	scheduler := startLifecycleScheduler(service, fake, time.Hour)
+
+	fake.Advance(59 * time.Minute)
+	if service.passes != 0 {
+		t.Fatalf("passes = %d before the interval elapsed, want 0", service.passes)
+	}
+
+	// The pass has run when Advance returns, without waiting for another goroutine.
+	fake.Advance(time.Minute)
+	if service.passes != 1 {
+		t.Fatalf("passes = %d once the interval elapsed, want 1", service.passes)
+	}
+	fake.Advance(time.Hour)
+	if service.passes != 2 {
+		t.Fatalf("passes = %d after the next interval, want 2", service.passes)
+	}
+
+	scheduler.Stop()
+	fake.Advance(24 * time.Hour)
+	if service.passes != 2 {
+		t.Errorf("passes = %d after Stop(), want 2", service.passes)
+	}
+}
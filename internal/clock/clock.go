@@ -0,0 +1,147 @@
+// Package clock provides the source of the current time used by the emulator, so tests can
+// replace the wall clock with a fake one they set and advance.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time and calls functions once durations have elapsed.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc calls f once d has elapsed. The returned Timer cancels the call.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call of Clock.AfterFunc.
+type Timer interface {
+	// Stop cancels the call, reporting whether it was still pending.
+	Stop() bool
+}
+
+// systemClock is the Clock reading the wall clock of the machine.
+type systemClock struct{}
+
+// System returns the Clock reading the wall clock, used unless another one is configured.
+func System() Clock {
+	return systemClock{}
+}
+
+// Now returns the current local time, as time.Now does.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc calls f in its own goroutine once d has elapsed in real time, as time.AfterFunc does.
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Fake is a Clock whose time only moves when Set or Advance is called, which call the functions
+// scheduled with AfterFunc whose duration has then elapsed before returning, so their effects are
+// visible to the caller. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending AfterFunc call of a Fake clock.
+type waiter struct {
+	clock    *Fake
+	deadline time.Time
+	f        func()
+}
+
+// NewFake returns a Fake clock stopped at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time the clock was last set to.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// AfterFunc schedules fn to be called once the clock is moved at least d forward, by the goroutine
+// moving it. fn is called immediately if d is not positive.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	w := &waiter{clock: f, f: fn}
+	if d <= 0 {
+		fn()
+		return w
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.deadline = f.now.Add(d)
+	f.waiters = append(f.waiters, w)
+	return w
+}
+
+// Advance moves the clock forward by d, as Set does.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	due := f.set(f.now.Add(d))
+	f.mu.Unlock()
+
+	call(due)
+}
+
+// Set moves the clock to now, which may be in the past of its current time, then calls the
+// functions whose deadline has passed, in the order they were scheduled.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	due := f.set(now)
+	f.mu.Unlock()
+
+	call(due)
+}
+
+// set moves the clock to now and removes the waiters whose deadline has passed, returning them.
+// The caller must hold the lock.
+func (f *Fake) set(now time.Time) []*waiter {
+	f.now = now
+
+	var due []*waiter
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if now.Before(w.deadline) {
+			pending = append(pending, w)
+			continue
+		}
+		due = append(due, w)
+	}
+	f.waiters = pending
+	return due
+}
+
+// call calls the functions of the due waiters. It runs without the lock of their clock, as the
+// functions usually read the clock or schedule another call.
+func call(due []*waiter) {
+	for _, w := range due {
+		w.f()
+	}
+}
+
+// Stop removes the waiter from its clock, reporting whether it was still pending.
+func (w *waiter) Stop() bool {
+	f := w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
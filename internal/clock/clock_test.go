@@ -0,0 +1,86 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func TestFakeAfterFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		advance    time.Duration
+		wantCalled bool
+	}{
+		{name: "before the deadline", delay: time.Hour, advance: 59 * time.Minute},
+		{name: "at the deadline", delay: time.Hour, advance: time.Hour, wantCalled: true},
+		{name: "past the deadline", delay: time.Hour, advance: 72 * time.Hour, wantCalled: true},
+		{name: "no delay", delay: 0, advance: 0, wantCalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFake(start)
+			var calledAt time.Time
+			called := false
+			clock.AfterFunc(tt.delay, func() {
+				called = true
+				calledAt = clock.Now()
+			})
+
+			clock.Advance(tt.advance)
+
+			if called != tt.wantCalled {
+				t.Fatalf("called = %v after Advance(%v), want %v", called, tt.advance, tt.wantCalled)
+			}
+			if called && !calledAt.Equal(start.Add(tt.advance)) {
+				t.Errorf("Now() in the call = %v, want %v", calledAt, start.Add(tt.advance))
+			}
+		})
+	}
+}
+
+func TestFakeTimerStop(t *testing.T) {
+	clock := NewFake(start)
+	called := false
+	timer := clock.AfterFunc(time.Hour, func() { called = true })
+
+	if !timer.Stop() {
+		t.Fatal("Stop() = false, want true for a pending call")
+	}
+	if len(clock.waiters) != 0 {
+		t.Errorf("waiters = %d after Stop(), want 0", len(clock.waiters))
+	}
+
+	clock.Advance(2 * time.Hour)
+	if called {
+		t.Error("stopped call was made")
+	}
+	if timer.Stop() {
+		t.Error("second Stop() = true, want false")
+	}
+}
+
+func TestFakeAfterFuncReschedule(t *testing.T) {
+	clock := NewFake(start)
+	calls := 0
+	var tick func()
+	tick = func() {
+		calls++
+		clock.AfterFunc(time.Hour, tick)
+	}
+	clock.AfterFunc(time.Hour, tick)
+
+	clock.Advance(time.Hour)
+	clock.Advance(30 * time.Minute)
+	clock.Set(start.Add(2 * time.Hour))
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if len(clock.waiters) != 1 {
+		t.Errorf("waiters = %d, want the next call only", len(clock.waiters))
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
+	storageImpl "github.com/bonifacio-pedro/s3ego/internal/storage/impl"
+)
+
+// ConfigBlobStore creates the BlobStore holding the data of objects and multipart upload parts,
+// and moves into it any data still stored inline in the database by earlier versions of S3EGO.
+//
+// dir is the directory the blobs are written to; when empty, blobs are kept in memory.
+//
+// If the blob store cannot be created or the inline data cannot be moved,
+// the function will log the error and terminate the application.
+func ConfigBlobStore(db *sql.DB, dir string) storage.BlobStore {
+	var blobs storage.BlobStore
+	if dir == "" {
+		blobs = storageImpl.NewMemoryBlobStore()
+		log.Println("[S3EGO] Started in-memory blob store")
+	} else {
+		var err error
+		blobs, err = storageImpl.NewFileSystemBlobStore(dir)
+		if err != nil {
+			log.Fatalf("[S3EGO] Failed to open blob store: %s", err)
+		}
+		log.Printf("[S3EGO] Started blob store at %s", dir)
+	}
+
+	for _, table := range []string{"files", "multipart_parts"} {
+		moved, err := moveInlineData(db, blobs, table)
+		if err != nil {
+			log.Fatalf("[S3EGO] Failed to move %s data to the blob store: %s", table, err)
+		}
+		if moved > 0 {
+			log.Printf("[S3EGO] Moved the data of %d %s rows to the blob store", moved, table)
+		}
+	}
+
+	return blobs
+}
+
+// moveInlineData stores the data column of every row of table without a blob reference in blobs,
+// then sets the reference and clears the inline copy.
+// Returns the number of rows moved, or an error if reading, storing or updating fails.
+func moveInlineData(db *sql.DB, blobs storage.BlobStore, table string) (int, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT id FROM %s WHERE blob_ref IS NULL", table))
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		var data []byte
+		if err := db.QueryRow(fmt.Sprintf("SELECT data FROM %s WHERE id = ?", table), id).Scan(&data); err != nil {
+			return 0, err
+		}
+
+		blob, err := blobs.Put(bytes.NewReader(data))
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = db.Exec(fmt.Sprintf("UPDATE %s SET blob_ref = ?, data = NULL WHERE id = ?", table), blob.Ref, id)
+		if err != nil {
+			blobs.Delete(blob.Ref)
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// CredentialsFromEnv reads the access key ID and secret access key pairs accepted by the S3 API
+// from the S3EGO_CREDENTIALS environment variable, formatted as "accessKey:secretKey,...".
+//
+// Returns nil, which disables authentication, if the variable is unset or empty.
+func CredentialsFromEnv() map[string]string {
+	value := os.Getenv("S3EGO_CREDENTIALS")
+	if value == "" {
+		return nil
+	}
+
+	credentials := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		accessKey, secretKey, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || accessKey == "" || secretKey == "" {
+			log.Fatalf("[S3EGO] Invalid S3EGO_CREDENTIALS entry %q, expected accessKey:secretKey", pair)
+		}
+		credentials[accessKey] = secretKey
+	}
+	log.Printf("[S3EGO] SigV4 authentication enabled for %d access key(s)", len(credentials))
+
+	return credentials
+}
@@ -1,79 +1,70 @@
 // Package config provides configuration utilities for the S3EGO project,
-// including initialization of the SQLite in-memory database schema.
+// including initialization and migration of the SQLite database schema.
 package config
 
 import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 
 	_ "modernc.org/sqlite" // SQLite driver for database/sql
 )
 
-// ConfigDatabase initializes an in-memory SQLite database,
-// sets up the necessary schema for buckets and files,
-// and returns the active *sql.DB connection.
+// memoryDatabases counts the in-memory databases opened by the process, naming each one uniquely.
+var memoryDatabases atomic.Int64
+
+// memoryDataSource returns the DSN of a new in-memory database, used when no data source is configured.
+// Every emulator gets its own database, as its blobs are kept in a memory store of its own; the
+// shared cache lets the connections of its pool see the same database.
+func memoryDataSource() string {
+	return fmt.Sprintf("file:s3ego-%d?mode=memory&cache=shared&_pragma=foreign_keys(1)", memoryDatabases.Add(1))
+}
+
+// ConfigDatabase opens the SQLite database of the emulator, migrates its schema
+// to the latest version and returns the active *sql.DB connection.
 //
-// If the database cannot be initialized or any schema fails to create,
+// dataSource is the path of the database file on disk, created if missing and opened in
+// WAL mode so a long-running instance keeps its data across restarts. When dataSource is
+// empty or ":memory:", the database lives in memory, private to the returned connection pool,
+// and is lost when it is closed. Foreign keys are enforced on every connection of the pool.
+//
+// If the database cannot be opened or any migration fails,
 // the function will log the error and terminate the application.
-func ConfigDatabase() *sql.DB {
-	// Open in-memory SQLite DB with shared cache
-	db, err := sql.Open("sqlite", "file::memory:?mode=memory&cache=shared")
-	if err != nil {
-		log.Fatal(fmt.Sprintf("[S3EGO] Failed to open database: %s", err))
-	}
-	log.Println("[S3EGO] Started Database")
+func ConfigDatabase(dataSource string) *sql.DB {
+	inMemory := dataSource == "" || dataSource == ":memory:"
 
-	// Create buckets table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS buckets (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT UNIQUE NOT NULL,
-			url TEXT UNIQUE,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	if err != nil {
-		log.Fatal(fmt.Sprintf("[S3EGO] Failed to create buckets table: %s", err))
+	var dsn string
+	if inMemory {
+		dsn = memoryDataSource()
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dataSource), 0o755); err != nil {
+			log.Fatalf("[S3EGO] Failed to create database directory: %s", err)
+		}
+		// WAL lets readers run alongside the single writer, and immediate transactions
+		// wait on busy_timeout for the write lock instead of failing on upgrade.
+		dsn = fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(1)&_txlock=immediate", dataSource)
 	}
-	log.Println("[S3EGO] Buckets table initialized")
 
-	// Create files table with S3 metadata support
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS files (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			key TEXT NOT NULL,
-			data BLOB,
-			bucket_id INTEGER NOT NULL,
-			etag TEXT NOT NULL,
-			content_type TEXT DEFAULT 'application/octet-stream',
-			size INTEGER DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			last_modified DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
-			UNIQUE(bucket_id, key)
-		);
-	`)
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[S3EGO] Failed to create files table: %s", err))
+		log.Fatalf("[S3EGO] Failed to open database: %s", err)
+	}
+	if err := db.Ping(); err != nil {
+		log.Fatalf("[S3EGO] Failed to open database: %s", err)
 	}
-	log.Println("[S3EGO] Files table initialized")
 
-	// Create indexes for better performance
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_files_bucket_key ON files(bucket_id, key);",
-		"CREATE INDEX IF NOT EXISTS idx_files_etag ON files(etag);",
-		"CREATE INDEX IF NOT EXISTS idx_buckets_name ON buckets(name);",
-		"CREATE INDEX IF NOT EXISTS idx_files_last_modified ON files(last_modified);",
+	if inMemory {
+		log.Println("[S3EGO] Started in-memory database")
+	} else {
+		log.Printf("[S3EGO] Started database at %s", dataSource)
 	}
 
-	for _, indexSQL := range indexes {
-		_, err = db.Exec(indexSQL)
-		if err != nil {
-			log.Printf("[S3EGO] Warning: Failed to create index: %s", err)
-		}
+	if err := migrate(db); err != nil {
+		log.Fatalf("[S3EGO] Failed to migrate database: %s", err)
 	}
-	log.Println("[S3EGO] Database indexes created")
-	
+
 	return db
 }
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// LifecycleIntervalFromEnv reads how often the lifecycle rules of buckets are applied from the
+// S3EGO_LIFECYCLE_INTERVAL environment variable, a Go duration such as "10m" or "1h".
+// "0" disables the background expiration.
+//
+// Returns defaultInterval if the variable is unset or empty.
+func LifecycleIntervalFromEnv(defaultInterval time.Duration) time.Duration {
+	value := os.Getenv("S3EGO_LIFECYCLE_INTERVAL")
+	if value == "" {
+		return defaultInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("[S3EGO] Invalid S3EGO_LIFECYCLE_INTERVAL %q, expected a duration such as 1h", value)
+	}
+	if interval > 0 {
+		log.Printf("[S3EGO] Applying bucket lifecycle rules every %s", interval)
+	}
+
+	return interval
+}
@@ -0,0 +1,321 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration is a versioned schema change, applied once and recorded in the schema_migrations table.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations lists every schema change in order. Applied migrations must never be edited;
+// schema changes are made by appending a new migration with the next version.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create buckets and files tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS buckets (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT UNIQUE NOT NULL,
+				url TEXT UNIQUE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);`,
+			`CREATE TABLE IF NOT EXISTS files (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				key TEXT NOT NULL,
+				data BLOB,
+				bucket_id INTEGER NOT NULL,
+				etag TEXT NOT NULL,
+				content_type TEXT DEFAULT 'application/octet-stream',
+				size INTEGER DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				last_modified DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
+				UNIQUE(bucket_id, key)
+			);`,
+			"CREATE INDEX IF NOT EXISTS idx_files_bucket_key ON files(bucket_id, key);",
+			"CREATE INDEX IF NOT EXISTS idx_files_etag ON files(etag);",
+			"CREATE INDEX IF NOT EXISTS idx_buckets_name ON buckets(name);",
+			"CREATE INDEX IF NOT EXISTS idx_files_last_modified ON files(last_modified);",
+		},
+	},
+	{
+		version:     2,
+		description: "create multipart upload tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS multipart_uploads (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				upload_id TEXT UNIQUE NOT NULL,
+				bucket_id INTEGER NOT NULL,
+				key TEXT NOT NULL,
+				content_type TEXT DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE
+			);`,
+			`CREATE TABLE IF NOT EXISTS multipart_parts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				multipart_id INTEGER NOT NULL,
+				part_number INTEGER NOT NULL,
+				data BLOB,
+				etag TEXT NOT NULL,
+				size INTEGER DEFAULT 0,
+				last_modified DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(multipart_id) REFERENCES multipart_uploads(id) ON DELETE CASCADE,
+				UNIQUE(multipart_id, part_number)
+			);`,
+			"CREATE INDEX IF NOT EXISTS idx_multipart_uploads_bucket_key ON multipart_uploads(bucket_id, key);",
+		},
+	},
+	{
+		version:     3,
+		description: "reference object and part data in the blob store",
+		statements: []string{
+			"ALTER TABLE files ADD COLUMN blob_ref TEXT;",
+			"ALTER TABLE multipart_parts ADD COLUMN blob_ref TEXT;",
+			"CREATE INDEX IF NOT EXISTS idx_files_blob_ref ON files(blob_ref);",
+			"CREATE INDEX IF NOT EXISTS idx_multipart_parts_blob_ref ON multipart_parts(blob_ref);",
+		},
+	},
+	{
+		version:     4,
+		description: "record the part sizes of multipart objects",
+		statements: []string{
+			"ALTER TABLE files ADD COLUMN part_sizes TEXT DEFAULT '';",
+		},
+	},
+	{
+		version:     5,
+		description: "create object metadata tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS file_metadata (
+				file_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE,
+				PRIMARY KEY(file_id, name)
+			);`,
+			`CREATE TABLE IF NOT EXISTS multipart_metadata (
+				multipart_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				FOREIGN KEY(multipart_id) REFERENCES multipart_uploads(id) ON DELETE CASCADE,
+				PRIMARY KEY(multipart_id, name)
+			);`,
+		},
+	},
+	{
+		version:     6,
+		description: "strip the bucket name prefix from object keys",
+		statements: []string{
+			// Keys used to be stored as "bucketName/key". The prefixed files are first moved to a
+			// negated bucket ID, so stripping their keys cannot collide with a key not yet stripped.
+			`UPDATE files SET bucket_id = -bucket_id
+			WHERE EXISTS (
+				SELECT 1 FROM buckets b
+				WHERE b.id = files.bucket_id AND substr(files.key, 1, length(b.name) + 1) = b.name || '/'
+			);`,
+			`UPDATE files
+			SET bucket_id = -bucket_id,
+				key = substr(key, length((SELECT name FROM buckets b WHERE b.id = -files.bucket_id)) + 2)
+			WHERE bucket_id < 0;`,
+		},
+	},
+	{
+		version:     7,
+		description: "keep multiple versions of each object",
+		statements: []string{
+			"ALTER TABLE buckets ADD COLUMN versioning TEXT DEFAULT '';",
+			// SQLite cannot drop the UNIQUE(bucket_id, key) constraint, so the files table is rebuilt,
+			// keeping the file IDs referenced by file_metadata. Existing files become "null" versions.
+			`CREATE TABLE files_versioned (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				key TEXT NOT NULL,
+				data BLOB,
+				bucket_id INTEGER NOT NULL,
+				etag TEXT NOT NULL,
+				content_type TEXT DEFAULT 'application/octet-stream',
+				size INTEGER DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				last_modified DATETIME DEFAULT CURRENT_TIMESTAMP,
+				blob_ref TEXT,
+				part_sizes TEXT DEFAULT '',
+				version_id TEXT NOT NULL DEFAULT 'null',
+				is_latest INTEGER NOT NULL DEFAULT 1,
+				delete_marker INTEGER NOT NULL DEFAULT 0,
+				FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
+				UNIQUE(bucket_id, key, version_id)
+			);`,
+			`INSERT INTO files_versioned (
+				id, key, data, bucket_id, etag, content_type, size, created_at, last_modified, blob_ref, part_sizes
+			)
+			SELECT id, key, data, bucket_id, etag, content_type, size, created_at, last_modified, blob_ref, part_sizes
+			FROM files;`,
+			"DROP TABLE files;",
+			"ALTER TABLE files_versioned RENAME TO files;",
+			// The versions of a key are listed newest (highest ID) first, and only one of them is the latest.
+			"CREATE INDEX IF NOT EXISTS idx_files_bucket_key ON files(bucket_id, key, id DESC);",
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_files_latest ON files(bucket_id, key) WHERE is_latest = 1;",
+			"CREATE INDEX IF NOT EXISTS idx_files_etag ON files(etag);",
+			"CREATE INDEX IF NOT EXISTS idx_files_last_modified ON files(last_modified);",
+			"CREATE INDEX IF NOT EXISTS idx_files_blob_ref ON files(blob_ref);",
+		},
+	},
+	{
+		version:     8,
+		description: "create bucket lifecycle rule tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS lifecycle_rules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				bucket_id INTEGER NOT NULL,
+				rule_id TEXT NOT NULL,
+				status TEXT NOT NULL,
+				prefix TEXT DEFAULT '',
+				expiration_days INTEGER DEFAULT 0,
+				expiration_date DATETIME,
+				expired_object_delete_marker INTEGER DEFAULT 0,
+				noncurrent_days INTEGER DEFAULT 0,
+				newer_noncurrent_versions INTEGER DEFAULT 0,
+				abort_incomplete_upload_days INTEGER DEFAULT 0,
+				FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
+				UNIQUE(bucket_id, rule_id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS lifecycle_rule_tags (
+				rule_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				FOREIGN KEY(rule_id) REFERENCES lifecycle_rules(id) ON DELETE CASCADE,
+				PRIMARY KEY(rule_id, name)
+			);`,
+		},
+	},
+	{
+		version:     9,
+		description: "create object and bucket tag tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS file_tags (
+				file_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				FOREIGN KEY(file_id) REFERENCES files(id) ON DELETE CASCADE,
+				PRIMARY KEY(file_id, name)
+			);`,
+			`CREATE TABLE IF NOT EXISTS bucket_tags (
+				bucket_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE,
+				PRIMARY KEY(bucket_id, name)
+			);`,
+		},
+	},
+	{
+		version:     10,
+		description: "create bucket CORS rule table",
+		statements: []string{
+			// The origins, methods and headers of a rule are stored as newline-separated lists.
+			`CREATE TABLE IF NOT EXISTS cors_rules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				bucket_id INTEGER NOT NULL,
+				rule_id TEXT DEFAULT '',
+				allowed_origins TEXT NOT NULL,
+				allowed_methods TEXT NOT NULL,
+				allowed_headers TEXT DEFAULT '',
+				expose_headers TEXT DEFAULT '',
+				max_age_seconds INTEGER DEFAULT 0,
+				FOREIGN KEY(bucket_id) REFERENCES buckets(id) ON DELETE CASCADE
+			);`,
+			"CREATE INDEX IF NOT EXISTS idx_cors_rules_bucket ON cors_rules(bucket_id, id);",
+		},
+	},
+}
+
+// migrate applies, in order and each in its own transaction, every migration
+// newer than the schema version recorded in the database.
+//
+// Migrations run on a single connection with foreign keys disabled, as SQLite requires to rebuild a
+// table: dropping the old files table would otherwise delete the rows referencing it in cascade.
+//
+// Returns an error if the schema version cannot be read or a migration fails,
+// in which case that migration is rolled back.
+func migrate(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+
+	if err := applyMigrations(ctx, conn, migrations); err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+		return fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+
+	return nil
+}
+
+// applyMigrations applies on conn, in order, the given migrations newer than the recorded schema version.
+func applyMigrations(ctx context.Context, conn *sql.Conn, migrations []migration) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.description, err)
+		}
+		log.Printf("[S3EGO] Applied migration %d: %s", m.version, m.description)
+	}
+
+	return nil
+}
+
+// applyMigration runs the statements of a migration and records its version in a single transaction.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range m.statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, description) VALUES (?, ?)", m.version, m.description); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
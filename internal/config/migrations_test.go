@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateKeepsReferencingRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s3ego.db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A database at version 5, holding an object with metadata under the old "bucketName/key" keys.
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyMigrations(ctx, conn, migrations[:5]); err != nil {
+		t.Fatalf("applyMigrations() unexpected error: %v", err)
+	}
+	conn.Close()
+
+	for _, statement := range []string{
+		"INSERT INTO buckets (id, name) VALUES (1, 'bkt')",
+		"INSERT INTO files (id, key, bucket_id, etag) VALUES (1, 'bkt/a.txt', 1, 'etag')",
+		"INSERT INTO file_metadata (file_id, name, value) VALUES (1, 'x-amz-meta-owner', 'me')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate() unexpected error: %v", err)
+	}
+
+	var key, value string
+	err = db.QueryRow("SELECT f.key, m.value FROM files f JOIN file_metadata m ON m.file_id = f.id").Scan(&key, &value)
+	if err != nil {
+		t.Fatalf("reading the migrated object: %v", err)
+	}
+	if key != "a.txt" || value != "me" {
+		t.Errorf("migrated object = %q with metadata %q, want %q with %q", key, value, "a.txt", "me")
+	}
+
+	var enabled bool
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil || !enabled {
+		t.Errorf("foreign_keys = %v, %v after migrate(), want enabled", enabled, err)
+	}
+}
+
+func TestForeignKeysEnforced(t *testing.T) {
+	db := ConfigDatabase("")
+	defer db.Close()
+
+	if _, err := db.Exec("INSERT INTO files (key, bucket_id, etag) VALUES ('a.txt', 42, 'etag')"); err == nil {
+		t.Error("inserting a file of an unknown bucket succeeded, want a foreign key error")
+	}
+
+	if _, err := db.Exec("INSERT INTO buckets (id, name) VALUES (1, 'bkt')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO files (key, bucket_id, etag) VALUES ('a.txt', 1, 'etag')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM buckets WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var files int
+	if err := db.QueryRow("SELECT COUNT(*) FROM files").Scan(&files); err != nil || files != 0 {
+		t.Errorf("files left after deleting their bucket = %d, %v, want 0", files, err)
+	}
+}
@@ -1,8 +1,20 @@
 package domain
 
+import "github.com/bonifacio-pedro/s3ego/internal/model"
+
 // BucketService interface for decoupling code
 type BucketService interface {
 	New(name string) (string, error)
+	Exists(bucketName string) (bool, error)
+	List(query model.BucketQuery) (*model.BucketListing, error)
 	FindAllFiles(bucketName string) (*[]string, error)
+	ListFiles(bucketName string, query model.ListQuery) (*model.Listing, error)
+	ListVersions(bucketName string, query model.VersionQuery) (*model.VersionListing, error)
+	SetVersioning(bucketName string, status model.VersioningStatus) error
+	GetVersioning(bucketName string) (model.VersioningStatus, error)
+	GetTagging(bucketName string) (map[string]string, error)
+	PutTagging(bucketName string, tags map[string]string) error
+	RemoveTagging(bucketName string) error
 	Remove(bucketName string) error
+	RemoveEmpty(bucketName string) error
 }
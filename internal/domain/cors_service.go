@@ -0,0 +1,11 @@
+package domain
+
+import "github.com/bonifacio-pedro/s3ego/internal/model"
+
+// CORSService interface for decoupling code
+type CORSService interface {
+	Put(bucketName string, rules []model.CORSRule) error
+	Get(bucketName string) ([]model.CORSRule, error)
+	Remove(bucketName string) error
+	Match(bucketName string, origin string, method string, headers []string) (*model.CORSRule, error)
+}
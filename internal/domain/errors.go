@@ -0,0 +1,73 @@
+package domain
+
+import "fmt"
+
+// Error is a business error identified by an S3 error code.
+// The transport layer maps the code to the HTTP status and error document S3 would return.
+type Error struct {
+	Code    string
+	Message string
+}
+
+// Error returns the human readable message of the error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a domain Error with the same code,
+// so errors.Is(err, domain.ErrNoSuchKey) works regardless of the message.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of the error with a custom formatted message.
+func (e *Error) WithMessage(format string, args ...any) *Error {
+	return &Error{Code: e.Code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Errors returned by the domain services.
+var (
+	ErrNoSuchBucket                 = &Error{Code: "NoSuchBucket", Message: "The specified bucket does not exist"}
+	ErrNoSuchKey                    = &Error{Code: "NoSuchKey", Message: "The specified key does not exist."}
+	ErrBucketAlreadyExists          = &Error{Code: "BucketAlreadyExists", Message: "The requested bucket name is not available."}
+	ErrBucketNotEmpty               = &Error{Code: "BucketNotEmpty", Message: "The bucket you tried to delete is not empty"}
+	ErrInvalidBucketName            = &Error{Code: "InvalidBucketName", Message: "The specified bucket is not valid."}
+	ErrInvalidArgument              = &Error{Code: "InvalidArgument", Message: "Invalid Argument"}
+	ErrInvalidRequest               = &Error{Code: "InvalidRequest", Message: "Invalid Request"}
+	ErrIncompleteBody               = &Error{Code: "IncompleteBody", Message: "You did not provide the number of bytes specified by the Content-Length HTTP header."}
+	ErrNotImplemented               = &Error{Code: "NotImplemented", Message: "A header or query you provided requested a function that is not implemented."}
+	ErrMalformedXML                 = &Error{Code: "MalformedXML", Message: "The XML you provided was not well-formed or did not validate against our published schema."}
+	ErrNoSuchUpload                 = &Error{Code: "NoSuchUpload", Message: "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed."}
+	ErrInvalidPart                  = &Error{Code: "InvalidPart", Message: "One or more of the specified parts could not be found. The part may not have been uploaded, or the specified entity tag may not match the part's entity tag."}
+	ErrInvalidPartOrder             = &Error{Code: "InvalidPartOrder", Message: "The list of parts was not in ascending order. The parts list must be specified in order by part number."}
+	ErrAccessDenied                 = &Error{Code: "AccessDenied", Message: "Access Denied"}
+	ErrInvalidAccessKeyID           = &Error{Code: "InvalidAccessKeyId", Message: "The AWS Access Key Id you provided does not exist in our records."}
+	ErrSignatureDoesNotMatch        = &Error{Code: "SignatureDoesNotMatch", Message: "The request signature we calculated does not match the signature you provided. Check your key and signing method."}
+	ErrRequestTimeTooSkewed         = &Error{Code: "RequestTimeTooSkewed", Message: "The difference between the request time and the current time is too large."}
+	ErrAuthorizationHeaderMalformed = &Error{Code: "AuthorizationHeaderMalformed", Message: "The authorization header is malformed."}
+	ErrContentSHA256Mismatch        = &Error{Code: "XAmzContentSHA256Mismatch", Message: "The provided 'x-amz-content-sha256' header does not match what was computed."}
+	ErrAuthorizationQueryParameters = &Error{Code: "AuthorizationQueryParametersError", Message: "The query-string authentication parameters are malformed."}
+	ErrEntityTooSmall               = &Error{Code: "EntityTooSmall", Message: "Your proposed upload is smaller than the minimum allowed object size."}
+	ErrInvalidRange                 = &Error{Code: "InvalidRange", Message: "The requested range is not satisfiable"}
+	ErrInvalidPartNumber            = &Error{Code: "InvalidPartNumber", Message: "The requested partnumber is not satisfiable"}
+	ErrPreconditionFailed           = &Error{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+	ErrNotModified                  = &Error{Code: "NotModified", Message: "Not Modified"}
+	ErrMetadataTooLarge             = &Error{Code: "MetadataTooLarge", Message: "Your metadata headers exceed the maximum allowed metadata size."}
+	ErrBadDigest                    = &Error{Code: "BadDigest", Message: "The Content-MD5 you specified did not match what we received."}
+	ErrInvalidDigest                = &Error{Code: "InvalidDigest", Message: "The Content-MD5 you specified is not valid."}
+	ErrNoSuchVersion                = &Error{Code: "NoSuchVersion", Message: "The specified version does not exist."}
+	ErrKeyTooLong                   = &Error{Code: "KeyTooLongError", Message: "Your key is too long"}
+	ErrEntityTooLarge               = &Error{Code: "EntityTooLarge", Message: "Your proposed upload exceeds the maximum allowed object size."}
+	ErrMalformedPOSTRequest         = &Error{Code: "MalformedPOSTRequest", Message: "The body of your POST request is not well-formed multipart/form-data."}
+	ErrInvalidPolicyDocument        = &Error{Code: "InvalidPolicyDocument", Message: "The content of the form does not meet the conditions specified in the policy document."}
+	ErrMaxPostPreDataLengthExceeded = &Error{Code: "MaxPostPreDataLengthExceededError", Message: "Your POST request fields preceding the upload file were too large."}
+	ErrMethodNotAllowed             = &Error{Code: "MethodNotAllowed", Message: "The specified method is not allowed against this resource."}
+	ErrIllegalVersioningConfig      = &Error{Code: "IllegalVersioningConfigurationException", Message: "The versioning configuration specified in the request is invalid."}
+	ErrNoSuchLifecycleConfig        = &Error{Code: "NoSuchLifecycleConfiguration", Message: "The lifecycle configuration does not exist."}
+	ErrNoSuchTagSet                 = &Error{Code: "NoSuchTagSet", Message: "The TagSet does not exist"}
+	ErrInvalidTag                   = &Error{Code: "InvalidTag", Message: "The tag provided was not a valid tag."}
+	ErrNoSuchCORSConfig             = &Error{Code: "NoSuchCORSConfiguration", Message: "The CORS configuration does not exist"}
+	ErrCORSForbidden                = &Error{Code: "AccessForbidden", Message: "CORSResponse: This CORS request is not allowed. This is usually because the evaluation of Origin, request method / Access-Control-Request-Method or Access-Control-Request-Headers are not whitelisted by the resource's CORS spec."}
+	ErrBadRequest                   = &Error{Code: "BadRequest", Message: "Bad Request"}
+)
@@ -1,9 +1,22 @@
 package domain
 
-import "github.com/bonifacio-pedro/s3ego/internal/model"
+import (
+	"io"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
 
 type FileService interface {
-	Get(bucketName string, key string) ([]byte, model.File, error)
+	Get(bucketName string, key string) (io.ReadSeekCloser, model.File, error)
+	GetRange(bucketName string, key string, options model.GetOptions) (io.ReadCloser, model.File, *model.ByteRange, error)
+	Head(bucketName string, key string, options model.GetOptions) (model.File, *model.ByteRange, error)
 	Remove(bucketName string, key string) error
-	Upload(bucketName string, data []byte, fileName string) (string, string, error)
+	RemoveVersion(bucketName string, key string, versionID string) (model.DeleteResult, error)
+	RemoveObjects(bucketName string, objects []model.ObjectIdentifier) ([]model.DeleteResult, error)
+	Upload(bucketName string, data io.Reader, key string) (string, string, error)
+	UploadWithOptions(bucketName string, data io.Reader, key string, options model.PutOptions) (model.File, error)
+	Copy(sourceBucket string, sourceKey string, bucketName string, key string, options model.CopyOptions) (model.File, error)
+	GetTagging(bucketName string, key string, versionID string) (model.File, error)
+	PutTagging(bucketName string, key string, versionID string, tags map[string]string) (model.File, error)
+	RemoveTagging(bucketName string, key string, versionID string) (model.File, error)
 }
\ No newline at end of file
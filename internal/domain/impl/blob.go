@@ -0,0 +1,149 @@
+package impl
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
+)
+
+// sniffLen is the number of leading bytes kept to detect the content type of an object.
+const sniffLen = 512
+
+// blobContent describes data streamed into the BlobStore by storeStream.
+type blobContent struct {
+	ref  string // Reference of the new blob
+	size int64  // Size of the data in bytes
+	etag string // Hex MD5 of the data, computed while streaming
+	head []byte // Up to the first sniffLen bytes of the data
+}
+
+// storeStream streams r into a new blob, computing its MD5 on the fly and keeping
+// its first bytes to detect the content type, so the data is never held in memory as a whole.
+// Returns the stored content, or an error if reading r or writing the blob fails;
+// domain errors raised by r (e.g. a payload signature mismatch) are returned unchanged.
+func storeStream(blobs storage.BlobStore, r io.Reader) (blobContent, error) {
+	buffered := bufio.NewReaderSize(r, sniffLen)
+
+	head, err := buffered.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return blobContent{}, err
+	}
+	head = bytes.Clone(head)
+
+	hash := md5.New()
+	blob, err := blobs.Put(io.TeeReader(buffered, hash))
+	if err != nil {
+		return blobContent{}, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return blobContent{
+		ref:  blob.Ref,
+		size: blob.Size,
+		etag: fmt.Sprintf("%x", hash.Sum(nil)),
+		head: head,
+	}, nil
+}
+
+// openBlob opens the blob with the given reference for reading.
+// Returns an error if the blob referenced by the metadata is missing or cannot be opened.
+func openBlob(blobs storage.BlobStore, ref string) (io.ReadSeekCloser, error) {
+	reader, err := blobs.Get(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob %s: %w", ref, err)
+	}
+
+	return reader, nil
+}
+
+// openBlobRange opens the blob with the given reference for reading only the bytes within byteRange.
+// Returns an error if the blob cannot be opened or positioned at the start of the range.
+func openBlobRange(blobs storage.BlobStore, ref string, byteRange model.ByteRange) (io.ReadCloser, error) {
+	reader, err := openBlob(blobs, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := reader.Seek(byteRange.Start, io.SeekStart); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to seek blob %s: %w", ref, err)
+	}
+
+	return &rangeReader{Reader: io.LimitReader(reader, byteRange.Length()), Closer: reader}, nil
+}
+
+// rangeReader reads a range of a blob, closing the underlying blob reader when closed.
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
+// releaseBlobs deletes the blobs a repository reported as no longer referenced.
+// The metadata change is already committed at that point, so failures are only logged.
+func releaseBlobs(blobs storage.BlobStore, refs []string) {
+	for _, ref := range refs {
+		if err := blobs.Delete(ref); err != nil {
+			log.Printf("[S3EGO] Warning: Failed to delete blob %s: %s", ref, err)
+		}
+	}
+}
+
+// concatReader reads the concatenation of several blobs, opening each one only
+// once the previous one is exhausted, so at most one blob is open at a time.
+type concatReader struct {
+	blobs   storage.BlobStore
+	refs    []string
+	current io.ReadCloser
+}
+
+// newConcatReader creates a reader of the blobs with the given references, in order.
+func newConcatReader(blobs storage.BlobStore, refs []string) *concatReader {
+	return &concatReader{blobs: blobs, refs: refs}
+}
+
+// Read reads from the current blob, moving on to the next one when it reaches its end.
+func (cr *concatReader) Read(p []byte) (int, error) {
+	for {
+		if cr.current == nil {
+			if len(cr.refs) == 0 {
+				return 0, io.EOF
+			}
+
+			reader, err := openBlob(cr.blobs, cr.refs[0])
+			if err != nil {
+				return 0, err
+			}
+			cr.refs = cr.refs[1:]
+			cr.current = reader
+		}
+
+		n, err := cr.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			cr.current.Close()
+			cr.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+
+		return n, err
+	}
+}
+
+// Close closes the blob currently being read, if any.
+func (cr *concatReader) Close() error {
+	if cr.current == nil {
+		return nil
+	}
+
+	err := cr.current.Close()
+	cr.current = nil
+	return err
+}
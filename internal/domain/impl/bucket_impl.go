@@ -4,31 +4,48 @@ package impl
 import (
 	"errors"
 	"log"
+	"net"
+	"regexp"
+	"strings"
 
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
 	"github.com/bonifacio-pedro/s3ego/internal/domain"
 	"github.com/bonifacio-pedro/s3ego/internal/model"
 	"github.com/bonifacio-pedro/s3ego/internal/repository"
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
 )
 
+// bucketNamePattern matches the characters and boundaries allowed by S3 bucket naming rules.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
 // BucketService encapsulates business logic related to S3EGO buckets.
 // It acts as an intermediary between the handler layer and the repository.
 type bucketService struct {
 	repository repository.BucketRepository
+	blobs      storage.BlobStore
+	clock      clock.Clock
 }
 
 // NewBucketService returns a new instance of BucketService.
 //
 // It receives a pointer to a BucketRepository which it uses
-// to persist and retrieve bucket data.
-func NewBucketService(repository repository.BucketRepository) domain.BucketService {
-	return &bucketService{repository: repository}
+// to persist and retrieve bucket data, and the BlobStore holding
+// the data of the files deleted together with a bucket. Buckets are created
+// at the current time of the given clock.
+func NewBucketService(repository repository.BucketRepository, blobs storage.BlobStore, clock clock.Clock) domain.BucketService {
+	return &bucketService{repository: repository, blobs: blobs, clock: clock}
 }
 
 // New creates a new bucket with the given name.
-// It returns the bucket URL on success, or an error if the bucket already exists
-// or if there was a problem creating it in the repository.
+// It returns the bucket URL on success, domain.ErrInvalidBucketName if the name breaks
+// the S3 naming rules, domain.ErrBucketAlreadyExists if the bucket already exists,
+// or an error if there was a problem creating it in the repository.
 func (bs *bucketService) New(name string) (string, error) {
-	bucket := model.NewBucket(name)
+	if err := validateBucketName(name); err != nil {
+		return "", err
+	}
+
+	bucket := model.NewBucket(name, bs.clock)
 
 	exists, err := bs.repository.ExistsByName(bucket.Name)
 	if err != nil {
@@ -36,7 +53,7 @@ func (bs *bucketService) New(name string) (string, error) {
 	}
 
 	if exists {
-		return "", errors.New("bucket already exists")
+		return "", domain.ErrBucketAlreadyExists
 	}
 
 	if err := bs.repository.New(&bucket); err != nil {
@@ -47,11 +64,33 @@ func (bs *bucketService) New(name string) (string, error) {
 	return bucket.Url, nil
 }
 
-// FindAllFiles returns all file keys stored in a given bucket by name.
-// It returns a slice of strings or an error if the bucket doesn't exist
-// or if there was an issue fetching the files.
+// Exists reports whether a bucket with the given name exists.
+func (bs *bucketService) Exists(bucketName string) (bool, error) {
+	return bs.repository.ExistsByName(bucketName)
+}
+
+// List returns the buckets whose name starts with query.Prefix, sorted by name and paged by
+// query.StartAfter and query.MaxBuckets. The zero query lists every bucket.
+// It returns domain.ErrInvalidArgument if MaxBuckets is negative, or an error if the listing fails.
+func (bs *bucketService) List(query model.BucketQuery) (*model.BucketListing, error) {
+	if query.MaxBuckets < 0 {
+		return nil, domain.ErrInvalidArgument.WithMessage("max-buckets must be a non-negative integer")
+	}
+
+	listing, err := bs.repository.List(query)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("[S3EGO] LISTED BUCKETS")
+	return listing, nil
+}
+
+// FindAllFiles returns the keys of all current objects stored in a given bucket by name.
+// It returns a slice of strings, domain.ErrNoSuchBucket if the bucket doesn't exist,
+// or an error if there was an issue fetching the files.
 func (bs *bucketService) FindAllFiles(bucketName string) (*[]string, error) {
-	bucket, err := bs.repository.GetByName(bucketName)
+	bucket, err := findBucket(bs.repository, bucketName)
 	if err != nil {
 		return nil, err
 	}
@@ -65,18 +104,216 @@ func (bs *bucketService) FindAllFiles(bucketName string) (*[]string, error) {
 	return &files, err
 }
 
-// Remove deletes a bucket by its name.
-// It returns an error if the bucket doesn't exist or fails to be deleted.
+// ListFiles returns a page of the files stored in a bucket, filtered and paged by the query
+// with S3 ListObjects semantics. MaxKeys above model.MaxListKeys is capped, as S3 does.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrInvalidArgument
+// if MaxKeys is negative, or an error if there was an issue listing the files.
+func (bs *bucketService) ListFiles(bucketName string, query model.ListQuery) (*model.Listing, error) {
+	if query.MaxKeys < 0 {
+		return nil, domain.ErrInvalidArgument.WithMessage("max-keys must be a non-negative integer")
+	}
+	query.MaxKeys = min(query.MaxKeys, model.MaxListKeys)
+
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	listing, err := bs.repository.ListFiles(bucket.ID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("[S3EGO] LISTED FILES IN A BUCKET:", bucket.Name)
+	return listing, nil
+}
+
+// ListVersions returns a page of the versions and delete markers of the files stored in a bucket,
+// filtered and paged by the query with S3 ListObjectVersions semantics. MaxKeys above
+// model.MaxListKeys is capped, as S3 does.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrInvalidArgument if MaxKeys
+// is negative or the version ID marker is not a version of the key marker, or an error if there was
+// an issue listing the versions.
+func (bs *bucketService) ListVersions(bucketName string, query model.VersionQuery) (*model.VersionListing, error) {
+	if query.MaxKeys < 0 {
+		return nil, domain.ErrInvalidArgument.WithMessage("max-keys must be a non-negative integer")
+	}
+	query.MaxKeys = min(query.MaxKeys, model.MaxListKeys)
+
+	if query.VersionIDMarker != "" && query.KeyMarker == "" {
+		return nil, domain.ErrInvalidArgument.WithMessage("A version-id marker cannot be specified without a key marker.")
+	}
+
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	listing, err := bs.repository.ListVersions(bucket.ID, query)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, domain.ErrInvalidArgument.WithMessage("Invalid version id specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("[S3EGO] LISTED FILE VERSIONS IN A BUCKET:", bucket.Name)
+	return listing, nil
+}
+
+// SetVersioning enables or suspends the versioning of a bucket. Once enabled, versioning can only
+// be suspended, never turned off: the bucket keeps the versions already stored.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrIllegalVersioningConfig
+// if status is neither Enabled nor Suspended, or an error if the update fails.
+func (bs *bucketService) SetVersioning(bucketName string, status model.VersioningStatus) error {
+	if status != model.VersioningEnabled && status != model.VersioningSuspended {
+		return domain.ErrIllegalVersioningConfig.WithMessage("The versioning status must be %s or %s", model.VersioningEnabled, model.VersioningSuspended)
+	}
+
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	if err := bs.repository.SetVersioning(bucket.ID, status); err != nil {
+		return err
+	}
+
+	log.Printf("[S3EGO] BUCKET VERSIONING %s: %s", strings.ToUpper(string(status)), bucket.Name)
+	return nil
+}
+
+// GetVersioning returns the versioning state of a bucket, model.VersioningUnversioned if it was never enabled.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist.
+func (bs *bucketService) GetVersioning(bucketName string) (model.VersioningStatus, error) {
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return "", err
+	}
+
+	return bucket.Versioning, nil
+}
+
+// GetTagging returns the tag set of a bucket.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, or domain.ErrNoSuchTagSet if it has no tags.
+func (bs *bucketService) GetTagging(bucketName string) (map[string]string, error) {
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := bs.repository.GetTags(bucket.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		return nil, domain.ErrNoSuchTagSet
+	}
+
+	return tags, nil
+}
+
+// PutTagging replaces the tag set of a bucket. An empty tag set removes the tags of the bucket.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrInvalidTag if the tags
+// break the S3 tagging limits, or an error if the write fails.
+func (bs *bucketService) PutTagging(bucketName string, tags map[string]string) error {
+	if err := validateTags(tags, model.MaxBucketTags, "Bucket"); err != nil {
+		return err
+	}
+
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	err = bs.repository.SetTags(bucket.ID, tags)
+	if errors.Is(err, repository.ErrNotFound) {
+		return domain.ErrNoSuchBucket
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[S3EGO] BUCKET TAGGED: %s (%d tags)", bucket.Name, len(tags))
+	return nil
+}
+
+// RemoveTagging removes the tag set of a bucket, as PutTagging does with an empty tag set.
+// As in S3, removing missing tags succeeds.
+func (bs *bucketService) RemoveTagging(bucketName string) error {
+	return bs.PutTagging(bucketName, nil)
+}
+
+// Remove deletes a bucket by its name, together with all of its files.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, or an error if it fails to be deleted.
 func (bs *bucketService) Remove(bucketName string) error {
-	bucket, err := bs.repository.GetByName(bucketName)
+	bucket, err := findBucket(bs.repository, bucketName)
 	if err != nil {
 		return err
 	}
 
-	if err := bs.repository.Remove(bucket.ID); err != nil {
+	released, err := bs.repository.Remove(bucket.ID)
+	if err != nil {
 		return err
 	}
+	releaseBlobs(bs.blobs, released)
 
 	log.Println("[S3EGO] BUCKET DELETED:", bucketName)
 	return nil
 }
+
+// RemoveEmpty deletes a bucket by its name only if it holds no files, as S3 DeleteBucket does:
+// noncurrent versions and delete markers count as files.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrBucketNotEmpty
+// if it still has files, or an error if it fails to be deleted.
+func (bs *bucketService) RemoveEmpty(bucketName string) error {
+	bucket, err := findBucket(bs.repository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	hasFiles, err := bs.repository.HasFiles(bucket.ID)
+	if err != nil {
+		return err
+	}
+
+	if hasFiles {
+		return domain.ErrBucketNotEmpty
+	}
+
+	released, err := bs.repository.Remove(bucket.ID)
+	if err != nil {
+		return err
+	}
+	releaseBlobs(bs.blobs, released)
+
+	log.Println("[S3EGO] BUCKET DELETED:", bucketName)
+	return nil
+}
+
+// findBucket retrieves a bucket by name, translating a missing bucket into domain.ErrNoSuchBucket.
+func findBucket(bucketRepository repository.BucketRepository, bucketName string) (*model.Bucket, error) {
+	bucket, err := bucketRepository.GetByName(bucketName)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, domain.ErrNoSuchBucket
+	}
+
+	return bucket, err
+}
+
+// validateBucketName checks a bucket name against the S3 general purpose bucket naming rules.
+func validateBucketName(name string) error {
+	switch {
+	case !bucketNamePattern.MatchString(name):
+		return domain.ErrInvalidBucketName.WithMessage("bucket name %q must be 3-63 lowercase letters, numbers, dots or hyphens", name)
+	case strings.Contains(name, ".."):
+		return domain.ErrInvalidBucketName.WithMessage("bucket name %q must not contain two adjacent periods", name)
+	case net.ParseIP(name) != nil:
+		return domain.ErrInvalidBucketName.WithMessage("bucket name %q must not be formatted as an IP address", name)
+	case strings.HasPrefix(name, "xn--"), strings.HasSuffix(name, "-s3alias"):
+		return domain.ErrInvalidBucketName.WithMessage("bucket name %q uses a reserved prefix or suffix", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package domain contains the business logic for managing buckets and files.
+package impl
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+)
+
+// maxCORSRuleIDLength is the maximum length of the ID of a CORS rule accepted by S3.
+const maxCORSRuleIDLength = 255
+
+// CORSService manages the CORS rules of buckets and evaluates cross-origin requests against them.
+type corsService struct {
+	corsRepository   repository.CORSRepository
+	bucketRepository repository.BucketRepository
+}
+
+// NewCORSService creates a new CORSService with the provided repositories.
+func NewCORSService(corsRepository repository.CORSRepository, bucketRepository repository.BucketRepository) domain.CORSService {
+	return &corsService{corsRepository: corsRepository, bucketRepository: bucketRepository}
+}
+
+// Put replaces the CORS rules of a bucket.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrMalformedXML,
+// domain.ErrInvalidArgument or domain.ErrInvalidRequest if the rules are not valid,
+// or an error if they cannot be stored.
+func (cs *corsService) Put(bucketName string, rules []model.CORSRule) error {
+	if err := validateCORSRules(rules); err != nil {
+		return err
+	}
+
+	bucket, err := findBucket(cs.bucketRepository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.corsRepository.Put(bucket.ID, rules); err != nil {
+		return err
+	}
+
+	log.Printf("[S3EGO] BUCKET CORS CONFIGURED: %s (%d rules)", bucket.Name, len(rules))
+	return nil
+}
+
+// Get returns the CORS rules of a bucket, in the order they were configured.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, or domain.ErrNoSuchCORSConfig
+// if the bucket has no CORS rules.
+func (cs *corsService) Get(bucketName string) ([]model.CORSRule, error) {
+	bucket, err := findBucket(cs.bucketRepository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := cs.corsRepository.Get(bucket.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, domain.ErrNoSuchCORSConfig
+	}
+
+	return rules, err
+}
+
+// Remove deletes the CORS rules of a bucket. As in S3, removing missing rules succeeds.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, or an error if the deletion fails.
+func (cs *corsService) Remove(bucketName string) error {
+	bucket, err := findBucket(cs.bucketRepository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.corsRepository.Remove(bucket.ID); err != nil {
+		return err
+	}
+
+	log.Println("[S3EGO] BUCKET CORS REMOVED:", bucket.Name)
+	return nil
+}
+
+// Match returns the first CORS rule of a bucket allowing a request from origin with the given
+// method, sending the given headers.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrCORSForbidden if the
+// bucket has no CORS rules or none of them allows the request, or an error if the rules cannot be read.
+func (cs *corsService) Match(bucketName string, origin string, method string, headers []string) (*model.CORSRule, error) {
+	rules, err := cs.Get(bucketName)
+	if errors.Is(err, domain.ErrNoSuchCORSConfig) {
+		return nil, domain.ErrCORSForbidden.WithMessage("CORSResponse: CORS is not enabled for this bucket.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rule := model.MatchCORSRule(rules, origin, method, headers)
+	if rule == nil {
+		return nil, domain.ErrCORSForbidden
+	}
+
+	return rule, nil
+}
+
+// validateCORSRules checks the rules of a CORS configuration against the S3 limits.
+func validateCORSRules(rules []model.CORSRule) error {
+	if len(rules) == 0 || len(rules) > model.MaxCORSRules {
+		return domain.ErrMalformedXML.WithMessage("A CORS configuration must have between 1 and %d rules", model.MaxCORSRules)
+	}
+
+	for _, rule := range rules {
+		if err := validateCORSRule(rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateCORSRule checks a single CORS rule against the S3 limits: it allows at least one origin
+// and one supported method, and its origins and headers hold at most one wildcard each.
+func validateCORSRule(rule model.CORSRule) error {
+	switch {
+	case len(rule.ID) > maxCORSRuleIDLength:
+		return domain.ErrInvalidArgument.WithMessage("ID length should not exceed allowed limit of %d", maxCORSRuleIDLength)
+	case len(rule.AllowedOrigins) == 0 || len(rule.AllowedMethods) == 0:
+		return domain.ErrMalformedXML.WithMessage("A CORS rule must have at least one AllowedOrigin and one AllowedMethod")
+	case rule.MaxAgeSeconds < 0:
+		return domain.ErrInvalidArgument.WithMessage("MaxAgeSeconds must be a non-negative integer")
+	}
+
+	for _, method := range rule.AllowedMethods {
+		if !slices.Contains(model.CORSMethods, method) {
+			return domain.ErrInvalidRequest.WithMessage("Found unsupported HTTP method in CORS config. Unsupported method is %s", method)
+		}
+	}
+
+	for _, origin := range rule.AllowedOrigins {
+		if strings.Count(origin, "*") > 1 {
+			return domain.ErrInvalidRequest.WithMessage("AllowedOrigin %q can not have more than one wildcard.", origin)
+		}
+	}
+
+	for _, header := range rule.AllowedHeaders {
+		if strings.Count(header, "*") > 1 {
+			return domain.ErrInvalidRequest.WithMessage("AllowedHeader %q can not have more than one wildcard.", header)
+		}
+	}
+
+	return nil
+}
@@ -2,99 +2,670 @@
 package impl
 
 import (
-	"fmt"
+	"cmp"
+	"errors"
+	"io"
 	"log"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
 	"github.com/bonifacio-pedro/s3ego/internal/domain"
 	"github.com/bonifacio-pedro/s3ego/internal/model"
 	"github.com/bonifacio-pedro/s3ego/internal/repository"
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
+	"github.com/google/uuid"
 )
 
 // FileService provides methods to manage files within buckets.
-// It communicates with FileRepository and BucketRepository to perform CRUD operations.
+// It communicates with FileRepository and BucketRepository to perform CRUD operations,
+// and keeps the file data in the BlobStore.
 type fileService struct {
 	fileRepository   repository.FileRepository
 	bucketRepository repository.BucketRepository
+	blobs            storage.BlobStore
+	clock            clock.Clock
 }
 
-// NewFileService creates a new FileService with the provided file and bucket repositories.
-func NewFileService(fileRepository repository.FileRepository, bucketRepository repository.BucketRepository) domain.FileService {
-	return &fileService{fileRepository: fileRepository, bucketRepository: bucketRepository}
+// NewFileService creates a new FileService with the provided file and bucket repositories and blob store,
+// stamping files with the current time of the given clock.
+func NewFileService(fileRepository repository.FileRepository, bucketRepository repository.BucketRepository, blobs storage.BlobStore, clock clock.Clock) domain.FileService {
+	return &fileService{fileRepository: fileRepository, bucketRepository: bucketRepository, blobs: blobs, clock: clock}
 }
 
-// Get retrieves the file data by bucket name and file key.
-// Returns the file data bytes or an error if the bucket or file doesn't exist,
-// or if the file does not belong to the specified bucket.
-func (fs *fileService) Get(bucketName string, key string) ([]byte, model.File, error) {
-	bucket, err := fs.bucketRepository.GetByName(bucketName)
+// Get opens the data of the current version of a file by bucket name and file key for streaming.
+// Returns a reader of the file data, which the caller must close, and the file metadata,
+// domain.ErrNoSuchBucket if the bucket doesn't exist, or domain.ErrNoSuchKey if the file doesn't
+// exist in the specified bucket or its latest version is a delete marker.
+func (fs *fileService) Get(bucketName string, key string) (io.ReadSeekCloser, model.File, error) {
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
 	if err != nil {
 		return nil, model.File{}, err
 	}
 
-	file, err := fs.fileRepository.GetByKey(key)
+	file, err := findFile(fs.fileRepository, bucket, key)
 	if err != nil {
 		return nil, model.File{}, err
 	}
 
-	if int(file.BucketID) != bucket.ID {
-		return nil, model.File{}, fmt.Errorf("this file is not in %s bucket", bucket.Name)
+	data, err := openBlob(fs.blobs, file.BlobRef)
+	if err != nil {
+		return nil, model.File{}, err
 	}
 
 	log.Printf("[S3EGO] PULLED NEW FILE: %s/%s", bucket.Name, key)
-	return file.Data, *file, nil
+	return data, *file, nil
+}
+
+// GetRange opens part of the file data by bucket name and file key for streaming: the bytes
+// within options.Range, or the part options.PartNumber of a file assembled by a multipart upload,
+// of the version options.VersionID, or of the current version if it is empty.
+// Without a range nor a part number the whole file is opened, as with Get. The file must first
+// meet options.Preconditions.
+//
+// Returns a reader of the requested data, which the caller must close, the file metadata and
+// the byte range read, nil for the whole file, or the errors described in Head.
+func (fs *fileService) GetRange(bucketName string, key string, options model.GetOptions) (io.ReadCloser, model.File, *model.ByteRange, error) {
+	file, selected, err := fs.Head(bucketName, key, options)
+	if err != nil {
+		return nil, file, nil, err
+	}
+
+	if selected == nil {
+		data, err := openBlob(fs.blobs, file.BlobRef)
+		if err != nil {
+			return nil, model.File{}, nil, err
+		}
+
+		log.Printf("[S3EGO] PULLED NEW FILE: %s/%s", bucketName, key)
+		return data, file, nil, nil
+	}
+
+	data, err := openBlobRange(fs.blobs, file.BlobRef, *selected)
+	if err != nil {
+		return nil, model.File{}, nil, err
+	}
+
+	log.Printf("[S3EGO] PULLED NEW FILE RANGE: %s/%s (bytes %d-%d)", bucketName, key, selected.Start, selected.End)
+	return data, file, selected, nil
 }
 
-// Remove deletes a file specified by bucket name and key.
-// Returns an error if the bucket or file does not exist,
-// or if the file does not belong to the specified bucket.
+// Head retrieves the metadata of a file by bucket name and file key, and resolves the byte range
+// GetRange would read with the same options, without reading the file data.
+//
+// Returns the file metadata and the byte range selected by options, nil for the whole file.
+// Returns domain.ErrNoSuchBucket, domain.ErrNoSuchKey or domain.ErrNoSuchVersion if the file doesn't exist,
+// along with the delete marker if the latest version of the key is one, or domain.ErrMethodNotAllowed
+// along with the delete marker if options.VersionID is one. Returns domain.ErrInvalidRequest if both
+// a range and a part number are given, or domain.ErrInvalidArgument if the part number is out of
+// bounds. Returns domain.ErrPreconditionFailed, domain.ErrNotModified, domain.ErrInvalidRange or
+// domain.ErrInvalidPartNumber along with the file metadata if the preconditions do not hold or the
+// range or part is not satisfiable.
+func (fs *fileService) Head(bucketName string, key string, options model.GetOptions) (model.File, *model.ByteRange, error) {
+	if options.Range != nil && options.PartNumber != 0 {
+		return model.File{}, nil, domain.ErrInvalidRequest.WithMessage("Cannot specify both Range header and partNumber query parameter")
+	}
+
+	if options.PartNumber < 0 || options.PartNumber > model.MaxPartNumber {
+		return model.File{}, nil, domain.ErrInvalidArgument.WithMessage("Part number must be an integer between 1 and %d, inclusive", model.MaxPartNumber)
+	}
+
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
+	if err != nil {
+		return model.File{}, nil, err
+	}
+
+	file, err := findVersion(fs.fileRepository, bucket, key, options.VersionID)
+	if err != nil {
+		if file != nil {
+			return *file, nil, err
+		}
+		return model.File{}, nil, err
+	}
+
+	if err := checkPreconditions(options.Preconditions, file, domain.ErrNotModified); err != nil {
+		return *file, nil, err
+	}
+
+	var selected model.ByteRange
+	var ok bool
+	switch {
+	case options.Range != nil:
+		if selected, ok = options.Range.Resolve(file.Size); !ok {
+			return *file, nil, domain.ErrInvalidRange
+		}
+	case options.PartNumber != 0:
+		if selected, ok = file.PartRange(options.PartNumber); !ok {
+			return *file, nil, domain.ErrInvalidPartNumber
+		}
+	}
+
+	// An empty part of an empty file is the whole file
+	if !ok || selected.Length() <= 0 {
+		return *file, nil, nil
+	}
+
+	return *file, &selected, nil
+}
+
+// Remove deletes the current version of a file specified by bucket name and key, as RemoveVersion does.
+// Returns domain.ErrNoSuchBucket if the bucket doesn't exist, or domain.ErrNoSuchKey if the file doesn't
+// exist in the specified bucket or its latest version is already a delete marker.
 func (fs *fileService) Remove(bucketName string, key string) error {
-	bucket, err := fs.bucketRepository.GetByName(bucketName)
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
 	if err != nil {
 		return err
 	}
 
-	file, err := fs.fileRepository.GetByKey(key)
-	if err != nil {
+	if _, err := findFile(fs.fileRepository, bucket, key); err != nil {
 		return err
 	}
 
-	if file.BucketID != uint(bucket.ID) {
-		return fmt.Errorf("this file is not in %s bucket", bucket.Name)
+	_, err = fs.removeObject(bucket, model.ObjectIdentifier{Key: key})
+	return err
+}
+
+// RemoveVersion deletes a file specified by bucket name and key, as S3 DeleteObject does: with a
+// versionID, that version is deleted permanently; without one, the file is deleted from an unversioned
+// bucket, while a delete marker is stored as its latest version in a versioned bucket, keeping the
+// previous versions. As in S3, deleting a key or version that does not exist succeeds.
+// Returns the outcome of the deletion, domain.ErrNoSuchBucket if the bucket doesn't exist,
+// or an error if the deletion fails.
+func (fs *fileService) RemoveVersion(bucketName string, key string, versionID string) (model.DeleteResult, error) {
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
+	if err != nil {
+		return model.DeleteResult{}, err
 	}
 
-	err = fs.fileRepository.Remove(key)
+	return fs.removeObject(bucket, model.ObjectIdentifier{Key: key, VersionID: versionID})
+}
+
+// removeObject deletes a file, or a version of it, from the given bucket as RemoveVersion describes.
+func (fs *fileService) removeObject(bucket *model.Bucket, object model.ObjectIdentifier) (model.DeleteResult, error) {
+	result, marker, err := fs.planDelete(bucket, object)
 	if err != nil {
-		return err
+		return model.DeleteResult{}, err
 	}
 
-	log.Printf("[S3EGO] FILE REMOVED: %s/%s", bucket.Name, key)
-	return nil
+	var released []string
+	if marker != nil {
+		released, err = fs.fileRepository.Put(marker)
+	} else {
+		released, err = fs.fileRepository.Remove(bucket.ID, object.Key, cmp.Or(object.VersionID, model.NullVersionID))
+	}
+	if err != nil {
+		return model.DeleteResult{}, err
+	}
+	releaseBlobs(fs.blobs, released)
+
+	log.Printf("[S3EGO] FILE REMOVED: %s/%s", bucket.Name, object.Key)
+	return result, nil
 }
 
-// Upload stores a new file in the specified bucket.
-// It returns the key of the stored file or an error if the bucket does not exist,
-// if the file already exists in the bucket, or if there was a failure during insertion.
-func (fs *fileService) Upload(bucketName string, data []byte, fileName string) (string, string, error) {
-	bucket, err := fs.bucketRepository.GetByName(bucketName)
+// RemoveObjects deletes several files, or versions of files, of the specified bucket at once,
+// each as RemoveVersion does, in a single repository transaction.
+// As in S3, a key or version that does not exist counts as deleted.
+// Returns the outcome of every object in request order, with domain.ErrInvalidArgument or
+// domain.ErrKeyTooLong for the objects that were not deleted.
+// Returns domain.ErrInvalidArgument if no object or more than model.MaxDeleteObjects are given,
+// domain.ErrNoSuchBucket if the bucket does not exist, or an error if the deletion fails, in which
+// case no file is deleted.
+func (fs *fileService) RemoveObjects(bucketName string, objects []model.ObjectIdentifier) ([]model.DeleteResult, error) {
+	if len(objects) == 0 || len(objects) > model.MaxDeleteObjects {
+		return nil, domain.ErrInvalidArgument.WithMessage("A multi-object delete must list between 1 and %d objects", model.MaxDeleteObjects)
+	}
+
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	fileModel := model.NewFile(data, *bucket, fileName)
+	results := make([]model.DeleteResult, 0, len(objects))
+	versions := make([]model.ObjectIdentifier, 0, len(objects))
+	markers := make([]*model.File, 0)
+	for _, object := range objects {
+		if err := validateKey(object.Key); err != nil {
+			results = append(results, model.DeleteResult{Key: object.Key, VersionID: object.VersionID, Err: err})
+			continue
+		}
+
+		result, marker, err := fs.planDelete(bucket, object)
+		if err != nil {
+			return nil, err
+		}
+
+		if marker != nil {
+			markers = append(markers, marker)
+		} else {
+			versions = append(versions, model.ObjectIdentifier{Key: object.Key, VersionID: cmp.Or(object.VersionID, model.NullVersionID)})
+		}
+		results = append(results, result)
+	}
 
-	fileExists, err := fs.bucketRepository.FileExists(bucketName, fileModel.Key)
+	released, err := fs.fileRepository.RemoveAll(bucket.ID, versions, markers)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+	releaseBlobs(fs.blobs, released)
+
+	log.Printf("[S3EGO] FILES REMOVED: %s (%d keys)", bucket.Name, len(versions)+len(markers))
+	return results, nil
+}
+
+// planDelete resolves how an object is deleted from bucket: a given version, or the "null" version
+// of an unversioned bucket, is deleted permanently, while deleting the current object of a versioned
+// bucket stores a delete marker as the latest version of its key.
+// Returns the outcome of the deletion and the delete marker to store, nil if the version is deleted,
+// or an error if the version cannot be looked up.
+func (fs *fileService) planDelete(bucket *model.Bucket, object model.ObjectIdentifier) (model.DeleteResult, *model.File, error) {
+	result := model.DeleteResult{Key: object.Key, VersionID: object.VersionID}
+
+	if object.VersionID != "" {
+		version, err := fs.fileRepository.GetVersion(bucket.ID, object.Key, object.VersionID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return model.DeleteResult{}, nil, err
+		}
+
+		result.DeleteMarker = version != nil && version.DeleteMarker
+		return result, nil, nil
 	}
 
-	if fileExists {
-		return fileModel.Key, fileModel.ETag, fmt.Errorf("file %s already exists in %s bucket", fileModel.Key, bucketName)
+	if bucket.Versioning == model.VersioningUnversioned {
+		return result, nil, nil
 	}
 
-	if err := fs.fileRepository.New(&fileModel); err != nil {
+	marker := model.NewFile(*bucket, object.Key, fs.clock)
+	marker.VersionID = newVersionID(bucket)
+	marker.DeleteMarker = true
+
+	result.DeleteMarker = true
+	result.DeleteMarkerVersionID = marker.VersionID
+	return result, &marker, nil
+}
+
+// Upload streams data into the file with the given key in the specified bucket, computing its ETag
+// on the fly. The key may contain slashes (e.g. "reports/2026/10/summary.csv"), and its extension
+// helps detecting the content type. As S3 PUT does, the file becomes the latest version of the key:
+// while the versioning of the bucket is enabled it gets a new version ID and the previous versions
+// are kept, and otherwise it replaces the "null" version of the key, keeping its CreatedAt.
+// It returns the key and ETag of the stored file, domain.ErrNoSuchBucket if the bucket does not exist,
+// domain.ErrInvalidArgument or domain.ErrKeyTooLong if key is not a valid key,
+// or an error if there was a failure while reading data or during the write.
+func (fs *fileService) Upload(bucketName string, data io.Reader, key string) (string, string, error) {
+	file, err := fs.UploadWithOptions(bucketName, data, key, model.PutOptions{})
+	if err != nil {
 		return "", "", err
 	}
 
+	return file.Key, file.ETag, nil
+}
+
+// UploadWithOptions streams data into a file of the specified bucket, as Upload does, storing
+// options.Metadata and options.Tags with it, with options.ContentType as its content type unless it is
+// empty, and applying the conditional write headers of S3 in options.Preconditions:
+// with IfNoneMatch set to "*" the file is only created if the key has no current object, and with
+// IfMatch set the current object is replaced only if its ETag matches, which the repository checks
+// atomically with the write. The modification time conditions are ignored.
+//
+// It returns the stored file, with its version ID, domain.ErrNoSuchBucket if the bucket does not exist,
+// domain.ErrInvalidArgument or domain.ErrKeyTooLong if key is not a valid key,
+// domain.ErrMetadataTooLarge if the user-defined metadata exceeds 2 KB, domain.ErrInvalidTag if the
+// tags break the S3 tagging limits, domain.ErrPreconditionFailed if a condition does not hold,
+// domain.ErrNoSuchKey if IfMatch is set and the file does not exist, domain.ErrNotImplemented if
+// IfNoneMatch is not "*", or an error if there was a failure while reading data or during the write.
+func (fs *fileService) UploadWithOptions(bucketName string, data io.Reader, key string, options model.PutOptions) (model.File, error) {
+	conditions := options.Preconditions
+	if conditions.IfNoneMatch != "" && conditions.IfNoneMatch != "*" {
+		return model.File{}, domain.ErrNotImplemented.WithMessage("If-None-Match only supports the value * on uploads")
+	}
+
+	if err := validateKey(key); err != nil {
+		return model.File{}, err
+	}
+
+	if err := validateMetadata(options.Metadata); err != nil {
+		return model.File{}, err
+	}
+
+	if err := validateTags(options.Tags, model.MaxObjectTags, "Object"); err != nil {
+		return model.File{}, err
+	}
+
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	fileModel := model.NewFile(*bucket, key, fs.clock)
+	fileModel.VersionID = newVersionID(bucket)
+
+	// Fail early, before reading the data; the repository checks the conditions again atomically
+	var currentETag string
+	switch {
+	case conditions.IfMatch != "":
+		current, err := findFile(fs.fileRepository, bucket, fileModel.Key)
+		if err != nil {
+			return model.File{}, err
+		}
+
+		if !etagMatches(conditions.IfMatch, current.ETag) {
+			return model.File{}, domain.ErrPreconditionFailed
+		}
+		currentETag = current.ETag
+	case conditions.IfNoneMatch == "*":
+		fileExists, err := fs.bucketRepository.FileExists(bucketName, fileModel.Key)
+		if err != nil {
+			return model.File{}, err
+		}
+
+		if fileExists {
+			return model.File{}, domain.ErrPreconditionFailed
+		}
+	}
+
+	content, err := storeStream(fs.blobs, data)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	fileModel.BlobRef = content.ref
+	fileModel.ETag = content.etag
+	fileModel.Size = content.size
+	fileModel.ContentType = options.ContentType
+	if fileModel.ContentType == "" {
+		fileModel.ContentType = model.DetectContentType(content.head, key)
+	}
+	fileModel.Metadata = options.Metadata
+	fileModel.Tags = options.Tags
+
+	released, err := fs.write(&fileModel, conditions, currentETag)
+	if err != nil {
+		releaseBlobs(fs.blobs, []string{fileModel.BlobRef})
+		return model.File{}, err
+	}
+	releaseBlobs(fs.blobs, released)
+
 	log.Printf("[S3EGO] RECEIVED NEW FILE: %s/%s/%s", bucket.Name, fileModel.Key, fileModel.ETag)
-	return fileModel.Key, fileModel.ETag, nil
+	return fileModel, nil
+}
+
+// Copy copies the file sourceKey of sourceBucket to key in the specified bucket without reading its data:
+// the copy shares the immutable blob of the source, so no bytes are duplicated in the BlobStore.
+// The copy keeps the ETag and size of the source, either its metadata and content type or options.Metadata
+// and options.ContentType, as options.MetadataDirective says, and either its tags or options.Tags, as
+// options.TaggingDirective says. The source is the version options.SourceVersionID of sourceKey,
+// or its current version if empty, and the copy becomes the latest version of key, as with Upload.
+//
+// Returns the stored copy, domain.ErrNoSuchBucket or domain.ErrNoSuchKey if the source or the destination
+// bucket does not exist, domain.ErrNoSuchVersion if the source version does not exist,
+// domain.ErrInvalidRequest if the source version is a delete marker, domain.ErrPreconditionFailed if
+// options.SourceConditions do not hold, domain.ErrInvalidArgument if the directive or the key is invalid,
+// domain.ErrKeyTooLong, domain.ErrMetadataTooLarge, domain.ErrInvalidTag, domain.ErrInvalidRequest if a
+// file would be copied onto itself without replacing its metadata, or an error if the write fails.
+func (fs *fileService) Copy(sourceBucket string, sourceKey string, bucketName string, key string, options model.CopyOptions) (model.File, error) {
+	if err := validateKey(key); err != nil {
+		return model.File{}, err
+	}
+
+	directive := options.MetadataDirective
+	switch directive {
+	case "", model.DirectiveCopy:
+		directive = model.DirectiveCopy
+	case model.DirectiveReplace:
+		if err := validateMetadata(options.Metadata); err != nil {
+			return model.File{}, err
+		}
+	default:
+		return model.File{}, domain.ErrInvalidArgument.WithMessage("Unknown metadata directive: %s", directive)
+	}
+
+	switch options.TaggingDirective {
+	case "", model.DirectiveCopy:
+	case model.DirectiveReplace:
+		if err := validateTags(options.Tags, model.MaxObjectTags, "Object"); err != nil {
+			return model.File{}, err
+		}
+	default:
+		return model.File{}, domain.ErrInvalidArgument.WithMessage("Unknown tagging directive: %s", options.TaggingDirective)
+	}
+
+	srcBucket, err := findBucket(fs.bucketRepository, sourceBucket)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	source, err := findCopySource(fs.fileRepository, srcBucket, sourceKey, options.SourceVersionID)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	if err := checkPreconditions(options.SourceConditions, source, domain.ErrPreconditionFailed); err != nil {
+		return model.File{}, err
+	}
+
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	// Copying an older version onto its own key restores it, which is allowed
+	if bucket.ID == srcBucket.ID && key == sourceKey && options.SourceVersionID == "" && directive == model.DirectiveCopy {
+		return model.File{}, domain.ErrInvalidRequest.WithMessage("This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata, storage class, website redirect location or encryption attributes.")
+	}
+
+	file := model.NewFile(*bucket, key, fs.clock)
+	file.VersionID = newVersionID(bucket)
+	file.BlobRef = source.BlobRef
+	file.ETag = source.ETag
+	file.ContentType = source.ContentType
+	file.Size = source.Size
+	file.PartSizes = source.PartSizes
+	file.Metadata = source.Metadata
+	if directive == model.DirectiveReplace {
+		file.Metadata = options.Metadata
+		if options.ContentType != "" {
+			file.ContentType = options.ContentType
+		}
+	}
+	file.Tags = source.Tags
+	if options.TaggingDirective == model.DirectiveReplace {
+		file.Tags = options.Tags
+	}
+
+	released, err := fs.fileRepository.PutCopy(&file)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.File{}, domain.ErrNoSuchKey
+	}
+	if err != nil {
+		return model.File{}, err
+	}
+	releaseBlobs(fs.blobs, released)
+
+	log.Printf("[S3EGO] COPIED FILE: %s/%s TO %s/%s", srcBucket.Name, sourceKey, bucket.Name, key)
+	return file, nil
+}
+
+// GetTagging returns the version versionID of a file, or its current version if versionID is empty,
+// with its tag set in Tags.
+// Returns domain.ErrNoSuchBucket, domain.ErrNoSuchKey or domain.ErrNoSuchVersion if the file doesn't exist
+// or its latest version is a delete marker, or domain.ErrMethodNotAllowed if versionID is a delete marker,
+// which cannot be tagged.
+func (fs *fileService) GetTagging(bucketName string, key string, versionID string) (model.File, error) {
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	file, err := findVersion(fs.fileRepository, bucket, key, versionID)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	return *file, nil
+}
+
+// PutTagging replaces the tag set of the version versionID of a file, or of its current version if
+// versionID is empty, without creating a new version. An empty tag set removes the tags of the version.
+// Returns the tagged version, domain.ErrInvalidTag if the tags break the S3 tagging limits,
+// the errors described in GetTagging, or an error if the write fails.
+func (fs *fileService) PutTagging(bucketName string, key string, versionID string, tags map[string]string) (model.File, error) {
+	if err := validateTags(tags, model.MaxObjectTags, "Object"); err != nil {
+		return model.File{}, err
+	}
+
+	bucket, err := findBucket(fs.bucketRepository, bucketName)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	file, err := findVersion(fs.fileRepository, bucket, key, versionID)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	err = fs.fileRepository.SetTags(file.ID, tags)
+	if errors.Is(err, repository.ErrNotFound) {
+		// The version was deleted since it was looked up
+		if versionID != "" {
+			return model.File{}, domain.ErrNoSuchVersion
+		}
+		return model.File{}, domain.ErrNoSuchKey
+	}
+	if err != nil {
+		return model.File{}, err
+	}
+	file.Tags = tags
+
+	log.Printf("[S3EGO] FILE TAGGED: %s/%s (%d tags)", bucket.Name, key, len(tags))
+	return *file, nil
+}
+
+// RemoveTagging removes the tag set of the version versionID of a file, or of its current version if
+// versionID is empty, as PutTagging does with an empty tag set.
+func (fs *fileService) RemoveTagging(bucketName string, key string, versionID string) (model.File, error) {
+	return fs.PutTagging(bucketName, key, versionID, nil)
+}
+
+// write stores the metadata of an uploaded file with the repository write matching its conditions:
+// a compare-and-swap on currentETag for If-Match, a create-only insert for "If-None-Match: *",
+// or an unconditional write otherwise.
+// Returns the references of the released blobs, domain.ErrNoSuchKey or domain.ErrPreconditionFailed
+// if the file changed since the conditions were checked, or an error if the write fails.
+func (fs *fileService) write(file *model.File, conditions model.Preconditions, currentETag string) ([]string, error) {
+	switch {
+	case conditions.IfMatch != "":
+		released, err := fs.fileRepository.Replace(file, currentETag)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, domain.ErrNoSuchKey
+		case errors.Is(err, repository.ErrConditionFailed):
+			return nil, domain.ErrPreconditionFailed
+		}
+		return released, err
+	case conditions.IfNoneMatch == "*":
+		released, err := fs.fileRepository.New(file)
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return nil, domain.ErrPreconditionFailed
+		}
+		return released, err
+	default:
+		return fs.fileRepository.Put(file)
+	}
+}
+
+// validateMetadata checks the user-defined metadata of an object against the S3 size limit.
+// Returns domain.ErrMetadataTooLarge if it exceeds model.MaxUserMetadataSize.
+func validateMetadata(metadata model.Metadata) error {
+	if size := metadata.UserSize(); size > model.MaxUserMetadataSize {
+		return domain.ErrMetadataTooLarge.WithMessage(
+			"Your metadata headers exceed the maximum allowed metadata size: %d bytes, maximum is %d",
+			size, model.MaxUserMetadataSize,
+		)
+	}
+
+	return nil
+}
+
+// validateKey checks an object key against the S3 rules: a non-empty UTF-8 string of at most
+// model.MaxKeyLength bytes. Returns domain.ErrInvalidArgument or domain.ErrKeyTooLong otherwise.
+func validateKey(key string) error {
+	switch {
+	case key == "":
+		return domain.ErrInvalidArgument.WithMessage("The object key must not be empty")
+	case len(key) > model.MaxKeyLength:
+		return domain.ErrKeyTooLong.WithMessage("Your key is too long: %d bytes, maximum is %d", len(key), model.MaxKeyLength)
+	case !utf8.ValidString(key):
+		return domain.ErrInvalidArgument.WithMessage("The object key must be valid UTF-8")
+	}
+
+	return nil
+}
+
+// findFile retrieves the current version of a file of the given bucket by key, as findVersion does.
+func findFile(fileRepository repository.FileRepository, bucket *model.Bucket, key string) (*model.File, error) {
+	return findVersion(fileRepository, bucket, key, "")
+}
+
+// findVersion retrieves a version of a file of the given bucket by key, the latest one if versionID is empty.
+// A missing file is reported as domain.ErrNoSuchKey and a missing version as domain.ErrNoSuchVersion.
+// A latest version that is a delete marker is returned along with domain.ErrNoSuchKey, and a requested
+// version that is one along with domain.ErrMethodNotAllowed, as S3 answers reads of delete markers.
+// The file is Versioned when versioning is enabled or suspended on the bucket.
+func findVersion(fileRepository repository.FileRepository, bucket *model.Bucket, key string, versionID string) (*model.File, error) {
+	file, err := getVersion(fileRepository, bucket, key, versionID)
+	if file != nil {
+		file.Versioned = bucket.Versioning != model.VersioningUnversioned
+	}
+	return file, err
+}
+
+// getVersion retrieves the version of a file for findVersion.
+func getVersion(fileRepository repository.FileRepository, bucket *model.Bucket, key string, versionID string) (*model.File, error) {
+	if versionID == "" {
+		file, err := fileRepository.GetByKey(bucket.ID, key)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, domain.ErrNoSuchKey
+		case err != nil:
+			return nil, err
+		case file.DeleteMarker:
+			return file, domain.ErrNoSuchKey
+		}
+		return file, nil
+	}
+
+	file, err := fileRepository.GetVersion(bucket.ID, key, versionID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil, domain.ErrNoSuchVersion
+	case err != nil:
+		return nil, err
+	case file.DeleteMarker:
+		return file, domain.ErrMethodNotAllowed
+	}
+	return file, nil
+}
+
+// findCopySource retrieves the version of a file copied by a copy request, as findVersion does,
+// reporting a requested version that is a delete marker as domain.ErrInvalidRequest, as S3 does.
+func findCopySource(fileRepository repository.FileRepository, bucket *model.Bucket, key string, versionID string) (*model.File, error) {
+	file, err := findVersion(fileRepository, bucket, key, versionID)
+	if errors.Is(err, domain.ErrMethodNotAllowed) {
+		return nil, domain.ErrInvalidRequest.WithMessage("The source of a copy request may not specifically refer to a delete marker by version id.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// newVersionID returns the version ID of a file written to bucket: a new unique ID while the
+// versioning of the bucket is enabled, or model.NullVersionID otherwise.
+func newVersionID(bucket *model.Bucket) string {
+	if bucket.Versioning == model.VersioningEnabled {
+		return strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+
+	return model.NullVersionID
 }
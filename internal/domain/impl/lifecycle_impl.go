@@ -0,0 +1,405 @@
+// Package domain contains the business logic for managing buckets and files.
+package impl
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Limits of the lifecycle rules accepted by S3.
+const (
+	maxLifecycleRuleIDLength   = 255
+	maxNewerNoncurrentVersions = 100
+)
+
+// LifecycleService manages the lifecycle rules of buckets and applies them.
+// Objects, versions and uploads are expired through the file and multipart services,
+// so they are deleted exactly as client requests would delete them.
+type lifecycleService struct {
+	lifecycleRepository repository.LifecycleRepository
+	bucketRepository    repository.BucketRepository
+	fileService         domain.FileService
+	multipartService    domain.MultipartService
+	clock               clock.Clock
+
+	// applying serializes the passes of Apply, so a key is never expired twice at once.
+	applying sync.Mutex
+}
+
+// NewLifecycleService creates a new LifecycleService with the provided repositories and services.
+// clock tells the time the rules are applied at.
+func NewLifecycleService(
+	lifecycleRepository repository.LifecycleRepository,
+	bucketRepository repository.BucketRepository,
+	fileService domain.FileService,
+	multipartService domain.MultipartService,
+	clock clock.Clock,
+) domain.LifecycleService {
+	return &lifecycleService{
+		lifecycleRepository: lifecycleRepository,
+		bucketRepository:    bucketRepository,
+		fileService:         fileService,
+		multipartService:    multipartService,
+		clock:               clock,
+	}
+}
+
+// Put replaces the lifecycle rules of a bucket. Rules without an ID are given a unique one.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, domain.ErrMalformedXML,
+// domain.ErrInvalidArgument or domain.ErrInvalidRequest if the rules are not valid,
+// or an error if they cannot be stored.
+func (ls *lifecycleService) Put(bucketName string, rules []model.LifecycleRule) error {
+	rules = slices.Clone(rules)
+	if err := validateLifecycleRules(rules); err != nil {
+		return err
+	}
+
+	bucket, err := findBucket(ls.bucketRepository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	if err := ls.lifecycleRepository.Put(bucket.ID, rules); err != nil {
+		return err
+	}
+
+	log.Printf("[S3EGO] BUCKET LIFECYCLE CONFIGURED: %s (%d rules)", bucket.Name, len(rules))
+	return nil
+}
+
+// Get returns the lifecycle rules of a bucket, in the order they were configured.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, or domain.ErrNoSuchLifecycleConfig
+// if the bucket has no lifecycle rules.
+func (ls *lifecycleService) Get(bucketName string) ([]model.LifecycleRule, error) {
+	bucket, err := findBucket(ls.bucketRepository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := ls.lifecycleRepository.Get(bucket.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, domain.ErrNoSuchLifecycleConfig
+	}
+
+	return rules, err
+}
+
+// Remove deletes the lifecycle rules of a bucket. As in S3, removing missing rules succeeds.
+// It returns domain.ErrNoSuchBucket if the bucket doesn't exist, or an error if the deletion fails.
+func (ls *lifecycleService) Remove(bucketName string) error {
+	bucket, err := findBucket(ls.bucketRepository, bucketName)
+	if err != nil {
+		return err
+	}
+
+	if err := ls.lifecycleRepository.Remove(bucket.ID); err != nil {
+		return err
+	}
+
+	log.Println("[S3EGO] BUCKET LIFECYCLE REMOVED:", bucket.Name)
+	return nil
+}
+
+// Apply runs a single pass of the enabled lifecycle rules of every bucket at the current time of
+// the clock. Current versions past their expiration are deleted, or hidden behind a delete marker in
+// versioned buckets, noncurrent versions past their expiration are deleted permanently, delete markers
+// left without noncurrent versions are removed, and incomplete multipart uploads are aborted.
+// Returns what the pass expired, or an error if a bucket cannot be processed, in which case the
+// buckets sorted after it are not processed.
+func (ls *lifecycleService) Apply() (model.LifecycleRun, error) {
+	ls.applying.Lock()
+	defer ls.applying.Unlock()
+
+	now := ls.clock.Now()
+	var run model.LifecycleRun
+
+	buckets, err := ls.lifecycleRepository.ListBuckets()
+	if err != nil {
+		return run, err
+	}
+
+	for _, bucket := range buckets {
+		rules, err := ls.lifecycleRepository.Get(bucket.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return run, err
+		}
+
+		rules = slices.DeleteFunc(rules, func(rule model.LifecycleRule) bool {
+			return rule.Status != model.LifecycleEnabled
+		})
+
+		err = ls.expireVersions(&bucket, rules, now, &run)
+		if err == nil {
+			err = ls.abortUploads(&bucket, rules, now, &run)
+		}
+		// A bucket deleted during the pass has nothing left to expire.
+		if err != nil && !errors.Is(err, domain.ErrNoSuchBucket) {
+			return run, err
+		}
+	}
+
+	if run != (model.LifecycleRun{}) {
+		log.Printf("[S3EGO] LIFECYCLE APPLIED: %d objects, %d versions and %d delete markers expired, %d uploads aborted",
+			run.ExpiredObjects, run.ExpiredVersions, run.ExpiredDeleteMarkers, run.AbortedUploads)
+	}
+	return run, nil
+}
+
+// expireVersions walks every version of the bucket, key by key, and deletes the expired ones
+// in batches of at most model.MaxDeleteObjects. The tags of the versions are only read when
+// a rule filters on tags.
+func (ls *lifecycleService) expireVersions(bucket *model.Bucket, rules []model.LifecycleRule, now time.Time, run *model.LifecycleRun) error {
+	expiresVersions := func(rule model.LifecycleRule) bool {
+		return rule.ExpirationDays > 0 || !rule.ExpirationDate.IsZero() || rule.ExpiredObjectDeleteMarker || rule.NoncurrentDays > 0
+	}
+	if !slices.ContainsFunc(rules, expiresVersions) {
+		return nil
+	}
+
+	filtersTags := slices.ContainsFunc(rules, func(rule model.LifecycleRule) bool { return len(rule.Tags) > 0 })
+
+	expired := make([]model.ObjectIdentifier, 0)
+	versions := make([]model.File, 0)
+	expireKey := func() error {
+		if filtersTags {
+			if err := ls.loadTags(bucket, versions); err != nil {
+				return err
+			}
+		}
+		expired = append(expired, expiredVersions(versions, rules, now, run)...)
+		versions = versions[:0]
+		return nil
+	}
+
+	query := model.VersionQuery{MaxKeys: model.MaxListKeys}
+	for {
+		listing, err := ls.bucketRepository.ListVersions(bucket.ID, query)
+		if err != nil {
+			return err
+		}
+
+		for _, version := range listing.Versions {
+			if len(versions) > 0 && versions[0].Key != version.Key {
+				if err := expireKey(); err != nil {
+					return err
+				}
+			}
+			versions = append(versions, version)
+		}
+
+		if !listing.IsTruncated {
+			break
+		}
+		query.KeyMarker, query.VersionIDMarker = listing.NextKeyMarker, listing.NextVersionIDMarker
+	}
+	if len(versions) > 0 {
+		if err := expireKey(); err != nil {
+			return err
+		}
+	}
+
+	for batch := range slices.Chunk(expired, model.MaxDeleteObjects) {
+		if _, err := ls.fileService.RemoveObjects(bucket.Name, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTags fills the tags of the versions of a single key, which the tag filters of rules are
+// matched against. Versions deleted since they were listed are left without tags.
+func (ls *lifecycleService) loadTags(bucket *model.Bucket, versions []model.File) error {
+	for i := range versions {
+		if versions[i].DeleteMarker {
+			continue
+		}
+
+		tagged, err := ls.fileService.GetTagging(bucket.Name, versions[i].Key, versions[i].VersionID)
+		switch {
+		case errors.Is(err, domain.ErrNoSuchKey), errors.Is(err, domain.ErrNoSuchVersion), errors.Is(err, domain.ErrMethodNotAllowed):
+			continue
+		case err != nil:
+			return err
+		}
+		versions[i].Tags = tagged.Tags
+	}
+
+	return nil
+}
+
+// expiredVersions returns the objects to delete among the versions of a single key, newest first,
+// each matched against the filters of rules with its own tags, counting them in run: the key itself
+// when its current version expired, the noncurrent versions that expired, and the latest delete
+// marker once no noncurrent version is left.
+func expiredVersions(versions []model.File, rules []model.LifecycleRule, now time.Time, run *model.LifecycleRun) []model.ObjectIdentifier {
+	key := versions[0].Key
+	current := versions[0]
+	expired := make([]model.ObjectIdentifier, 0)
+
+	if current.IsLatest && !current.DeleteMarker {
+		if slices.ContainsFunc(rules, func(rule model.LifecycleRule) bool {
+			return rule.Matches(key, current.Tags) && currentExpired(rule, current, now)
+		}) {
+			expired = append(expired, model.ObjectIdentifier{Key: key})
+			run.ExpiredObjects++
+		}
+	}
+
+	// A version becomes noncurrent when the next newer version of its key is created.
+	for i := 1; i < len(versions); i++ {
+		noncurrentSince := versions[i-1].LastModified
+		isExpired := slices.ContainsFunc(rules, func(rule model.LifecycleRule) bool {
+			return rule.Matches(key, versions[i].Tags) && rule.NoncurrentDays > 0 && i > rule.NewerNoncurrentVersions &&
+				!now.Before(model.LifecycleExpiry(noncurrentSince, rule.NoncurrentDays))
+		})
+
+		if isExpired {
+			expired = append(expired, model.ObjectIdentifier{Key: key, VersionID: versions[i].VersionID})
+			run.ExpiredVersions++
+		}
+	}
+
+	if current.IsLatest && current.DeleteMarker && len(expired) == len(versions)-1 {
+		// Delete markers have no tags
+		if slices.ContainsFunc(rules, func(rule model.LifecycleRule) bool { return rule.Matches(key, nil) && rule.ExpiredObjectDeleteMarker }) {
+			expired = append(expired, model.ObjectIdentifier{Key: key, VersionID: current.VersionID})
+			run.ExpiredDeleteMarkers++
+		}
+	}
+
+	return expired
+}
+
+// currentExpired reports whether the Expiration action of rule expires the current version file at now.
+func currentExpired(rule model.LifecycleRule, file model.File, now time.Time) bool {
+	switch {
+	case rule.ExpirationDays > 0:
+		return !now.Before(model.LifecycleExpiry(file.LastModified, rule.ExpirationDays))
+	case !rule.ExpirationDate.IsZero():
+		return !now.Before(rule.ExpirationDate)
+	}
+
+	return false
+}
+
+// abortUploads aborts the multipart uploads of the bucket initiated longer ago than the
+// AbortIncompleteUploadDays of a rule matching their key.
+func (ls *lifecycleService) abortUploads(bucket *model.Bucket, rules []model.LifecycleRule, now time.Time, run *model.LifecycleRun) error {
+	expired := make(map[string]model.MultipartUpload)
+	for _, rule := range rules {
+		if rule.AbortIncompleteUploadDays == 0 {
+			continue
+		}
+
+		query := model.UploadQuery{Prefix: rule.Prefix, MaxUploads: model.MaxListKeys}
+		for {
+			listing, err := ls.multipartService.ListUploads(bucket.Name, query)
+			if err != nil {
+				return err
+			}
+
+			for _, upload := range listing.Uploads {
+				if !now.Before(model.LifecycleExpiry(upload.CreatedAt, rule.AbortIncompleteUploadDays)) {
+					expired[upload.UploadID] = upload
+				}
+			}
+
+			if !listing.IsTruncated {
+				break
+			}
+			last := listing.Uploads[len(listing.Uploads)-1]
+			query.KeyMarker, query.UploadIDMarker = last.Key, last.UploadID
+		}
+	}
+
+	for _, upload := range expired {
+		err := ls.multipartService.Abort(bucket.Name, upload.Key, upload.UploadID)
+		// An upload completed or aborted during the pass is no longer incomplete.
+		if errors.Is(err, domain.ErrNoSuchUpload) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		run.AbortedUploads++
+	}
+
+	return nil
+}
+
+// validateLifecycleRules checks the rules of a lifecycle configuration against the S3 limits,
+// giving a unique ID to the rules without one.
+func validateLifecycleRules(rules []model.LifecycleRule) error {
+	if len(rules) == 0 || len(rules) > model.MaxLifecycleRules {
+		return domain.ErrMalformedXML.WithMessage("A lifecycle configuration must have between 1 and %d rules", model.MaxLifecycleRules)
+	}
+
+	ids := make(map[string]bool, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ID == "" {
+			rule.ID = strings.ReplaceAll(uuid.New().String(), "-", "")
+		}
+
+		if err := validateLifecycleRule(*rule); err != nil {
+			return err
+		}
+
+		if ids[rule.ID] {
+			return domain.ErrInvalidArgument.WithMessage("Rule ID must be unique. Found same ID for more than one rule")
+		}
+		ids[rule.ID] = true
+	}
+
+	return nil
+}
+
+// validateLifecycleRule checks a single lifecycle rule against the S3 limits.
+func validateLifecycleRule(rule model.LifecycleRule) error {
+	hasExpiration := rule.ExpirationDays != 0 || !rule.ExpirationDate.IsZero()
+
+	switch {
+	case len(rule.ID) > maxLifecycleRuleIDLength:
+		return domain.ErrInvalidArgument.WithMessage("ID length should not exceed allowed limit of %d", maxLifecycleRuleIDLength)
+	case rule.Status != model.LifecycleEnabled && rule.Status != model.LifecycleDisabled:
+		return domain.ErrMalformedXML.WithMessage("The lifecycle rule status must be %s or %s", model.LifecycleEnabled, model.LifecycleDisabled)
+	case !hasExpiration && !rule.ExpiredObjectDeleteMarker && rule.NoncurrentDays == 0 && rule.AbortIncompleteUploadDays == 0:
+		return domain.ErrInvalidRequest.WithMessage("At least one action needs to be specified in a rule")
+	case rule.ExpirationDays < 0:
+		return domain.ErrInvalidArgument.WithMessage("'Days' for Expiration action must be a positive integer")
+	case rule.ExpirationDays > 0 && !rule.ExpirationDate.IsZero():
+		return domain.ErrMalformedXML.WithMessage("Expiration cannot specify both Days and Date")
+	case !rule.ExpirationDate.IsZero() && !rule.ExpirationDate.Equal(rule.ExpirationDate.UTC().Truncate(24*time.Hour)):
+		return domain.ErrInvalidArgument.WithMessage("'Date' must be at midnight GMT")
+	case rule.ExpiredObjectDeleteMarker && hasExpiration:
+		return domain.ErrMalformedXML.WithMessage("ExpiredObjectDeleteMarker cannot be specified with Days or Date in a Lifecycle Expiration Policy.")
+	case rule.ExpiredObjectDeleteMarker && len(rule.Tags) > 0:
+		return domain.ErrInvalidRequest.WithMessage("ExpiredObjectDeleteMarker cannot be specified with object tag filters.")
+	case rule.NoncurrentDays < 0:
+		return domain.ErrInvalidArgument.WithMessage("'NoncurrentDays' for NoncurrentVersionExpiration action must be a positive integer")
+	case rule.NewerNoncurrentVersions != 0 && rule.NoncurrentDays == 0:
+		return domain.ErrInvalidArgument.WithMessage("NewerNoncurrentVersions requires NoncurrentDays to be specified")
+	case rule.NewerNoncurrentVersions < 0 || rule.NewerNoncurrentVersions > maxNewerNoncurrentVersions:
+		return domain.ErrInvalidArgument.WithMessage("NewerNoncurrentVersions must be between 1 and %d", maxNewerNoncurrentVersions)
+	case rule.AbortIncompleteUploadDays < 0:
+		return domain.ErrInvalidArgument.WithMessage("'DaysAfterInitiation' for AbortIncompleteMultipartUpload action must be a positive integer")
+	case rule.AbortIncompleteUploadDays > 0 && len(rule.Tags) > 0:
+		return domain.ErrInvalidRequest.WithMessage("AbortIncompleteMultipartUpload cannot be specified with Tags.")
+	}
+
+	return nil
+}
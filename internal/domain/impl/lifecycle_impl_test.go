@@ -0,0 +1,143 @@
+package impl
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+func TestExpiredVersions(t *testing.T) {
+	created := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(created)
+
+	// version returns a version of key "logs/a.txt" written at the current time of the clock.
+	version := func(versionID string, deleteMarker bool, tags map[string]string) model.File {
+		file := model.NewFile(model.Bucket{}, "logs/a.txt", fake)
+		file.VersionID = versionID
+		file.DeleteMarker = deleteMarker
+		file.Tags = tags
+		return file
+	}
+
+	current := version(model.NullVersionID, false, nil)
+	tagged := version(model.NullVersionID, false, map[string]string{"temp": "yes"})
+
+	// A key with three versions written a day apart, newest first.
+	v1 := version("v1", false, nil)
+	fake.Advance(24 * time.Hour)
+	v2 := version("v2", false, nil)
+	fake.Advance(24 * time.Hour)
+	v3 := version("v3", false, nil)
+	v1.IsLatest, v2.IsLatest = false, false
+	history := []model.File{v3, v2, v1}
+
+	marker := version("m1", true, nil)
+
+	enabled := func(rule model.LifecycleRule) []model.LifecycleRule {
+		rule.ID, rule.Status = "rule", model.LifecycleEnabled
+		return []model.LifecycleRule{rule}
+	}
+
+	tests := []struct {
+		name     string
+		versions []model.File
+		rules    []model.LifecycleRule
+		now      time.Time
+		want     []model.ObjectIdentifier
+		wantRun  model.LifecycleRun
+	}{
+		{
+			name:     "current version before its expiry",
+			versions: []model.File{current},
+			rules:    enabled(model.LifecycleRule{ExpirationDays: 1}),
+			now:      time.Date(2026, 1, 2, 23, 59, 59, 0, time.UTC),
+			want:     []model.ObjectIdentifier{},
+		},
+		{
+			name:     "current version at the midnight after its expiry",
+			versions: []model.File{current},
+			rules:    enabled(model.LifecycleRule{ExpirationDays: 1}),
+			now:      time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC),
+			want:     []model.ObjectIdentifier{{Key: "logs/a.txt"}},
+			wantRun:  model.LifecycleRun{ExpiredObjects: 1},
+		},
+		{
+			name:     "expiration date",
+			versions: []model.File{current},
+			rules:    enabled(model.LifecycleRule{ExpirationDate: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}),
+			now:      time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			want:     []model.ObjectIdentifier{{Key: "logs/a.txt"}},
+			wantRun:  model.LifecycleRun{ExpiredObjects: 1},
+		},
+		{
+			name:     "prefix not matching",
+			versions: []model.File{current},
+			rules:    enabled(model.LifecycleRule{Prefix: "tmp/", ExpirationDays: 1}),
+			now:      created.AddDate(1, 0, 0),
+			want:     []model.ObjectIdentifier{},
+		},
+		{
+			name:     "tag filter matching",
+			versions: []model.File{tagged},
+			rules:    enabled(model.LifecycleRule{Tags: map[string]string{"temp": "yes"}, ExpirationDays: 1}),
+			now:      created.AddDate(1, 0, 0),
+			want:     []model.ObjectIdentifier{{Key: "logs/a.txt"}},
+			wantRun:  model.LifecycleRun{ExpiredObjects: 1},
+		},
+		{
+			name:     "tag filter not matching",
+			versions: []model.File{current},
+			rules:    enabled(model.LifecycleRule{Tags: map[string]string{"temp": "yes"}, ExpirationDays: 1}),
+			now:      created.AddDate(1, 0, 0),
+			want:     []model.ObjectIdentifier{},
+		},
+		{
+			name:     "noncurrent versions expire from when they became noncurrent",
+			versions: history,
+			rules:    enabled(model.LifecycleRule{NoncurrentDays: 1}),
+			now:      time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+			want:     []model.ObjectIdentifier{{Key: "logs/a.txt", VersionID: "v1"}},
+			wantRun:  model.LifecycleRun{ExpiredVersions: 1},
+		},
+		{
+			name:     "newer noncurrent versions are kept",
+			versions: history,
+			rules:    enabled(model.LifecycleRule{NoncurrentDays: 1, NewerNoncurrentVersions: 1}),
+			now:      created.AddDate(1, 0, 0),
+			want:     []model.ObjectIdentifier{{Key: "logs/a.txt", VersionID: "v1"}},
+			wantRun:  model.LifecycleRun{ExpiredVersions: 1},
+		},
+		{
+			name:     "delete marker left alone is removed",
+			versions: []model.File{marker, v1},
+			rules:    enabled(model.LifecycleRule{NoncurrentDays: 1, ExpiredObjectDeleteMarker: true}),
+			now:      created.AddDate(1, 0, 0),
+			want:     []model.ObjectIdentifier{{Key: "logs/a.txt", VersionID: "v1"}, {Key: "logs/a.txt", VersionID: "m1"}},
+			wantRun:  model.LifecycleRun{ExpiredVersions: 1, ExpiredDeleteMarkers: 1},
+		},
+		{
+			name:     "delete marker with noncurrent versions is kept",
+			versions: []model.File{marker, v1},
+			rules:    enabled(model.LifecycleRule{ExpiredObjectDeleteMarker: true}),
+			now:      created.AddDate(1, 0, 0),
+			want:     []model.ObjectIdentifier{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var run model.LifecycleRun
+			got := expiredVersions(tt.versions, tt.rules, tt.now, &run)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expiredVersions() = %v, want %v", got, tt.want)
+			}
+			if run != tt.wantRun {
+				t.Errorf("run = %+v, want %+v", run, tt.wantRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+// Package domain contains the business logic for managing buckets and files.
+package impl
+
+import (
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
+	"github.com/google/uuid"
+)
+
+// MultipartService provides methods to upload objects in parts, as S3 multipart uploads do.
+// Parts are stored by the MultipartRepository, with their data in the BlobStore,
+// until the upload is completed or aborted.
+type multipartService struct {
+	multipartRepository repository.MultipartRepository
+	fileRepository      repository.FileRepository
+	bucketRepository    repository.BucketRepository
+	blobs               storage.BlobStore
+	minPartSize         int64
+	clock               clock.Clock
+}
+
+// NewMultipartService creates a new MultipartService with the provided repositories and blob store.
+// minPartSize is the minimum size in bytes of every part of an upload except the last one.
+// Uploads, parts and completed objects are stamped with the current time of the given clock.
+func NewMultipartService(
+	multipartRepository repository.MultipartRepository,
+	fileRepository repository.FileRepository,
+	bucketRepository repository.BucketRepository,
+	blobs storage.BlobStore,
+	minPartSize int64,
+	clock clock.Clock,
+) domain.MultipartService {
+	return &multipartService{
+		multipartRepository: multipartRepository,
+		fileRepository:      fileRepository,
+		bucketRepository:    bucketRepository,
+		blobs:               blobs,
+		minPartSize:         minPartSize,
+		clock:               clock,
+	}
+}
+
+// Create initiates a multipart upload of the given key in a bucket.
+// contentType is used for the final object; if empty it is detected when the upload completes.
+// metadata is stored with the final object.
+// Returns the upload ID, domain.ErrNoSuchBucket if the bucket does not exist,
+// domain.ErrInvalidArgument or domain.ErrKeyTooLong if key is not a valid key,
+// or domain.ErrMetadataTooLarge if the user-defined metadata exceeds 2 KB.
+func (ms *multipartService) Create(bucketName string, key string, contentType string, metadata model.Metadata) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+
+	if err := validateMetadata(metadata); err != nil {
+		return "", err
+	}
+
+	bucket, err := findBucket(ms.bucketRepository, bucketName)
+	if err != nil {
+		return "", err
+	}
+
+	upload := model.MultipartUpload{
+		UploadID:    strings.ReplaceAll(uuid.New().String(), "-", ""),
+		BucketID:    uint(bucket.ID),
+		Key:         key,
+		ContentType: contentType,
+		Metadata:    metadata,
+		CreatedAt:   ms.clock.Now(),
+	}
+
+	if err := ms.multipartRepository.New(&upload); err != nil {
+		return "", err
+	}
+
+	log.Printf("[S3EGO] STARTED MULTIPART UPLOAD: %s/%s/%s", bucket.Name, key, upload.UploadID)
+	return upload.UploadID, nil
+}
+
+// UploadPart streams a part of an in-progress multipart upload into storage, replacing any part with the same number.
+// Returns the part ETag, domain.ErrNoSuchUpload if the upload does not exist,
+// or domain.ErrInvalidArgument if the part number is out of range.
+func (ms *multipartService) UploadPart(bucketName string, key string, uploadID string, partNumber int, data io.Reader) (string, error) {
+	upload, err := ms.findUpload(bucketName, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	part, err := ms.putPart(upload, partNumber, data)
+	if err != nil {
+		return "", err
+	}
+
+	return part.ETag, nil
+}
+
+// UploadPartCopy stores a part of an in-progress multipart upload using the data of an existing file,
+// optionally restricted to sourceRange. sourceKey is the object key within sourceBucket,
+// and sourceVersionID its version, empty for the current one.
+// The source file must meet sourceConditions (the x-amz-copy-source-if-* headers).
+// Returns the stored part, domain.ErrNoSuchUpload if the upload does not exist, domain.ErrNoSuchBucket,
+// domain.ErrNoSuchKey or domain.ErrNoSuchVersion if the source does not exist, domain.ErrInvalidRequest
+// if the source version is a delete marker, domain.ErrPreconditionFailed if the source does not meet
+// the conditions, or domain.ErrInvalidArgument if the part number or the source range is invalid.
+func (ms *multipartService) UploadPartCopy(
+	bucketName string,
+	key string,
+	uploadID string,
+	partNumber int,
+	sourceBucket string,
+	sourceKey string,
+	sourceVersionID string,
+	sourceRange *model.ByteRange,
+	sourceConditions model.Preconditions,
+) (model.Part, error) {
+	upload, err := ms.findUpload(bucketName, key, uploadID)
+	if err != nil {
+		return model.Part{}, err
+	}
+
+	bucket, err := findBucket(ms.bucketRepository, sourceBucket)
+	if err != nil {
+		return model.Part{}, err
+	}
+
+	source, err := findCopySource(ms.fileRepository, bucket, sourceKey, sourceVersionID)
+	if err != nil {
+		return model.Part{}, err
+	}
+
+	if err := checkPreconditions(sourceConditions, source, domain.ErrPreconditionFailed); err != nil {
+		return model.Part{}, err
+	}
+
+	if sourceRange != nil {
+		if sourceRange.Start < 0 || sourceRange.End < sourceRange.Start || sourceRange.End >= source.Size {
+			return model.Part{}, domain.ErrInvalidArgument.WithMessage("Range specified is not valid for source object of size: %d", source.Size)
+		}
+	}
+
+	if sourceRange == nil {
+		sourceRange = &model.ByteRange{Start: 0, End: source.Size - 1}
+	}
+
+	data, err := openBlobRange(ms.blobs, source.BlobRef, *sourceRange)
+	if err != nil {
+		return model.Part{}, err
+	}
+	defer data.Close()
+
+	return ms.putPart(upload, partNumber, data)
+}
+
+// ListParts returns the parts uploaded so far, numbered after partNumberMarker, up to maxParts.
+// Returns domain.ErrNoSuchUpload if the upload does not exist.
+func (ms *multipartService) ListParts(bucketName string, key string, uploadID string, partNumberMarker int, maxParts int) (*model.PartListing, error) {
+	upload, err := ms.findUpload(bucketName, key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.multipartRepository.ListParts(upload.ID, partNumberMarker, maxParts)
+}
+
+// ListUploads returns the in-progress multipart uploads of a bucket matching the query.
+// Returns domain.ErrNoSuchBucket if the bucket does not exist.
+func (ms *multipartService) ListUploads(bucketName string, query model.UploadQuery) (*model.UploadListing, error) {
+	bucket, err := findBucket(ms.bucketRepository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms.multipartRepository.List(bucket.ID, query)
+}
+
+// Complete assembles the listed parts into the final object and removes the upload.
+//
+// Parts must be listed in ascending part number order and match the uploaded part ETags,
+// and every part except the last must be at least the minimum part size. The object ETag is
+// the S3 composite "md5-of-md5s-N" ETag.
+//
+// Returns the stored file, domain.ErrNoSuchUpload if the upload does not exist, domain.ErrMalformedXML
+// if no parts are listed, domain.ErrInvalidPartOrder, domain.ErrInvalidPart or domain.ErrEntityTooSmall
+// if the parts are invalid. As with a PUT, the object becomes the latest version of its key.
+func (ms *multipartService) Complete(bucketName string, key string, uploadID string, completedParts []model.CompletedPart) (model.File, error) {
+	upload, err := ms.findUpload(bucketName, key, uploadID)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	if len(completedParts) == 0 {
+		return model.File{}, domain.ErrMalformedXML
+	}
+
+	uploadedParts, err := ms.multipartRepository.GetParts(upload.ID)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	partsByNumber := make(map[int]model.Part, len(uploadedParts))
+	for _, part := range uploadedParts {
+		partsByNumber[part.PartNumber] = part
+	}
+
+	for i := 1; i < len(completedParts); i++ {
+		if completedParts[i].PartNumber <= completedParts[i-1].PartNumber {
+			return model.File{}, domain.ErrInvalidPartOrder
+		}
+	}
+
+	parts := make([]model.Part, 0, len(completedParts))
+	for i, completed := range completedParts {
+		part, ok := partsByNumber[completed.PartNumber]
+		if !ok || strings.Trim(completed.ETag, `"`) != part.ETag {
+			return model.File{}, domain.ErrInvalidPart
+		}
+
+		if i < len(completedParts)-1 && part.Size < ms.minPartSize {
+			return model.File{}, domain.ErrEntityTooSmall.WithMessage(
+				"Your proposed upload is smaller than the minimum allowed size: part %d is %d bytes, minimum is %d",
+				part.PartNumber, part.Size, ms.minPartSize,
+			)
+		}
+
+		parts = append(parts, part)
+	}
+
+	etag, err := model.MultipartETag(parts)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	bucket, err := findBucket(ms.bucketRepository, bucketName)
+	if err != nil {
+		return model.File{}, err
+	}
+
+	file := model.NewFile(*bucket, upload.Key, ms.clock)
+	file.VersionID = newVersionID(bucket)
+
+	refs := make([]string, 0, len(parts))
+	file.PartSizes = make([]int64, 0, len(parts))
+	for _, part := range parts {
+		refs = append(refs, part.BlobRef)
+		file.PartSizes = append(file.PartSizes, part.Size)
+	}
+
+	data := newConcatReader(ms.blobs, refs)
+	content, err := storeStream(ms.blobs, data)
+	data.Close()
+	if err != nil {
+		return model.File{}, err
+	}
+
+	file.BlobRef = content.ref
+	file.ETag = etag
+	file.Size = content.size
+	file.Metadata = upload.Metadata
+	file.ContentType = upload.ContentType
+	if file.ContentType == "" {
+		file.ContentType = model.DetectContentType(content.head, upload.Key)
+	}
+
+	released, err := ms.multipartRepository.Complete(upload, &file)
+	if err != nil {
+		releaseBlobs(ms.blobs, []string{file.BlobRef})
+		return model.File{}, err
+	}
+	releaseBlobs(ms.blobs, released)
+
+	log.Printf("[S3EGO] COMPLETED MULTIPART UPLOAD: %s/%s/%s", bucket.Name, file.Key, file.ETag)
+	return file, nil
+}
+
+// Abort discards an in-progress multipart upload and all of its parts.
+// Returns domain.ErrNoSuchUpload if the upload does not exist.
+func (ms *multipartService) Abort(bucketName string, key string, uploadID string) error {
+	upload, err := ms.findUpload(bucketName, key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	released, err := ms.multipartRepository.Remove(upload.ID)
+	if err != nil {
+		return err
+	}
+	releaseBlobs(ms.blobs, released)
+
+	log.Printf("[S3EGO] ABORTED MULTIPART UPLOAD: %s/%s/%s", bucketName, key, uploadID)
+	return nil
+}
+
+// findUpload retrieves an in-progress upload and ensures it belongs to the given bucket and key.
+// A missing upload, or one for another object, is reported as domain.ErrNoSuchUpload.
+func (ms *multipartService) findUpload(bucketName string, key string, uploadID string) (*model.MultipartUpload, error) {
+	bucket, err := findBucket(ms.bucketRepository, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := ms.multipartRepository.GetByUploadID(uploadID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, domain.ErrNoSuchUpload
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if int(upload.BucketID) != bucket.ID || upload.Key != key {
+		return nil, domain.ErrNoSuchUpload
+	}
+
+	return upload, nil
+}
+
+// putPart validates the part number and streams the part data into the given upload.
+func (ms *multipartService) putPart(upload *model.MultipartUpload, partNumber int, data io.Reader) (model.Part, error) {
+	if partNumber < 1 || partNumber > model.MaxPartNumber {
+		return model.Part{}, domain.ErrInvalidArgument.WithMessage("Part number must be an integer between 1 and %d, inclusive", model.MaxPartNumber)
+	}
+
+	content, err := storeStream(ms.blobs, data)
+	if err != nil {
+		return model.Part{}, err
+	}
+
+	part := model.NewPart(*upload, partNumber, ms.clock)
+	part.BlobRef = content.ref
+	part.ETag = content.etag
+	part.Size = content.size
+
+	released, err := ms.multipartRepository.PutPart(&part)
+	if err != nil {
+		releaseBlobs(ms.blobs, []string{part.BlobRef})
+		return model.Part{}, err
+	}
+	releaseBlobs(ms.blobs, released)
+
+	log.Printf("[S3EGO] RECEIVED PART %d OF MULTIPART UPLOAD: %s/%s", partNumber, upload.Key, upload.UploadID)
+	return part, nil
+}
@@ -0,0 +1,58 @@
+// Package domain contains the business logic for managing buckets and files.
+package impl
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+// checkPreconditions evaluates the conditions of a request against the current file,
+// with the precedence of RFC 7232: If-Match overrides If-Unmodified-Since, and
+// If-None-Match overrides If-Modified-Since.
+//
+// Returns domain.ErrPreconditionFailed if If-Match or If-Unmodified-Since does not hold,
+// or notModified if If-None-Match or If-Modified-Since does not hold: domain.ErrNotModified
+// for reads, and domain.ErrPreconditionFailed for copies.
+func checkPreconditions(conditions model.Preconditions, file *model.File, notModified *domain.Error) error {
+	// HTTP dates have a one second resolution
+	lastModified := file.LastModified.Truncate(time.Second)
+
+	if conditions.IfMatch != "" {
+		if !etagMatches(conditions.IfMatch, file.ETag) {
+			return domain.ErrPreconditionFailed
+		}
+	} else if !conditions.IfUnmodifiedSince.IsZero() && lastModified.After(conditions.IfUnmodifiedSince) {
+		return domain.ErrPreconditionFailed
+	}
+
+	if conditions.IfNoneMatch != "" {
+		if etagMatches(conditions.IfNoneMatch, file.ETag) {
+			return notModified
+		}
+	} else if !conditions.IfModifiedSince.IsZero() && !lastModified.After(conditions.IfModifiedSince) {
+		return notModified
+	}
+
+	return nil
+}
+
+// etagMatches reports whether etag is listed in the value of an If-Match or If-None-Match header:
+// "*", or a comma-separated list of ETags, quoted or not, compared without their weak "W/" prefix.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		candidate = strings.Trim(strings.TrimPrefix(candidate, "W/"), `"`)
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
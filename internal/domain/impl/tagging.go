@@ -0,0 +1,57 @@
+// Package domain contains the business logic for managing buckets and files.
+package impl
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+// systemTagPrefix is the prefix of the tag keys reserved for tags set by AWS.
+const systemTagPrefix = "aws:"
+
+// validateTags checks a tag set against the S3 limits: at most maxTags tags, whose keys of
+// 1 to model.MaxTagKeyLength characters and values of at most model.MaxTagValueLength characters
+// only hold letters, numbers, spaces and the symbols + - = . _ : / @, and whose keys do not
+// start with "aws:". kind names the owner of the tags ("Object" or "Bucket") in error messages.
+// Returns domain.ErrInvalidTag otherwise.
+func validateTags(tags map[string]string, maxTags int, kind string) error {
+	if len(tags) > maxTags {
+		return domain.ErrInvalidTag.WithMessage("%s tags cannot be greater than %d", kind, maxTags)
+	}
+
+	for key, value := range tags {
+		switch {
+		case key == "" || !validTagText(key):
+			return domain.ErrInvalidTag.WithMessage("The TagKey you have provided is invalid")
+		case utf8.RuneCountInString(key) > model.MaxTagKeyLength:
+			return domain.ErrInvalidTag.WithMessage("The TagKey you have provided is too long, max %d", model.MaxTagKeyLength)
+		case strings.HasPrefix(strings.ToLower(key), systemTagPrefix):
+			return domain.ErrInvalidTag.WithMessage("Your TagKey cannot be prefixed with %s", systemTagPrefix)
+		case !validTagText(value):
+			return domain.ErrInvalidTag.WithMessage("The TagValue you have provided is invalid")
+		case utf8.RuneCountInString(value) > model.MaxTagValueLength:
+			return domain.ErrInvalidTag.WithMessage("The TagValue you have provided is too long, max %d", model.MaxTagValueLength)
+		}
+	}
+
+	return nil
+}
+
+// validTagText reports whether a tag key or value only holds the characters S3 allows in tags.
+func validTagText(text string) bool {
+	if !utf8.ValidString(text) {
+		return false
+	}
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsSpace(r) && !strings.ContainsRune("+-=._:/@", r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,51 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+func TestValidateTags(t *testing.T) {
+	// tagSet returns a tag set of n distinct tags.
+	tagSet := func(n int) map[string]string {
+		tags := make(map[string]string, n)
+		for i := range n {
+			tags[fmt.Sprintf("key%d", i)] = "value"
+		}
+		return tags
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr error
+	}{
+		{name: "no tags", tags: map[string]string{}},
+		{name: "allowed characters", tags: map[string]string{"project/team-1": "a+b=c.d_e:f@g h", "ключ": "值"}},
+		{name: "empty value", tags: map[string]string{"temp": ""}},
+		{name: "maximum number of tags", tags: tagSet(model.MaxObjectTags)},
+		{name: "too many tags", tags: tagSet(model.MaxObjectTags + 1), wantErr: domain.ErrInvalidTag},
+		{name: "empty key", tags: map[string]string{"": "value"}, wantErr: domain.ErrInvalidTag},
+		{name: "invalid key character", tags: map[string]string{"a*b": "value"}, wantErr: domain.ErrInvalidTag},
+		{name: "invalid value character", tags: map[string]string{"key": "a#b"}, wantErr: domain.ErrInvalidTag},
+		{name: "key of the maximum length", tags: map[string]string{strings.Repeat("é", model.MaxTagKeyLength): "value"}},
+		{name: "key too long", tags: map[string]string{strings.Repeat("k", model.MaxTagKeyLength+1): "value"}, wantErr: domain.ErrInvalidTag},
+		{name: "value too long", tags: map[string]string{"key": strings.Repeat("v", model.MaxTagValueLength+1)}, wantErr: domain.ErrInvalidTag},
+		{name: "reserved prefix", tags: map[string]string{"AWS:cost": "value"}, wantErr: domain.ErrInvalidTag},
+		{name: "invalid UTF-8", tags: map[string]string{"key": "\xff"}, wantErr: domain.ErrInvalidTag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTags(tt.tags, model.MaxObjectTags, "Object")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateTags() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
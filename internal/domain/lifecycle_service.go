@@ -0,0 +1,11 @@
+package domain
+
+import "github.com/bonifacio-pedro/s3ego/internal/model"
+
+// LifecycleService interface for decoupling code
+type LifecycleService interface {
+	Put(bucketName string, rules []model.LifecycleRule) error
+	Get(bucketName string) ([]model.LifecycleRule, error)
+	Remove(bucketName string) error
+	Apply() (model.LifecycleRun, error)
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"io"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+// MultipartService interface for decoupling code
+type MultipartService interface {
+	Create(bucketName string, key string, contentType string, metadata model.Metadata) (string, error)
+	UploadPart(bucketName string, key string, uploadID string, partNumber int, data io.Reader) (string, error)
+	UploadPartCopy(bucketName string, key string, uploadID string, partNumber int, sourceBucket string, sourceKey string, sourceVersionID string, sourceRange *model.ByteRange, sourceConditions model.Preconditions) (model.Part, error)
+	ListParts(bucketName string, key string, uploadID string, partNumberMarker int, maxParts int) (*model.PartListing, error)
+	ListUploads(bucketName string, query model.UploadQuery) (*model.UploadListing, error)
+	Complete(bucketName string, key string, uploadID string, parts []model.CompletedPart) (model.File, error)
+	Abort(bucketName string, key string, uploadID string) error
+}
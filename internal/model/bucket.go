@@ -3,25 +3,31 @@ package model
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
 )
 
 // Bucket represents an S3 bucket in the emulator.
 // It holds a unique identifier, name, URL, and associated files.
 type Bucket struct {
-	ID    int    `json:"id"`    // Unique identifier of the bucket in the database
-	Name  string `json:"name"`  // Name of the bucket
-	Url   string `json:"url"`   // Base URL of the bucket
-	Files []File `json:"files"` // List of files contained in the bucket
+	ID         int              `json:"id"`         // Unique identifier of the bucket in the database
+	Name       string           `json:"name"`       // Name of the bucket
+	Url        string           `json:"url"`        // Base URL of the bucket
+	Files      []File           `json:"files"`      // List of files contained in the bucket
+	Versioning VersioningStatus `json:"versioning"` // Versioning state of the bucket
+	CreatedAt  time.Time        `json:"created_at"` // Timestamp when the bucket was created
 }
 
 // NewBucket creates and initializes a new Bucket instance with the given name.
 // The bucket URL is generated in the format "s3ego:7777//<bucketName>".
-// It initializes the Files slice as empty.
-func NewBucket(bucketName string) Bucket {
+// It initializes the Files slice as empty and stamps the creation time from the given clock.
+func NewBucket(bucketName string, clock clock.Clock) Bucket {
 	bucket := new(Bucket)
 	bucket.Name = bucketName
 	bucket.Url = fmt.Sprintf("s3ego:7777//%s", bucketName)
 	bucket.Files = make([]File, 0)
+	bucket.CreatedAt = clock.Now()
 
 	return *bucket
 }
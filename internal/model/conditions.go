@@ -0,0 +1,36 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// Preconditions holds the conditional headers of a request, which make it apply only
+// to an object with a matching ETag or modification time. Empty fields are not checked.
+type Preconditions struct {
+	IfMatch           string    // ETags, or "*", of which the object must match one
+	IfNoneMatch       string    // ETags, or "*", of which the object must match none
+	IfModifiedSince   time.Time // The object must have been modified after this time
+	IfUnmodifiedSince time.Time // The object must not have been modified after this time
+}
+
+// ParsePreconditions reads the If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since
+// headers, each preceded by prefix (e.g. "x-amz-copy-source-" for the conditions on a copy source).
+// Dates that are not valid HTTP dates are ignored, as RFC 7232 requires.
+func ParsePreconditions(header http.Header, prefix string) Preconditions {
+	conditions := Preconditions{
+		IfMatch:     header.Get(prefix + "If-Match"),
+		IfNoneMatch: header.Get(prefix + "If-None-Match"),
+	}
+
+	if since, err := http.ParseTime(header.Get(prefix + "If-Modified-Since")); err == nil {
+		conditions.IfModifiedSince = since
+	}
+
+	if since, err := http.ParseTime(header.Get(prefix + "If-Unmodified-Since")); err == nil {
+		conditions.IfUnmodifiedSince = since
+	}
+
+	return conditions
+}
@@ -0,0 +1,71 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// MaxCORSRules is the maximum number of rules in the CORS configuration of a bucket.
+const MaxCORSRules = 100
+
+// CORSMethods are the HTTP methods a CORS rule may allow.
+var CORSMethods = []string{"GET", "PUT", "HEAD", "POST", "DELETE"}
+
+// CORSRule is a rule of the CORS configuration of a bucket, allowing cross-origin requests from
+// the browsers of its origins. Origins and allowed headers may contain a single "*" wildcard.
+type CORSRule struct {
+	ID             string   // Optional identifier of the rule
+	AllowedOrigins []string // Origins allowed to send cross-origin requests, such as "https://*.example.com"
+	AllowedMethods []string // Methods of the allowed requests, among CORSMethods
+	AllowedHeaders []string // Headers a preflight may ask to send, matched case-insensitively
+	ExposeHeaders  []string // Response headers the browser lets the scripts of the origin read
+	MaxAgeSeconds  int      // How long browsers may cache the preflight response, 0 if not set
+}
+
+// AllowsOrigin reports whether origin matches one of the allowed origins of the rule.
+func (r CORSRule) AllowsOrigin(origin string) bool {
+	return slices.ContainsFunc(r.AllowedOrigins, func(pattern string) bool { return wildcardMatch(pattern, origin) })
+}
+
+// Matches reports whether the rule allows a request from origin with the given method, sending
+// the given headers, which preflight requests list in Access-Control-Request-Headers.
+func (r CORSRule) Matches(origin string, method string, headers []string) bool {
+	if !r.AllowsOrigin(origin) || !slices.Contains(r.AllowedMethods, method) {
+		return false
+	}
+
+	for _, header := range headers {
+		allowed := slices.ContainsFunc(r.AllowedHeaders, func(pattern string) bool {
+			return wildcardMatch(strings.ToLower(pattern), strings.ToLower(header))
+		})
+		if !allowed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchCORSRule returns the first of rules allowing a request from origin with the given method
+// and headers, as S3 evaluates them, or nil if none does.
+func MatchCORSRule(rules []CORSRule, origin string, method string, headers []string) *CORSRule {
+	for i := range rules {
+		if rules[i].Matches(origin, method, headers) {
+			return &rules[i]
+		}
+	}
+
+	return nil
+}
+
+// wildcardMatch reports whether value matches pattern, in which a single "*" stands for any
+// sequence of characters.
+func wildcardMatch(pattern string, value string) bool {
+	prefix, suffix, found := strings.Cut(pattern, "*")
+	if !found {
+		return pattern == value
+	}
+
+	return len(value) >= len(prefix)+len(suffix) && strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
@@ -0,0 +1,20 @@
+// Package model contains the data models used in the application.
+package model
+
+// MaxDeleteObjects is the maximum number of objects deleted by a single multi-object delete.
+const MaxDeleteObjects = 1000
+
+// ObjectIdentifier identifies an object, by key and optionally version, listed in a DeleteObjects request.
+type ObjectIdentifier struct {
+	Key       string
+	VersionID string // Version of the object, empty for the current one
+}
+
+// DeleteResult is the outcome of deleting a single object, or a single object of a multi-object delete.
+type DeleteResult struct {
+	Key                   string
+	VersionID             string // Version deleted, empty if none was requested
+	DeleteMarker          bool   // Whether a delete marker was created, or the version deleted was one
+	DeleteMarkerVersionID string // Version of the delete marker created, empty if none was
+	Err                   error  // Why the object was not deleted, nil if it was (or did not exist)
+}
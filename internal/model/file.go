@@ -2,41 +2,54 @@
 package model
 
 import (
-	"crypto/md5"
-	"fmt"
 	"net/http"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
 )
 
+// MaxKeyLength is the maximum length in bytes of an object key.
+const MaxKeyLength = 1024
+
 // File represents a file stored within a bucket in the S3 emulator.
-// It contains an ID, unique key, raw data, metadata, and the ID of the bucket it belongs to.
+// It contains an ID, key, version, metadata, the reference of the blob holding its data,
+// and the ID of the bucket it belongs to. A bucket holds one File per version of each key;
+// a delete marker is a File without data.
 type File struct {
-	ID           int       `json:"id" db:"id"`                       // Unique identifier of the file in the database
-	Key          string    `json:"key" db:"key"`                     // Unique key of the file (usually bucketName/filename)
-	Data         []byte    `json:"data" db:"data"`                   // Raw binary data of the file
-	BucketID     uint      `json:"bucket_id" db:"bucket_id"`         // Foreign key referencing the bucket this file belongs to
-	ETag         string    `json:"etag" db:"etag"`                   // MD5 hash of the file content for integrity
-	ContentType  string    `json:"content_type" db:"content_type"`   // MIME type of the file
-	Size         int64     `json:"size" db:"size"`                   // Size of the file in bytes
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`       // Timestamp when file was created
-	LastModified time.Time `json:"last_modified" db:"last_modified"` // Timestamp when file was last modified
+	ID           int               `json:"id" db:"id"`                       // Unique identifier of the file in the database
+	Key          string            `json:"key" db:"key"`                     // Key of the file within its bucket
+	VersionID    string            `json:"version_id" db:"version_id"`       // Version of the file, unique within its key, or NullVersionID
+	IsLatest     bool              `json:"is_latest" db:"is_latest"`         // Whether this is the current version of the key
+	DeleteMarker bool              `json:"delete_marker" db:"delete_marker"` // Whether this version is a delete marker, holding no data
+	BlobRef      string            `json:"-" db:"blob_ref"`                  // Reference of the blob holding the file data in the BlobStore
+	BucketID     uint              `json:"bucket_id" db:"bucket_id"`         // Foreign key referencing the bucket this file belongs to
+	ETag         string            `json:"etag" db:"etag"`                   // MD5 hash of the file content for integrity
+	ContentType  string            `json:"content_type" db:"content_type"`   // MIME type of the file
+	Size         int64             `json:"size" db:"size"`                   // Size of the file in bytes
+	PartSizes    []int64           `json:"-" db:"part_sizes"`                // Sizes of the parts of a file assembled by a multipart upload
+	Metadata     Metadata          `json:"metadata" db:"-"`                  // User-defined metadata and system headers, stored in file_metadata
+	Tags         map[string]string `json:"tags,omitempty" db:"-"`            // Tag set of the version, stored in file_tags
+	Versioned    bool              `json:"-" db:"-"`                         // Whether versioning was ever enabled on the bucket, so the version ID is reported
+	CreatedAt    time.Time         `json:"created_at" db:"created_at"`       // Timestamp when file was created
+	LastModified time.Time         `json:"last_modified" db:"last_modified"` // Timestamp when file was last modified
 }
 
-// NewFile creates a new File instance given the file data, bucket, and file name.
-// It generates the Key by combining the bucket name and file name in the format "bucketName/fileName".
-// It also calculates metadata like ETag, ContentType, and Size automatically.
-func NewFile(data []byte, bucket Bucket, fileName string) File {
-	now := time.Now()
+// NewFile creates a new File instance given the bucket and its key within the bucket,
+// which is stored exactly as given, as the latest "null" version of the key, created at the current
+// time of the given clock. The file is Versioned when versioning is enabled or suspended on the bucket.
+// The content metadata (BlobRef, ETag, ContentType and Size) is set once the file data
+// has been written to the BlobStore.
+func NewFile(bucket Bucket, key string, clock clock.Clock) File {
+	now := clock.Now()
 
 	file := File{
-		Data:         data,
 		BucketID:     uint(bucket.ID),
-		Key:          fmt.Sprintf("%s/%s", bucket.Name, fileName),
-		ETag:         calculateETag(data),
-		ContentType:  detectContentType(data, fileName),
-		Size:         int64(len(data)),
+		Key:          key,
+		VersionID:    NullVersionID,
+		IsLatest:     true,
+		Versioned:    bucket.Versioning != VersioningUnversioned,
 		CreatedAt:    now,
 		LastModified: now,
 	}
@@ -44,14 +57,40 @@ func NewFile(data []byte, bucket Bucket, fileName string) File {
 	return file
 }
 
-// calculateETag calculates the MD5 hash of file data (S3-style ETag)
-func calculateETag(data []byte) string {
-	return fmt.Sprintf("%x", md5.Sum(data))
+// PartsCount returns the number of parts the file was uploaded in:
+// the number of parts of a multipart upload, or 1 for a file uploaded at once.
+func (f File) PartsCount() int {
+	if len(f.PartSizes) == 0 {
+		return 1
+	}
+
+	return len(f.PartSizes)
+}
+
+// PartRange returns the byte range of the part partNumber (1-based, in upload order) of the file.
+// A file uploaded at once has a single part spanning the whole file.
+// Returns false if the file has no such part.
+func (f File) PartRange(partNumber int) (ByteRange, bool) {
+	if partNumber < 1 || partNumber > f.PartsCount() {
+		return ByteRange{}, false
+	}
+
+	if len(f.PartSizes) == 0 {
+		return ByteRange{Start: 0, End: f.Size - 1}, true
+	}
+
+	var start int64
+	for _, size := range f.PartSizes[:partNumber-1] {
+		start += size
+	}
+
+	return ByteRange{Start: start, End: start + f.PartSizes[partNumber-1] - 1}, true
 }
 
-// detectContentType tries to detect the content type of a file based on its data and extension.
+// DetectContentType tries to detect the content type of a file based on its data and extension.
+// data only needs to hold the first 512 bytes of the file.
 // It uses http.DetectContentType first, and falls back to file extension if the result is generic.
-func detectContentType(data []byte, filename string) string {
+func DetectContentType(data []byte, filename string) string {
 	contentType := http.DetectContentType(data)
 	if contentType != "application/octet-stream" {
 		return contentType
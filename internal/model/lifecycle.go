@@ -0,0 +1,65 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxLifecycleRules is the maximum number of rules in the lifecycle configuration of a bucket.
+const MaxLifecycleRules = 1000
+
+// LifecycleStatus tells whether a lifecycle rule is applied.
+type LifecycleStatus string
+
+const (
+	LifecycleEnabled  LifecycleStatus = "Enabled"  // The rule is applied
+	LifecycleDisabled LifecycleStatus = "Disabled" // The rule is kept but not applied
+)
+
+// LifecycleRule is a rule of the lifecycle configuration of a bucket, expiring the objects, versions
+// and multipart uploads matching its filter. Zero action fields are not set: a rule takes at least one action.
+type LifecycleRule struct {
+	ID     string            // Identifier of the rule, unique within the configuration
+	Status LifecycleStatus   // Whether the rule is applied
+	Prefix string            // Only keys starting with Prefix match the rule
+	Tags   map[string]string // Only objects carrying every one of these tags match the rule
+
+	ExpirationDays            int       // Current versions expire this many days after their creation
+	ExpirationDate            time.Time // Current versions expire on this date, at midnight UTC
+	ExpiredObjectDeleteMarker bool      // Delete markers left without any noncurrent version are removed
+
+	NoncurrentDays          int // Noncurrent versions are deleted this many days after becoming noncurrent...
+	NewerNoncurrentVersions int // ...except for this many newer noncurrent versions of each key
+
+	AbortIncompleteUploadDays int // Multipart uploads are aborted this many days after their initiation
+}
+
+// LifecycleRun counts what a single pass of the lifecycle rules of every bucket expired.
+type LifecycleRun struct {
+	ExpiredObjects       int // Current versions deleted, or hidden behind a delete marker in versioned buckets
+	ExpiredVersions      int // Noncurrent versions deleted permanently
+	ExpiredDeleteMarkers int // Delete markers without noncurrent versions removed
+	AbortedUploads       int // Incomplete multipart uploads aborted
+}
+
+// Matches reports whether an object with the given key and tags is selected by the filter of the rule.
+func (r LifecycleRule) Matches(key string, tags map[string]string) bool {
+	if !strings.HasPrefix(key, r.Prefix) {
+		return false
+	}
+
+	for name, value := range r.Tags {
+		if tag, ok := tags[name]; !ok || tag != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LifecycleExpiry returns when an object created, or made noncurrent, at t expires after the given
+// number of days: as S3 does, t plus days rounded up to the next midnight UTC.
+func LifecycleExpiry(t time.Time, days int) time.Time {
+	return t.UTC().AddDate(0, 0, days).Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
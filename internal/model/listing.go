@@ -0,0 +1,40 @@
+// Package model contains the data models used in the application.
+package model
+
+// MaxListKeys is the maximum number of entries S3 returns in a single listing page.
+const MaxListKeys = 1000
+
+// ListQuery holds the filters and paging parameters of an object listing.
+type ListQuery struct {
+	Prefix     string // Only keys starting with Prefix are listed
+	Delimiter  string // Keys containing Delimiter after Prefix are rolled up into common prefixes
+	StartAfter string // Only entries sorted after StartAfter are listed
+	MaxKeys    int    // Maximum number of objects plus common prefixes to return
+}
+
+// Listing is a page of objects and common prefixes returned by an object listing.
+type Listing struct {
+	Files          []File   // Objects in the page (without Data), sorted by key
+	CommonPrefixes []string // Rolled up "folders" in the page, sorted
+	IsTruncated    bool     // Whether more entries exist after this page
+	LastEntry      string   // Last key or common prefix of the page, used to continue the listing
+}
+
+// Count returns the number of entries (objects and common prefixes) in the page.
+func (l *Listing) Count() int {
+	return len(l.Files) + len(l.CommonPrefixes)
+}
+
+// BucketQuery holds the filters and paging parameters of a bucket listing.
+// The zero value lists every bucket.
+type BucketQuery struct {
+	Prefix     string // Only buckets whose name starts with Prefix are listed
+	StartAfter string // Only buckets sorted after StartAfter are listed
+	MaxBuckets int    // Maximum number of buckets to return, 0 for no limit
+}
+
+// BucketListing is a page of buckets returned by a bucket listing.
+type BucketListing struct {
+	Buckets     []Bucket // Buckets in the page (without Files), sorted by name
+	IsTruncated bool     // Whether more buckets exist after this page
+}
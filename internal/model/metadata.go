@@ -0,0 +1,87 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MaxUserMetadataSize is the maximum size in bytes of the user-defined metadata of an object,
+// measured as the sum of the lengths of every key and value.
+const MaxUserMetadataSize = 2 * 1024
+
+// userMetadataPrefix is the prefix of the headers holding user-defined metadata.
+const userMetadataPrefix = "x-amz-meta-"
+
+// Metadata holds the metadata stored with an object besides its content type:
+// the user-defined metadata and the system headers returned as is on GET and HEAD.
+type Metadata struct {
+	User               map[string]string `json:"user,omitempty"`                // x-amz-meta-* headers, keyed by lowercase name without the prefix
+	CacheControl       string            `json:"cache_control,omitempty"`       // Cache-Control header
+	ContentDisposition string            `json:"content_disposition,omitempty"` // Content-Disposition header
+	ContentEncoding    string            `json:"content_encoding,omitempty"`    // Content-Encoding header
+	ContentLanguage    string            `json:"content_language,omitempty"`    // Content-Language header
+	Expires            string            `json:"expires,omitempty"`             // Expires header
+}
+
+// ParseMetadata reads the x-amz-meta-* headers and the Cache-Control, Content-Disposition,
+// Content-Encoding, Content-Language and Expires headers. Repeated user metadata headers
+// are joined with commas, as S3 does.
+func ParseMetadata(header http.Header) Metadata {
+	metadata := Metadata{
+		CacheControl:       header.Get("Cache-Control"),
+		ContentDisposition: header.Get("Content-Disposition"),
+		ContentEncoding:    header.Get("Content-Encoding"),
+		ContentLanguage:    header.Get("Content-Language"),
+		Expires:            header.Get("Expires"),
+	}
+
+	for name, values := range header {
+		key, ok := strings.CutPrefix(strings.ToLower(name), userMetadataPrefix)
+		if !ok || key == "" {
+			continue
+		}
+
+		if metadata.User == nil {
+			metadata.User = make(map[string]string)
+		}
+		metadata.User[key] = strings.Join(values, ",")
+	}
+
+	return metadata
+}
+
+// Header returns the metadata as the response headers S3 returns on GET and HEAD,
+// leaving out the empty system headers.
+func (m Metadata) Header() http.Header {
+	header := make(http.Header)
+	for key, value := range m.User {
+		header.Set(userMetadataPrefix+key, value)
+	}
+
+	system := map[string]string{
+		"Cache-Control":       m.CacheControl,
+		"Content-Disposition": m.ContentDisposition,
+		"Content-Encoding":    m.ContentEncoding,
+		"Content-Language":    m.ContentLanguage,
+		"Expires":             m.Expires,
+	}
+	for name, value := range system {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+
+	return header
+}
+
+// UserSize returns the size in bytes of the user-defined metadata,
+// to be checked against MaxUserMetadataSize.
+func (m Metadata) UserSize() int {
+	size := 0
+	for key, value := range m.User {
+		size += len(key) + len(value)
+	}
+
+	return size
+}
@@ -0,0 +1,88 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+)
+
+// MaxPartNumber is the highest part number accepted in a multipart upload.
+const MaxPartNumber = 10000
+
+// MultipartUpload represents an in-progress multipart upload of an object.
+type MultipartUpload struct {
+	ID          int       `json:"id" db:"id"`                     // Unique identifier of the upload in the database
+	UploadID    string    `json:"upload_id" db:"upload_id"`       // Opaque identifier handed to clients
+	BucketID    uint      `json:"bucket_id" db:"bucket_id"`       // Foreign key referencing the bucket of the upload
+	Key         string    `json:"key" db:"key"`                   // Key of the object being uploaded
+	ContentType string    `json:"content_type" db:"content_type"` // MIME type of the final object, empty to detect it
+	Metadata    Metadata  `json:"metadata" db:"-"`                // Metadata of the final object, stored in multipart_metadata
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`     // Timestamp when the upload was initiated
+}
+
+// Part represents a single uploaded part of a multipart upload.
+type Part struct {
+	ID           int       `json:"id" db:"id"`                       // Unique identifier of the part in the database
+	MultipartID  int       `json:"multipart_id" db:"multipart_id"`   // Foreign key referencing the multipart upload
+	PartNumber   int       `json:"part_number" db:"part_number"`     // Position of the part in the final object (1-10000)
+	BlobRef      string    `json:"-" db:"blob_ref"`                  // Reference of the blob holding the part data in the BlobStore
+	ETag         string    `json:"etag" db:"etag"`                   // MD5 hash of the part content
+	Size         int64     `json:"size" db:"size"`                   // Size of the part in bytes
+	LastModified time.Time `json:"last_modified" db:"last_modified"` // Timestamp when the part was uploaded
+}
+
+// CompletedPart identifies a part, by number and ETag, listed in a CompleteMultipartUpload request.
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// UploadQuery holds the filters and paging parameters of a multipart upload listing.
+type UploadQuery struct {
+	Prefix         string // Only uploads whose key starts with Prefix are listed
+	KeyMarker      string // Only uploads for keys sorted after KeyMarker are listed
+	UploadIDMarker string // With KeyMarker, also lists uploads for KeyMarker initiated after this upload
+	MaxUploads     int    // Maximum number of uploads to return
+}
+
+// UploadListing is a page of multipart uploads, sorted by key and initiation time.
+type UploadListing struct {
+	Uploads     []MultipartUpload
+	IsTruncated bool
+}
+
+// PartListing is a page of the parts of a multipart upload, sorted by part number.
+type PartListing struct {
+	Parts       []Part // Parts in the page
+	IsTruncated bool
+}
+
+// NewPart creates a new Part of the given upload. Its content metadata (BlobRef, ETag and Size)
+// is set once the part data has been written to the BlobStore; it is last modified at the current
+// time of the given clock.
+func NewPart(upload MultipartUpload, partNumber int, clock clock.Clock) Part {
+	return Part{
+		MultipartID:  upload.ID,
+		PartNumber:   partNumber,
+		LastModified: clock.Now(),
+	}
+}
+
+// MultipartETag calculates the S3-style ETag of an object assembled from parts:
+// the MD5 of the concatenated binary MD5s of every part, followed by "-" and the number of parts.
+func MultipartETag(parts []Part) (string, error) {
+	digests := make([]byte, 0, len(parts)*md5.Size)
+	for _, part := range parts {
+		digest, err := hex.DecodeString(part.ETag)
+		if err != nil {
+			return "", fmt.Errorf("invalid ETag %q for part %d: %w", part.ETag, part.PartNumber, err)
+		}
+		digests = append(digests, digest...)
+	}
+
+	return fmt.Sprintf("%x-%d", md5.Sum(digests), len(parts)), nil
+}
@@ -0,0 +1,70 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetOptions selects the version and part of an object read by FileService.GetRange
+// and the conditions the object must meet.
+type GetOptions struct {
+	VersionID     string        // Version of the object to read, empty for the current one
+	Range         *RangeRequest // Single byte range to read, nil to read the whole object
+	PartNumber    int           // Part of an object assembled by a multipart upload to read, 0 to read the whole object
+	Preconditions Preconditions // Conditions on the ETag and modification time of the object
+}
+
+// ParseGetOptions reads the options of a download request: the versionId and partNumber query
+// parameters, the Range header and the If-Match, If-None-Match, If-Modified-Since and
+// If-Unmodified-Since headers. A partNumber that is not an integer is read as -1, which
+// FileService rejects as out of bounds.
+func ParseGetOptions(header http.Header, query url.Values) GetOptions {
+	options := GetOptions{
+		VersionID:     query.Get("versionId"),
+		Preconditions: ParsePreconditions(header, ""),
+	}
+
+	if requested, ok := ParseRange(header.Get("Range")); ok {
+		options.Range = &requested
+	}
+
+	if query.Has("partNumber") {
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			partNumber = -1
+		}
+		options.PartNumber = partNumber
+	}
+
+	return options
+}
+
+// PutOptions holds the settings of an upload besides its data.
+type PutOptions struct {
+	Preconditions Preconditions     // Conditional write headers (If-Match and If-None-Match) of the upload
+	ContentType   string            // MIME type of the object, detected from its data and key if empty
+	Metadata      Metadata          // Metadata stored with the object
+	Tags          map[string]string // Tag set stored with the object
+}
+
+// Directive tells a copy whether to keep a property of the source object or to replace it
+// with the value given in the request.
+type Directive string
+
+const (
+	DirectiveCopy    Directive = "COPY"    // Keep the property of the source object (the default)
+	DirectiveReplace Directive = "REPLACE" // Take the property from the request
+)
+
+// CopyOptions holds the settings of a server-side copy of an object.
+type CopyOptions struct {
+	SourceVersionID   string            // Version of the source object to copy, empty for the current one
+	SourceConditions  Preconditions     // Conditions the source object must meet (x-amz-copy-source-if-* headers)
+	MetadataDirective Directive         // Whether the copy keeps the source metadata or takes Metadata, COPY if empty
+	Metadata          Metadata          // Metadata stored with the copy when MetadataDirective is REPLACE
+	ContentType       string            // MIME type of the copy when MetadataDirective is REPLACE, the source one if empty
+	TaggingDirective  Directive         // Whether the copy keeps the source tags or takes Tags, COPY if empty
+	Tags              map[string]string // Tag set stored with the copy when TaggingDirective is REPLACE
+}
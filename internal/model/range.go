@@ -0,0 +1,89 @@
+// Package model contains the data models used in the application.
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ByteRange is an inclusive range of byte offsets within an object.
+type ByteRange struct {
+	Start int64 // Offset of the first byte of the range
+	End   int64 // Offset of the last byte of the range
+}
+
+// Length returns the number of bytes covered by the range.
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// RangeRequest is a single byte range requested with an HTTP Range header ("bytes=first-last",
+// "bytes=first-" or "bytes=-suffix"), before it is resolved against the size of an object.
+type RangeRequest struct {
+	First  int64 // Offset of the first byte, or -1 for a suffix range
+	Last   int64 // Offset of the last byte, or -1 to read up to the end of the object
+	Suffix int64 // Number of bytes to read from the end of the object, for a suffix range
+}
+
+// ParseRange parses the value of an HTTP Range header holding a single byte range.
+// Returns false if the header is empty, malformed or lists several ranges; S3 ignores
+// such headers and returns the whole object.
+func ParseRange(header string) (RangeRequest, bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return RangeRequest{}, false
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return RangeRequest{}, false
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return RangeRequest{}, false
+		}
+		return RangeRequest{First: -1, Last: -1, Suffix: suffix}, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return RangeRequest{}, false
+	}
+
+	if last == "" {
+		return RangeRequest{First: start, Last: -1}, true
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return RangeRequest{}, false
+	}
+
+	return RangeRequest{First: start, Last: end}, true
+}
+
+// Resolve returns the byte range the request covers within an object of the given size,
+// clamping the last offset to the end of the object.
+// Returns false if the range is not satisfiable, i.e. it starts past the end of the object
+// or is an empty suffix.
+func (r RangeRequest) Resolve(size int64) (ByteRange, bool) {
+	if r.First < 0 {
+		if r.Suffix == 0 || size == 0 {
+			return ByteRange{}, false
+		}
+		return ByteRange{Start: max(size-r.Suffix, 0), End: size - 1}, true
+	}
+
+	if r.First >= size {
+		return ByteRange{}, false
+	}
+
+	end := r.Last
+	if end < 0 || end >= size {
+		end = size - 1
+	}
+
+	return ByteRange{Start: r.First, End: end}, true
+}
@@ -0,0 +1,10 @@
+// Package model contains the data models used in the application.
+package model
+
+// Limits of the tag sets of objects and buckets accepted by S3.
+const (
+	MaxObjectTags     = 10  // Maximum number of tags of an object version
+	MaxBucketTags     = 50  // Maximum number of tags of a bucket
+	MaxTagKeyLength   = 128 // Maximum length in characters of a tag key
+	MaxTagValueLength = 256 // Maximum length in characters of a tag value
+)
@@ -0,0 +1,38 @@
+// Package model contains the data models used in the application.
+package model
+
+// NullVersionID is the version ID of the objects written while the versioning of their bucket
+// is not enabled. A bucket holds at most one "null" version of each key.
+const NullVersionID = "null"
+
+// VersioningStatus is the versioning state of a bucket.
+type VersioningStatus string
+
+const (
+	VersioningUnversioned VersioningStatus = ""          // Versioning was never enabled (the default)
+	VersioningEnabled     VersioningStatus = "Enabled"   // Every write creates a new version
+	VersioningSuspended   VersioningStatus = "Suspended" // Writes replace the "null" version, older versions are kept
+)
+
+// VersionQuery holds the filters and paging parameters of an object versions listing.
+type VersionQuery struct {
+	Prefix          string // Only keys starting with Prefix are listed
+	Delimiter       string // Keys containing Delimiter after Prefix are rolled up into common prefixes
+	KeyMarker       string // Only versions of keys sorted after KeyMarker are listed...
+	VersionIDMarker string // ...and the versions of KeyMarker older than VersionIDMarker, when set
+	MaxKeys         int    // Maximum number of versions, delete markers and common prefixes to return
+}
+
+// VersionListing is a page of object versions and common prefixes returned by a versions listing.
+type VersionListing struct {
+	Versions            []File   // Versions and delete markers in the page (without Data), by key and newest first
+	CommonPrefixes      []string // Rolled up "folders" in the page, sorted
+	IsTruncated         bool     // Whether more entries exist after this page
+	NextKeyMarker       string   // KeyMarker continuing the listing after this page
+	NextVersionIDMarker string   // VersionIDMarker continuing the listing after this page
+}
+
+// Count returns the number of entries (versions, delete markers and common prefixes) in the page.
+func (l *VersionListing) Count() int {
+	return len(l.Versions) + len(l.CommonPrefixes)
+}
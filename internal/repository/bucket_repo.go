@@ -5,9 +5,16 @@ import "github.com/bonifacio-pedro/s3ego/internal/model"
 // BucketRepository interface for decoupling code
 type BucketRepository interface {
 	New(bucket *model.Bucket) error
-	Remove(bucketID int) error
+	Remove(bucketID int) ([]string, error)
 	ExistsByName(bucketName string) (bool, error)
 	GetByName(bucketName string) (*model.Bucket, error)
+	List(query model.BucketQuery) (*model.BucketListing, error)
+	SetVersioning(bucketID int, status model.VersioningStatus) error
+	GetTags(bucketID int) (map[string]string, error)
+	SetTags(bucketID int, tags map[string]string) error
+	HasFiles(bucketID int) (bool, error)
 	GetFiles(bucketID int) ([]string, error)
+	ListFiles(bucketID int, query model.ListQuery) (*model.Listing, error)
+	ListVersions(bucketID int, query model.VersionQuery) (*model.VersionListing, error)
 	FileExists(bucketName string, key string) (bool, error)
 }
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/bonifacio-pedro/s3ego/internal/model"
+
+// CORSRepository interface for decoupling code
+type CORSRepository interface {
+	Put(bucketID int, rules []model.CORSRule) error
+	Get(bucketID int) ([]model.CORSRule, error)
+	Remove(bucketID int) error
+}
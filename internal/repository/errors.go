@@ -0,0 +1,13 @@
+package repository
+
+import "errors"
+
+// ErrNotFound is returned by repositories when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrAlreadyExists is returned by repositories when a record with the same unique key already exists.
+var ErrAlreadyExists = errors.New("record already exists")
+
+// ErrConditionFailed is returned by repositories when a conditional write finds
+// the stored record in a state other than the expected one.
+var ErrConditionFailed = errors.New("record does not match the write condition")
@@ -4,7 +4,13 @@ import "github.com/bonifacio-pedro/s3ego/internal/model"
 
 // FileRepository interface for decoupling code
 type FileRepository interface {
-	New(file *model.File) error
-	Remove(key string) error
-	GetByKey(key string) (*model.File, error)
+	New(file *model.File) ([]string, error)
+	Put(file *model.File) ([]string, error)
+	PutCopy(file *model.File) ([]string, error)
+	Replace(file *model.File, etag string) ([]string, error)
+	Remove(bucketID int, key string, versionID string) ([]string, error)
+	RemoveAll(bucketID int, versions []model.ObjectIdentifier, markers []*model.File) ([]string, error)
+	GetByKey(bucketID int, key string) (*model.File, error)
+	GetVersion(bucketID int, key string, versionID string) (*model.File, error)
+	SetTags(fileID int, tags map[string]string) error
 }
@@ -0,0 +1,71 @@
+package impl
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// queryBlobRefs runs a query selecting a single blob_ref column within the given transaction
+// and returns the non-empty references.
+func queryBlobRefs(tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blob references: %w", err)
+	}
+	defer rows.Close()
+
+	refs := make([]string, 0)
+	for rows.Next() {
+		var ref sql.NullString
+		if err := rows.Scan(&ref); err != nil {
+			return nil, fmt.Errorf("error scanning blob reference: %w", err)
+		}
+		if ref.String != "" {
+			refs = append(refs, ref.String)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return refs, nil
+}
+
+// releasedBlobs returns, without duplicates, the references among refs that are no longer used
+// by any file or multipart part, so the caller can delete their blobs once the transaction commits.
+func releasedBlobs(tx *sql.Tx, refs []string) ([]string, error) {
+	released := make([]string, 0, len(refs))
+	seen := make(map[string]bool, len(refs))
+
+	for _, ref := range refs {
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		used, err := blobInUse(tx, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if !used {
+			released = append(released, ref)
+		}
+	}
+
+	return released, nil
+}
+
+// blobInUse reports whether any file or multipart part references the blob ref.
+func blobInUse(tx *sql.Tx, ref string) (bool, error) {
+	var used bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM files WHERE blob_ref = ?1)
+			OR EXISTS(SELECT 1 FROM multipart_parts WHERE blob_ref = ?1)`, ref).Scan(&used)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blob reference usage: %w", err)
+	}
+
+	return used, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bonifacio-pedro/s3ego/internal/model"
 	"github.com/bonifacio-pedro/s3ego/internal/repository"
@@ -23,7 +24,7 @@ func NewBucketRepository(db *sql.DB) repository.BucketRepository {
 // New inserts a new bucket into the database.
 // Returns an error if the insertion fails.
 func (br *bucketRepository) New(bucket *model.Bucket) error {
-	_, err := br.db.Exec("INSERT INTO buckets (name, url) VALUES (?, ?)", bucket.Name, bucket.Url)
+	_, err := br.db.Exec("INSERT INTO buckets (name, url, created_at) VALUES (?, ?, ?)", bucket.Name, bucket.Url, bucket.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to insert bucket: %w", err)
 	}
@@ -31,20 +32,85 @@ func (br *bucketRepository) New(bucket *model.Bucket) error {
 	return nil
 }
 
-// Remove deletes all files associated with a bucket and then removes the bucket itself from the database.
-// Returns an error if the deletion fails.
-func (br *bucketRepository) Remove(bucketID int) error {
-	_, err := br.db.Exec("DELETE FROM files WHERE bucket_id = ?", bucketID)
+// Remove deletes all files and multipart uploads associated with a bucket, with their metadata
+// and tags, and its lifecycle rules, CORS rules and tags, and then removes the bucket itself from the database, in a single transaction.
+// Returns the references of the blobs no longer used by any file or part, which the caller
+// must delete from the BlobStore, or an error if the deletion fails.
+func (br *bucketRepository) Remove(bucketID int) ([]string, error) {
+	tx, err := br.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to remove bucket files: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	_, err = br.db.Exec("DELETE FROM buckets WHERE id = ?", bucketID)
+	refs, err := queryBlobRefs(tx, `
+		SELECT blob_ref FROM files WHERE bucket_id = ?1
+		UNION ALL
+		SELECT blob_ref FROM multipart_parts
+		WHERE multipart_id IN (SELECT id FROM multipart_uploads WHERE bucket_id = ?1)`, bucketID)
 	if err != nil {
-		return fmt.Errorf("failed to remove bucket: %w", err)
+		return nil, err
 	}
 
-	return nil
+	err = deleteMetadata(tx, fileMetadataTable, "SELECT id FROM files WHERE bucket_id = ?", bucketID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deleteTags(tx, fileTagsTable, "SELECT id FROM files WHERE bucket_id = ?", bucketID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM files WHERE bucket_id = ?", bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove bucket files: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM multipart_parts
+		WHERE multipart_id IN (SELECT id FROM multipart_uploads WHERE bucket_id = ?)`, bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove bucket multipart parts: %w", err)
+	}
+
+	err = deleteMetadata(tx, multipartMetadataTable, "SELECT id FROM multipart_uploads WHERE bucket_id = ?", bucketID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM multipart_uploads WHERE bucket_id = ?", bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove bucket multipart uploads: %w", err)
+	}
+
+	if err := deleteLifecycleRules(tx, bucketID); err != nil {
+		return nil, err
+	}
+
+	if err := deleteCORSRules(tx, bucketID); err != nil {
+		return nil, err
+	}
+
+	if err := deleteTags(tx, bucketTagsTable, "?", bucketID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("DELETE FROM buckets WHERE id = ?", bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove bucket: %w", err)
+	}
+
+	released, err := releasedBlobs(tx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bucket removal: %w", err)
+	}
+
+	return released, nil
 }
 
 // ExistsByName checks if a bucket with the given name exists in the database.
@@ -60,21 +126,113 @@ func (br *bucketRepository) ExistsByName(bucketName string) (bool, error) {
 }
 
 // GetByName retrieves a bucket by its name.
-// Returns a pointer to the Bucket model or an error if the bucket is not found.
+// Returns a pointer to the Bucket model, repository.ErrNotFound if the bucket is not found,
+// or an error if scanning fails.
 func (br *bucketRepository) GetByName(bucketName string) (*model.Bucket, error) {
-	row := br.db.QueryRow("SELECT id, name, url FROM buckets WHERE name = ?", bucketName)
+	row := br.db.QueryRow("SELECT id, name, url, created_at, COALESCE(versioning, '') FROM buckets WHERE name = ?", bucketName)
 	var bucket model.Bucket
 
-	if err := row.Scan(&bucket.ID, &bucket.Name, &bucket.Url); err != nil {
-		return nil, errors.New("bucket not found")
+	if err := row.Scan(&bucket.ID, &bucket.Name, &bucket.Url, &bucket.CreatedAt, &bucket.Versioning); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("error scanning bucket DB row: %w", err)
 	}
 	return &bucket, nil
 }
 
-// GetFiles retrieves all file keys associated with a given bucket ID.
+// List retrieves the buckets matching the given query, sorted by name.
+// Returns a page of at most query.MaxBuckets buckets (all of them if zero), or an error if the query fails.
+func (br *bucketRepository) List(query model.BucketQuery) (*model.BucketListing, error) {
+	limit := -1
+	if query.MaxBuckets > 0 {
+		limit = query.MaxBuckets + 1
+	}
+
+	rows, err := br.db.Query(`
+		SELECT id, name, url, created_at
+		FROM buckets
+		WHERE name >= ?1 AND (?2 = '' OR name < ?2) AND name > ?3
+		ORDER BY name
+		LIMIT ?4`,
+		query.Prefix,
+		prefixUpperBound(query.Prefix),
+		query.StartAfter,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buckets: %w", err)
+	}
+	defer rows.Close()
+
+	listing := &model.BucketListing{Buckets: make([]model.Bucket, 0)}
+	for rows.Next() {
+		if query.MaxBuckets > 0 && len(listing.Buckets) == query.MaxBuckets {
+			listing.IsTruncated = true
+			break
+		}
+
+		var bucket model.Bucket
+		if err := rows.Scan(&bucket.ID, &bucket.Name, &bucket.Url, &bucket.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in buckets iteration: %w", err)
+		}
+		listing.Buckets = append(listing.Buckets, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return listing, nil
+}
+
+// SetVersioning updates the versioning state of a bucket.
+// Returns an error if the update fails.
+func (br *bucketRepository) SetVersioning(bucketID int, status model.VersioningStatus) error {
+	if _, err := br.db.Exec("UPDATE buckets SET versioning = ? WHERE id = ?", status, bucketID); err != nil {
+		return fmt.Errorf("failed to update bucket versioning: %w", err)
+	}
+
+	return nil
+}
+
+// GetTags retrieves the tag set of a bucket, nil if it has none.
+// Returns an error if the query fails.
+func (br *bucketRepository) GetTags(bucketID int) (map[string]string, error) {
+	return queryTags(br.db, bucketTagsTable, bucketID)
+}
+
+// SetTags replaces the tag set of a bucket, removing it when tags is empty.
+// Returns repository.ErrNotFound if the bucket no longer exists, or an error if the write fails.
+func (br *bucketRepository) SetTags(bucketID int, tags map[string]string) error {
+	found, err := replaceTags(br.db, bucketTagsTable, "buckets", bucketID, tags)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// HasFiles reports whether a bucket holds any file version, delete markers included.
+// Returns an error if the query fails.
+func (br *bucketRepository) HasFiles(bucketID int) (bool, error) {
+	var exists bool
+	err := br.db.QueryRow("SELECT EXISTS(SELECT 1 FROM files WHERE bucket_id = ?)", bucketID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if bucket has files: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetFiles retrieves the keys of the current objects of a given bucket ID, leaving out
+// noncurrent versions and keys whose latest version is a delete marker.
 // Returns a slice of file keys or an error if the query fails or no files are found.
 func (br *bucketRepository) GetFiles(bucketID int) ([]string, error) {
-	rows, err := br.db.Query("SELECT key FROM files WHERE bucket_id = ?", bucketID)
+	rows, err := br.db.Query("SELECT key FROM files WHERE bucket_id = ? AND is_latest = 1 AND delete_marker = 0", bucketID)
 	if err != nil {
 		return nil, errors.New("no files found with that bucket id")
 	}
@@ -96,7 +254,220 @@ func (br *bucketRepository) GetFiles(bucketID int) ([]string, error) {
 	return keys, nil
 }
 
-// FileExists checks whether a file with the specified key exists within the given bucket name.
+// listFilesQuery lists the current objects of a bucket in key order. Keys containing the delimiter
+// after the prefix are rolled up into a single common prefix entry by the GROUP BY, so objects
+// and common prefixes are paged together as S3 does. The key range predicates (?1 and ?4)
+// and the lower bound (?5) let SQLite walk the (bucket_id, key) index instead of the whole table.
+//
+// Parameters: ?1 prefix, ?2 delimiter, ?3 bucket ID, ?4 prefix upper bound (empty for none),
+// ?5 exclusive lower bound, ?6 inclusive lower bound (empty for none), ?7 limit.
+const listFilesQuery = `
+	SELECT entry, MAX(is_prefix), etag, size, last_modified
+	FROM (
+		SELECT
+			key, etag, size, last_modified,
+			CASE WHEN ?2 <> '' AND instr(substr(key, length(?1) + 1), ?2) > 0
+				THEN 1 ELSE 0 END AS is_prefix,
+			CASE WHEN ?2 <> '' AND instr(substr(key, length(?1) + 1), ?2) > 0
+				THEN substr(key, 1, length(?1) + instr(substr(key, length(?1) + 1), ?2) + length(?2) - 1)
+				ELSE key END AS entry
+		FROM files
+		WHERE bucket_id = ?3
+			AND is_latest = 1
+			AND delete_marker = 0
+			AND key >= ?1
+			AND (?4 = '' OR key < ?4)
+			AND key > ?5
+			AND (?6 = '' OR key >= ?6)
+	)
+	WHERE entry > ?5
+	GROUP BY entry
+	ORDER BY entry
+	LIMIT ?7
+`
+
+// ListFiles lists the files of a bucket matching the given query, sorted by key.
+// With a delimiter, keys sharing the same prefix up to the delimiter are returned as common prefixes.
+// Returns a page of at most query.MaxKeys entries, or an error if the query fails.
+func (br *bucketRepository) ListFiles(bucketID int, query model.ListQuery) (*model.Listing, error) {
+	listing := &model.Listing{Files: make([]model.File, 0), CommonPrefixes: make([]string, 0)}
+	if query.MaxKeys <= 0 {
+		return listing, nil
+	}
+
+	// When continuing after a common prefix, every key under it can be skipped at once.
+	skipUntil := ""
+	if query.Delimiter != "" && len(query.StartAfter) > len(query.Prefix) && strings.HasSuffix(query.StartAfter, query.Delimiter) {
+		skipUntil = prefixUpperBound(query.StartAfter)
+	}
+
+	rows, err := br.db.Query(listFilesQuery,
+		query.Prefix,
+		query.Delimiter,
+		bucketID,
+		prefixUpperBound(query.Prefix),
+		query.StartAfter,
+		skipUntil,
+		query.MaxKeys+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bucket files: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if listing.Count() == query.MaxKeys {
+			listing.IsTruncated = true
+			break
+		}
+
+		var entry string
+		var isPrefix bool
+		var file model.File
+		if err := rows.Scan(&entry, &isPrefix, &file.ETag, &file.Size, &file.LastModified); err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in files listing: %w", err)
+		}
+
+		if isPrefix {
+			listing.CommonPrefixes = append(listing.CommonPrefixes, entry)
+		} else {
+			file.Key = entry
+			file.BucketID = uint(bucketID)
+			listing.Files = append(listing.Files, file)
+		}
+		listing.LastEntry = entry
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return listing, nil
+}
+
+// listVersionsQuery lists the versions of the keys of a bucket in key order, the newest version of
+// each key first, after the version with ID ?5 of key ?4 (or after key ?4 when ?5 is 0).
+// Like listFilesQuery, the key range predicates let SQLite walk the (bucket_id, key, id DESC) index.
+//
+// Parameters: ?1 bucket ID, ?2 prefix, ?3 prefix upper bound (empty for none), ?4 key marker,
+// ?5 version ID marker row ID, ?6 inclusive lower bound (empty for none).
+const listVersionsQuery = `
+	SELECT ` + fileColumns + `
+	FROM files
+	WHERE bucket_id = ?1
+		AND key >= ?2
+		AND (?3 = '' OR key < ?3)
+		AND key >= ?4
+		AND (key > ?4 OR id < ?5)
+		AND (?6 = '' OR key >= ?6)
+	ORDER BY key, id DESC
+`
+
+// ListVersions lists the versions and delete markers of the files of a bucket matching the given
+// query, sorted by key and from the newest to the oldest version of each key. With a delimiter,
+// keys sharing the same prefix up to the delimiter are returned as common prefixes.
+// Returns a page of at most query.MaxKeys entries, repository.ErrNotFound if query.VersionIDMarker
+// is not a version of query.KeyMarker, or an error if the query fails.
+func (br *bucketRepository) ListVersions(bucketID int, query model.VersionQuery) (*model.VersionListing, error) {
+	listing := &model.VersionListing{Versions: make([]model.File, 0), CommonPrefixes: make([]string, 0)}
+
+	markerID := 0
+	if query.VersionIDMarker != "" {
+		err := br.db.QueryRow("SELECT id FROM files WHERE bucket_id = ? AND key = ? AND version_id = ?",
+			bucketID, query.KeyMarker, query.VersionIDMarker).Scan(&markerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find version ID marker: %w", err)
+		}
+	}
+
+	if query.MaxKeys <= 0 {
+		return listing, nil
+	}
+
+	// When continuing after a common prefix, every key under it can be skipped at once.
+	skipUntil := ""
+	if query.Delimiter != "" && len(query.KeyMarker) > len(query.Prefix) && strings.HasSuffix(query.KeyMarker, query.Delimiter) {
+		skipUntil = prefixUpperBound(query.KeyMarker)
+	}
+
+	rows, err := br.db.Query(listVersionsQuery,
+		bucketID,
+		query.Prefix,
+		prefixUpperBound(query.Prefix),
+		query.KeyMarker,
+		markerID,
+		skipUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bucket file versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in file versions listing: %w", err)
+		}
+
+		prefix := ""
+		if query.Delimiter != "" {
+			if i := strings.Index(file.Key[len(query.Prefix):], query.Delimiter); i >= 0 {
+				prefix = file.Key[:len(query.Prefix)+i+len(query.Delimiter)]
+			}
+		}
+
+		// The versions of the keys under a common prefix already listed are rolled up into it
+		last := len(listing.CommonPrefixes) - 1
+		if prefix != "" && (prefix == query.KeyMarker || last >= 0 && listing.CommonPrefixes[last] == prefix) {
+			continue
+		}
+
+		if listing.Count() == query.MaxKeys {
+			listing.IsTruncated = true
+			break
+		}
+
+		if prefix != "" {
+			listing.CommonPrefixes = append(listing.CommonPrefixes, prefix)
+			listing.NextKeyMarker, listing.NextVersionIDMarker = prefix, ""
+			continue
+		}
+
+		file.BucketID = uint(bucketID)
+		listing.Versions = append(listing.Versions, *file)
+		listing.NextKeyMarker, listing.NextVersionIDMarker = file.Key, file.VersionID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	if !listing.IsTruncated {
+		listing.NextKeyMarker, listing.NextVersionIDMarker = "", ""
+	}
+
+	return listing, nil
+}
+
+// prefixUpperBound returns the smallest string greater than every string starting with prefix,
+// or an empty string if there is none (empty prefix or only 0xff bytes).
+func prefixUpperBound(prefix string) string {
+	bound := []byte(prefix)
+	for i := len(bound) - 1; i >= 0; i-- {
+		if bound[i] < 0xff {
+			bound[i]++
+			return string(bound[:i+1])
+		}
+	}
+
+	return ""
+}
+
+// FileExists checks whether a current object with the specified key exists within the given bucket name,
+// that is a key whose latest version is not a delete marker.
 // Returns true if the file exists, false otherwise, or an error if the query fails.
 func (br *bucketRepository) FileExists(bucketName string, key string) (bool, error) {
 	var exists bool
@@ -105,7 +476,7 @@ func (br *bucketRepository) FileExists(bucketName string, key string) (bool, err
 			SELECT 1
 			FROM files f
 			INNER JOIN buckets b ON f.bucket_id = b.id
-			WHERE b.name = ? AND f.key = ?
+			WHERE b.name = ? AND f.key = ? AND f.is_latest = 1 AND f.delete_marker = 0
 		)
 	`
 
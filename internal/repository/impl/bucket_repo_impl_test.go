@@ -0,0 +1,245 @@
+package impl
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/config"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+// newTestBucket opens a new in-memory database holding an empty bucket "bkt".
+// Returns the database and the bucket.
+func newTestBucket(t *testing.T) (*sql.DB, *model.Bucket) {
+	t.Helper()
+
+	db := config.ConfigDatabase("")
+	t.Cleanup(func() { db.Close() })
+
+	buckets := NewBucketRepository(db)
+	if err := buckets.New(&model.Bucket{Name: "bkt", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	bucket, err := buckets.GetByName("bkt")
+	if err != nil {
+		t.Fatalf("GetByName() unexpected error: %v", err)
+	}
+
+	return db, bucket
+}
+
+// putFile stores a version of key in bucket with the given version ID, as the latest one.
+func putFile(t *testing.T, db *sql.DB, bucket *model.Bucket, key string, versionID string) *model.File {
+	t.Helper()
+
+	file := &model.File{
+		BucketID:     uint(bucket.ID),
+		Key:          key,
+		VersionID:    versionID,
+		BlobRef:      key + "@" + versionID,
+		ETag:         "etag-" + key + "@" + versionID,
+		Size:         int64(len(key)),
+		CreatedAt:    time.Now(),
+		LastModified: time.Now(),
+	}
+	if _, err := NewFileRepository(db).Put(file); err != nil {
+		t.Fatalf("Put(%q) unexpected error: %v", key, err)
+	}
+
+	return file
+}
+
+func TestListFiles(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	for _, key := range []string{
+		"a.txt",
+		"docs/2025/report.pdf",
+		"docs/2026/report.pdf",
+		"docs/readme.md",
+		"photos/cat.jpg",
+		"photos/dog.jpg",
+		"z\xff\xff/x",
+		"z\xff\xff/y",
+	} {
+		putFile(t, db, bucket, key, model.NullVersionID)
+	}
+
+	tests := []struct {
+		name          string
+		query         model.ListQuery
+		wantFiles     []string
+		wantPrefixes  []string
+		wantTruncated bool
+		wantLastEntry string
+	}{
+		{
+			name:          "every key",
+			query:         model.ListQuery{MaxKeys: 1000},
+			wantFiles:     []string{"a.txt", "docs/2025/report.pdf", "docs/2026/report.pdf", "docs/readme.md", "photos/cat.jpg", "photos/dog.jpg", "z\xff\xff/x", "z\xff\xff/y"},
+			wantLastEntry: "z\xff\xff/y",
+		},
+		{
+			name:          "prefix",
+			query:         model.ListQuery{Prefix: "docs/", MaxKeys: 1000},
+			wantFiles:     []string{"docs/2025/report.pdf", "docs/2026/report.pdf", "docs/readme.md"},
+			wantLastEntry: "docs/readme.md",
+		},
+		{
+			name:          "prefix ending with 0xff bytes",
+			query:         model.ListQuery{Prefix: "z\xff\xff", MaxKeys: 1000},
+			wantFiles:     []string{"z\xff\xff/x", "z\xff\xff/y"},
+			wantLastEntry: "z\xff\xff/y",
+		},
+		{
+			name:          "delimiter rolls up common prefixes",
+			query:         model.ListQuery{Delimiter: "/", MaxKeys: 1000},
+			wantFiles:     []string{"a.txt"},
+			wantPrefixes:  []string{"docs/", "photos/", "z\xff\xff/"},
+			wantLastEntry: "z\xff\xff/",
+		},
+		{
+			name:          "delimiter under a prefix",
+			query:         model.ListQuery{Prefix: "docs/", Delimiter: "/", MaxKeys: 1000},
+			wantFiles:     []string{"docs/readme.md"},
+			wantPrefixes:  []string{"docs/2025/", "docs/2026/"},
+			wantLastEntry: "docs/readme.md",
+		},
+		{
+			name:          "start after a key",
+			query:         model.ListQuery{StartAfter: "docs/readme.md", MaxKeys: 1000},
+			wantFiles:     []string{"photos/cat.jpg", "photos/dog.jpg", "z\xff\xff/x", "z\xff\xff/y"},
+			wantLastEntry: "z\xff\xff/y",
+		},
+		{
+			name:          "continuation landing on a common prefix",
+			query:         model.ListQuery{Delimiter: "/", StartAfter: "docs/", MaxKeys: 1000},
+			wantPrefixes:  []string{"photos/", "z\xff\xff/"},
+			wantLastEntry: "z\xff\xff/",
+		},
+		{
+			name:          "continuation after a common prefix of 0xff bytes",
+			query:         model.ListQuery{Delimiter: "/", StartAfter: "z\xff\xff/", MaxKeys: 1000},
+			wantLastEntry: "",
+		},
+		{
+			name:          "max keys 1",
+			query:         model.ListQuery{MaxKeys: 1},
+			wantFiles:     []string{"a.txt"},
+			wantTruncated: true,
+			wantLastEntry: "a.txt",
+		},
+		{
+			name:          "max keys 1 on a common prefix",
+			query:         model.ListQuery{Delimiter: "/", StartAfter: "a.txt", MaxKeys: 1},
+			wantPrefixes:  []string{"docs/"},
+			wantTruncated: true,
+			wantLastEntry: "docs/",
+		},
+		{
+			name:          "page ending on the last entry is not truncated",
+			query:         model.ListQuery{Prefix: "photos/", MaxKeys: 2},
+			wantFiles:     []string{"photos/cat.jpg", "photos/dog.jpg"},
+			wantLastEntry: "photos/dog.jpg",
+		},
+		{
+			name:  "max keys 0",
+			query: model.ListQuery{MaxKeys: 0},
+		},
+	}
+
+	repository := NewBucketRepository(db)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, err := repository.ListFiles(bucket.ID, tt.query)
+			if err != nil {
+				t.Fatalf("ListFiles() unexpected error: %v", err)
+			}
+
+			keys := make([]string, 0, len(listing.Files))
+			for _, file := range listing.Files {
+				keys = append(keys, file.Key)
+			}
+			if !slices.Equal(keys, tt.wantFiles) && len(keys)+len(tt.wantFiles) > 0 {
+				t.Errorf("Files = %q, want %q", keys, tt.wantFiles)
+			}
+			if !slices.Equal(listing.CommonPrefixes, tt.wantPrefixes) && len(listing.CommonPrefixes)+len(tt.wantPrefixes) > 0 {
+				t.Errorf("CommonPrefixes = %q, want %q", listing.CommonPrefixes, tt.wantPrefixes)
+			}
+			if listing.IsTruncated != tt.wantTruncated {
+				t.Errorf("IsTruncated = %v, want %v", listing.IsTruncated, tt.wantTruncated)
+			}
+			if listing.LastEntry != tt.wantLastEntry {
+				t.Errorf("LastEntry = %q, want %q", listing.LastEntry, tt.wantLastEntry)
+			}
+		})
+	}
+}
+
+func TestListFilesPaging(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	for _, key := range []string{"a/1", "a/2", "b", "c/1", "d"} {
+		putFile(t, db, bucket, key, model.NullVersionID)
+	}
+
+	// Paging one entry at a time from LastEntry visits every entry exactly once.
+	repository := NewBucketRepository(db)
+	query := model.ListQuery{Delimiter: "/", MaxKeys: 1}
+	var entries []string
+	for {
+		listing, err := repository.ListFiles(bucket.ID, query)
+		if err != nil {
+			t.Fatalf("ListFiles() unexpected error: %v", err)
+		}
+		entries = append(entries, listing.LastEntry)
+		if !listing.IsTruncated {
+			break
+		}
+		query.StartAfter = listing.LastEntry
+	}
+
+	if want := []string{"a/", "b", "c/", "d"}; !slices.Equal(entries, want) {
+		t.Errorf("entries = %q, want %q", entries, want)
+	}
+}
+
+func TestListFilesListsCurrentVersionsOnly(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	putFile(t, db, bucket, "a", "v1")
+	putFile(t, db, bucket, "a", "v2")
+	putFile(t, db, bucket, "b", "v1")
+
+	marker := &model.File{BucketID: uint(bucket.ID), Key: "b", VersionID: "m1", DeleteMarker: true, CreatedAt: time.Now()}
+	if _, err := NewFileRepository(db).Put(marker); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	listing, err := NewBucketRepository(db).ListFiles(bucket.ID, model.ListQuery{MaxKeys: 1000})
+	if err != nil {
+		t.Fatalf("ListFiles() unexpected error: %v", err)
+	}
+	if len(listing.Files) != 1 || listing.Files[0].Key != "a" || listing.Files[0].ETag != "etag-a@v2" {
+		t.Errorf("Files = %+v, want the version v2 of a only", listing.Files)
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "", want: ""},
+		{prefix: "abc", want: "abd"},
+		{prefix: "a/", want: "a0"},
+		{prefix: "a\xff", want: "b"},
+		{prefix: "a\xff\xff", want: "b"},
+		{prefix: "\xff\xff", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := prefixUpperBound(tt.prefix); got != tt.want {
+			t.Errorf("prefixUpperBound(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package impl
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+)
+
+// CORSRepository handles the CORS rules of buckets in the database.
+type corsRepository struct {
+	db *sql.DB
+}
+
+// NewCORSRepository creates a new CORSRepository with the given database connection.
+func NewCORSRepository(db *sql.DB) repository.CORSRepository {
+	return &corsRepository{db: db}
+}
+
+// Put replaces the CORS rules of a bucket in a single transaction.
+// Returns an error if the replacement fails.
+func (cr *corsRepository) Put(bucketID int, rules []model.CORSRule) error {
+	tx, err := cr.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteCORSRules(tx, bucketID); err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		_, err := tx.Exec(`
+			INSERT INTO cors_rules (
+				bucket_id, rule_id, allowed_origins, allowed_methods, allowed_headers, expose_headers, max_age_seconds
+			) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			bucketID,
+			rule.ID,
+			encodeList(rule.AllowedOrigins),
+			encodeList(rule.AllowedMethods),
+			encodeList(rule.AllowedHeaders),
+			encodeList(rule.ExposeHeaders),
+			rule.MaxAgeSeconds,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting CORS rule DB row: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit CORS rules: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves the CORS rules of a bucket, in the order they were configured.
+// Returns repository.ErrNotFound if the bucket has no CORS rules, or an error if the query fails.
+func (cr *corsRepository) Get(bucketID int) ([]model.CORSRule, error) {
+	rows, err := cr.db.Query(`
+		SELECT rule_id, allowed_origins, allowed_methods, allowed_headers, expose_headers, max_age_seconds
+		FROM cors_rules
+		WHERE bucket_id = ?
+		ORDER BY id`, bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query CORS rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]model.CORSRule, 0)
+	for rows.Next() {
+		var rule model.CORSRule
+		var origins, methods, headers, exposed string
+
+		if err := rows.Scan(&rule.ID, &origins, &methods, &headers, &exposed, &rule.MaxAgeSeconds); err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in CORS rules iteration: %w", err)
+		}
+		rule.AllowedOrigins = decodeList(origins)
+		rule.AllowedMethods = decodeList(methods)
+		rule.AllowedHeaders = decodeList(headers)
+		rule.ExposeHeaders = decodeList(exposed)
+
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	if len(rules) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	return rules, nil
+}
+
+// Remove deletes the CORS rules of a bucket, if it has any.
+// Returns an error if the deletion fails.
+func (cr *corsRepository) Remove(bucketID int) error {
+	if _, err := cr.db.Exec("DELETE FROM cors_rules WHERE bucket_id = ?", bucketID); err != nil {
+		return fmt.Errorf("failed to remove CORS rules: %w", err)
+	}
+
+	return nil
+}
+
+// deleteCORSRules deletes the CORS rules of a bucket within the given transaction.
+func deleteCORSRules(tx *sql.Tx, bucketID int) error {
+	if _, err := tx.Exec("DELETE FROM cors_rules WHERE bucket_id = ?", bucketID); err != nil {
+		return fmt.Errorf("failed to remove CORS rules: %w", err)
+	}
+
+	return nil
+}
+
+// encodeList encodes the values of a CORS rule list as the newline-separated text stored in its column.
+func encodeList(values []string) string {
+	return strings.Join(values, "\n")
+}
+
+// decodeList decodes a column value written by encodeList, nil for an empty list.
+func decodeList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, "\n")
+}
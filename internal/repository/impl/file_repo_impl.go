@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/bonifacio-pedro/s3ego/internal/model"
 	"github.com/bonifacio-pedro/s3ego/internal/repository"
@@ -20,52 +22,424 @@ func NewFileRepository(db *sql.DB) repository.FileRepository {
 	return &fileRepository{db: db}
 }
 
-// New inserts a new file record into the files table.
-// Returns an error if the insertion fails.
-func (fr *fileRepository) New(file *model.File) error {
-	_, err := fr.db.Exec(`
+// fileColumns are the columns of the files table read into a model.File by scanFile.
+const fileColumns = `id, key, COALESCE(blob_ref, ''), bucket_id, etag, content_type, size,
+	COALESCE(part_sizes, ''), created_at, last_modified, version_id, is_latest, delete_marker`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanFile reads a row selecting fileColumns into a file, without its metadata.
+func scanFile(row rowScanner) (*model.File, error) {
+	var f model.File
+	var partSizes string
+
+	err := row.Scan(&f.ID, &f.Key, &f.BlobRef, &f.BucketID, &f.ETag, &f.ContentType, &f.Size, &partSizes,
+		&f.CreatedAt, &f.LastModified, &f.VersionID, &f.IsLatest, &f.DeleteMarker)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.PartSizes, err = decodePartSizes(partSizes); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// New stores a file as the latest version of its key, as Put does, only if the key has no
+// current object: it has no version, or its latest version is a delete marker.
+// Returns the references of the blobs no longer used by any file or part (a replaced "null" version),
+// which the caller must delete from the BlobStore, repository.ErrAlreadyExists if the bucket
+// already holds a current object with the same key, or an error if the insertion fails.
+func (fr *fileRepository) New(file *model.File) ([]string, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := queryVersion(tx, "bucket_id = ? AND key = ? AND is_latest = 1", file.BucketID, file.Key)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && !current.DeleteMarker {
+		return nil, repository.ErrAlreadyExists
+	}
+
+	released, err := putVersion(tx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file insertion: %w", err)
+	}
+
+	return released, nil
+}
+
+// Put stores a file, or a delete marker, as the latest version of its key. A file with the
+// same version ID (the "null" version) is replaced, keeping its CreatedAt, and the previous
+// latest version is kept as a noncurrent version.
+// Returns the references of the blobs no longer used by any file or part (the replaced data),
+// which the caller must delete from the BlobStore, or an error if the write fails.
+func (fr *fileRepository) Put(file *model.File) ([]string, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	released, err := putVersion(tx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file write: %w", err)
+	}
+
+	return released, nil
+}
+
+// PutCopy stores a file sharing the blob of another file, as Put does, after checking in the same
+// transaction that the blob is still referenced, so a concurrent removal of the source cannot release it.
+// Returns the references of the blobs no longer used by any file or part (the replaced data),
+// which the caller must delete from the BlobStore, repository.ErrNotFound if no file or part
+// references file.BlobRef anymore, or an error if the write fails.
+func (fr *fileRepository) PutCopy(file *model.File) ([]string, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	used, err := blobInUse(tx, file.BlobRef)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, repository.ErrNotFound
+	}
+
+	released, err := putVersion(tx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file copy: %w", err)
+	}
+
+	return released, nil
+}
+
+// Replace stores a file as the latest version of its key, as Put does, only if the ETag of the
+// current object is etag, checking and writing it in a single transaction.
+// Returns the references of the blobs no longer used by any file or part (the replaced data),
+// which the caller must delete from the BlobStore, repository.ErrNotFound if the key has no current
+// object, repository.ErrConditionFailed if its ETag is not etag, or an error if the write fails.
+func (fr *fileRepository) Replace(file *model.File, etag string) ([]string, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := queryVersion(tx, "bucket_id = ? AND key = ? AND is_latest = 1", file.BucketID, file.Key)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.DeleteMarker {
+		return nil, repository.ErrNotFound
+	}
+
+	if current.ETag != etag {
+		return nil, repository.ErrConditionFailed
+	}
+
+	released, err := putVersion(tx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file replacement: %w", err)
+	}
+
+	return released, nil
+}
+
+// putVersion stores a file as the latest version of its key within the given transaction,
+// setting file.ID, and file.CreatedAt when it replaces a file with the same version ID.
+// Returns the references of the blobs released by the replacement, or an error if the write fails.
+func putVersion(tx *sql.Tx, file *model.File) ([]string, error) {
+	replaced, err := queryVersion(tx, "bucket_id = ? AND key = ? AND version_id = ?", file.BucketID, file.Key, file.VersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, 1)
+	if replaced != nil {
+		if err := deleteVersion(tx, replaced.ID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, replaced.BlobRef)
+		file.CreatedAt = replaced.CreatedAt
+	}
+
+	_, err = tx.Exec("UPDATE files SET is_latest = 0 WHERE bucket_id = ? AND key = ? AND is_latest = 1", file.BucketID, file.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error updating file DB rows in files: %w", err)
+	}
+
+	file.IsLatest = true
+	result, err := tx.Exec(`
 		INSERT INTO files (
-			key, data, bucket_id, etag, content_type, size, created_at, last_modified
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			key, blob_ref, bucket_id, etag, content_type, size, part_sizes, created_at, last_modified,
+			version_id, is_latest, delete_marker
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		file.Key,
-		file.Data,
+		file.BlobRef,
 		file.BucketID,
 		file.ETag,
 		file.ContentType,
 		file.Size,
+		encodePartSizes(file.PartSizes),
 		file.CreatedAt,
 		file.LastModified,
+		file.VersionID,
+		file.IsLatest,
+		file.DeleteMarker,
 	)
 	if err != nil {
-		return fmt.Errorf("error inserting file DB row into files: %w", err)
+		return nil, fmt.Errorf("error inserting file DB row into files: %w", err)
 	}
 
-	return nil
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file DB row ID: %w", err)
+	}
+	file.ID = int(id)
+
+	if err := insertMetadata(tx, fileMetadataTable, file.ID, file.Metadata); err != nil {
+		return nil, err
+	}
+
+	if err := insertTags(tx, fileTagsTable, file.ID, file.Tags); err != nil {
+		return nil, err
+	}
+
+	return releasedBlobs(tx, refs)
+}
+
+// Remove permanently deletes a version of the file with the given key from a bucket, together
+// with its metadata. When the latest version is deleted, the newest remaining one becomes the latest.
+// A version the bucket does not hold is skipped.
+// Returns the references of the blobs no longer used by any file or part, which the caller
+// must delete from the BlobStore, or an error if the deletion fails.
+func (fr *fileRepository) Remove(bucketID int, key string, versionID string) ([]string, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	refs, err := removeVersion(tx, bucketID, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := releasedBlobs(tx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit file removal: %w", err)
+	}
+
+	return released, nil
 }
 
-// Remove deletes a file record from the files table by its key.
-// Returns an error if the deletion fails.
-func (fr *fileRepository) Remove(key string) error {
-	_, err := fr.db.Exec("DELETE FROM files WHERE key=?", key)
+// RemoveAll permanently deletes the given versions of files of a bucket, as Remove does,
+// and stores the given delete markers, as Put does, in a single transaction.
+// Returns the references of the blobs no longer used by any file or part, which the caller
+// must delete from the BlobStore, or an error if any write fails, in which case nothing is changed.
+func (fr *fileRepository) RemoveAll(bucketID int, versions []model.ObjectIdentifier, markers []*model.File) ([]string, error) {
+	tx, err := fr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	refs := make([]string, 0, len(versions)+len(markers))
+	for _, version := range versions {
+		versionRefs, err := removeVersion(tx, bucketID, version.Key, version.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, versionRefs...)
+	}
+
+	for _, marker := range markers {
+		markerRefs, err := putVersion(tx, marker)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, markerRefs...)
+	}
+
+	released, err := releasedBlobs(tx, refs)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit files removal: %w", err)
+	}
+
+	return released, nil
+}
+
+// removeVersion deletes a version of a file, and its metadata and tags, within the given transaction,
+// promoting the newest remaining version of the key to latest if it was the latest.
+// Returns the blob references the version held, or an error if the deletion fails.
+func removeVersion(tx *sql.Tx, bucketID int, key string, versionID string) ([]string, error) {
+	version, err := queryVersion(tx, "bucket_id = ? AND key = ? AND version_id = ?", bucketID, key, versionID)
+	if err != nil || version == nil {
+		return nil, err
+	}
+
+	if err := deleteVersion(tx, version.ID); err != nil {
+		return nil, err
+	}
+
+	if version.IsLatest {
+		_, err := tx.Exec(`
+			UPDATE files SET is_latest = 1
+			WHERE id = (SELECT MAX(id) FROM files WHERE bucket_id = ? AND key = ?)`, bucketID, key)
+		if err != nil {
+			return nil, fmt.Errorf("error updating file DB row in files: %w", err)
+		}
+	}
+
+	return []string{version.BlobRef}, nil
+}
+
+// deleteVersion deletes the file record id, its metadata and its tags within the given transaction.
+func deleteVersion(tx *sql.Tx, id int) error {
+	if err := deleteMetadata(tx, fileMetadataTable, "?", id); err != nil {
+		return err
+	}
+
+	if err := deleteTags(tx, fileTagsTable, "?", id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM files WHERE id = ?", id); err != nil {
 		return fmt.Errorf("error deleting file DB row from files: %w", err)
 	}
 
 	return nil
 }
 
-// GetByKey retrieves a file from the database by its key.
-// Returns the file model or an error if the file does not exist or scanning fails.
-func (fr *fileRepository) GetByKey(key string) (*model.File, error) {
-	row := fr.db.QueryRow("SELECT id, key, data, bucket_id FROM files WHERE key = ?", key)
-	var f model.File
+// queryVersion retrieves, within the given transaction, the file record matching the where clause,
+// without its metadata. Returns nil if there is none, or an error if the query fails.
+func queryVersion(tx *sql.Tx, where string, args ...any) (*model.File, error) {
+	file, err := scanFile(tx.QueryRow("SELECT "+fileColumns+" FROM files WHERE "+where, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning file DB row: %w", err)
+	}
 
-	if err := row.Scan(&f.ID, &f.Key, &f.Data, &f.BucketID); err != nil {
+	return file, nil
+}
+
+// GetByKey retrieves the latest version of a file, with its metadata and tags, from the database by its
+// bucket ID and key. The latest version may be a delete marker.
+// Returns the file model, repository.ErrNotFound if the bucket holds no file with that key,
+// or an error if scanning fails.
+func (fr *fileRepository) GetByKey(bucketID int, key string) (*model.File, error) {
+	return fr.get("bucket_id = ? AND key = ? AND is_latest = 1", bucketID, key)
+}
+
+// GetVersion retrieves a version of a file, with its metadata and tags, from the database by its
+// bucket ID, key and version ID. The version may be a delete marker.
+// Returns the file model, repository.ErrNotFound if the bucket holds no such version,
+// or an error if scanning fails.
+func (fr *fileRepository) GetVersion(bucketID int, key string, versionID string) (*model.File, error) {
+	return fr.get("bucket_id = ? AND key = ? AND version_id = ?", bucketID, key, versionID)
+}
+
+// get retrieves the file record matching the where clause, with its metadata and tags.
+// Returns repository.ErrNotFound if there is none, or an error if scanning fails.
+func (fr *fileRepository) get(where string, args ...any) (*model.File, error) {
+	f, err := scanFile(fr.db.QueryRow("SELECT "+fileColumns+" FROM files WHERE "+where, args...))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("file does not exist")
+			return nil, repository.ErrNotFound
 		}
 		return nil, fmt.Errorf("error scanning file DB row: %w", err)
 	}
 
-	return &f, nil
+	if f.Metadata, err = queryMetadata(fr.db, fileMetadataTable, f.ID); err != nil {
+		return nil, err
+	}
+
+	if f.Tags, err = queryTags(fr.db, fileTagsTable, f.ID); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// SetTags replaces the tag set of the file version fileID, removing it when tags is empty.
+// Returns repository.ErrNotFound if the version no longer exists, or an error if the write fails.
+func (fr *fileRepository) SetTags(fileID int, tags map[string]string) error {
+	found, err := replaceTags(fr.db, fileTagsTable, "files", fileID, tags)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// encodePartSizes encodes the part sizes of a file as the comma-separated list stored
+// in the part_sizes column, empty for a file uploaded at once.
+func encodePartSizes(sizes []int64) string {
+	fields := make([]string, 0, len(sizes))
+	for _, size := range sizes {
+		fields = append(fields, strconv.FormatInt(size, 10))
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// decodePartSizes decodes a part_sizes column value written by encodePartSizes.
+// Returns an error if the value is corrupted.
+func decodePartSizes(value string) ([]int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(value, ",")
+	sizes := make([]int64, 0, len(fields))
+	for _, field := range fields {
+		size, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid part sizes %q: %w", value, err)
+		}
+		sizes = append(sizes, size)
+	}
+
+	return sizes, nil
 }
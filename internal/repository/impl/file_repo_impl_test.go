@@ -0,0 +1,318 @@
+package impl
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+)
+
+// putMarker stores a delete marker with the given version ID as the latest version of key in bucket.
+func putMarker(t *testing.T, db *sql.DB, bucket *model.Bucket, key string, versionID string) {
+	t.Helper()
+
+	marker := &model.File{
+		BucketID:     uint(bucket.ID),
+		Key:          key,
+		VersionID:    versionID,
+		DeleteMarker: true,
+		CreatedAt:    time.Now(),
+		LastModified: time.Now(),
+	}
+	if _, err := NewFileRepository(db).Put(marker); err != nil {
+		t.Fatalf("Put(%q) unexpected error: %v", key, err)
+	}
+}
+
+// versionIDs returns the version IDs of the versions of key held by bucket, newest first, with the
+// version ID of the latest one.
+func versionIDs(t *testing.T, db *sql.DB, bucket *model.Bucket, key string) ([]string, string) {
+	t.Helper()
+
+	listing, err := NewBucketRepository(db).ListVersions(bucket.ID, model.VersionQuery{Prefix: key, MaxKeys: 1000})
+	if err != nil {
+		t.Fatalf("ListVersions() unexpected error: %v", err)
+	}
+
+	ids := make([]string, 0, len(listing.Versions))
+	latest := ""
+	for _, version := range listing.Versions {
+		ids = append(ids, version.VersionID)
+		if version.IsLatest {
+			latest = version.VersionID
+		}
+	}
+
+	return ids, latest
+}
+
+func TestPutVersionedOverwrite(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	putFile(t, db, bucket, "a", "v1")
+	putFile(t, db, bucket, "a", "v2")
+
+	files := NewFileRepository(db)
+	latest, err := files.GetByKey(bucket.ID, "a")
+	if err != nil {
+		t.Fatalf("GetByKey() unexpected error: %v", err)
+	}
+	if latest.VersionID != "v2" || !latest.IsLatest {
+		t.Errorf("GetByKey() = version %q, IsLatest = %v, want v2 and true", latest.VersionID, latest.IsLatest)
+	}
+
+	previous, err := files.GetVersion(bucket.ID, "a", "v1")
+	if err != nil {
+		t.Fatalf("GetVersion() unexpected error: %v", err)
+	}
+	if previous.IsLatest || previous.BlobRef != "a@v1" {
+		t.Errorf("GetVersion(v1) IsLatest = %v, BlobRef = %q, want false and a@v1", previous.IsLatest, previous.BlobRef)
+	}
+
+	if ids, latestID := versionIDs(t, db, bucket, "a"); !slices.Equal(ids, []string{"v2", "v1"}) || latestID != "v2" {
+		t.Errorf("versions = %q, latest %q, want [v2 v1], latest v2", ids, latestID)
+	}
+}
+
+func TestPutReplacesSameVersion(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	first := putFile(t, db, bucket, "a", model.NullVersionID)
+
+	second := &model.File{
+		BucketID:     uint(bucket.ID),
+		Key:          "a",
+		VersionID:    model.NullVersionID,
+		BlobRef:      "a@second",
+		CreatedAt:    time.Now().Add(time.Hour),
+		LastModified: time.Now().Add(time.Hour),
+	}
+	released, err := NewFileRepository(db).Put(second)
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	if !slices.Equal(released, []string{first.BlobRef}) {
+		t.Errorf("Put() released %q, want %q", released, first.BlobRef)
+	}
+	if !second.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want the CreatedAt of the replaced version %v", second.CreatedAt, first.CreatedAt)
+	}
+	if ids, _ := versionIDs(t, db, bucket, "a"); len(ids) != 1 {
+		t.Errorf("versions = %q, want a single null version", ids)
+	}
+}
+
+func TestPutDeleteMarker(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	putFile(t, db, bucket, "a", "v1")
+	putMarker(t, db, bucket, "a", "m1")
+
+	latest, err := NewFileRepository(db).GetByKey(bucket.ID, "a")
+	if err != nil {
+		t.Fatalf("GetByKey() unexpected error: %v", err)
+	}
+	if latest.VersionID != "m1" || !latest.DeleteMarker {
+		t.Errorf("GetByKey() = version %q, DeleteMarker = %v, want m1 and true", latest.VersionID, latest.DeleteMarker)
+	}
+
+	listing, err := NewBucketRepository(db).ListFiles(bucket.ID, model.ListQuery{MaxKeys: 1000})
+	if err != nil {
+		t.Fatalf("ListFiles() unexpected error: %v", err)
+	}
+	if len(listing.Files) != 0 {
+		t.Errorf("ListFiles() = %d files, want none behind a delete marker", len(listing.Files))
+	}
+
+	if ids, latestID := versionIDs(t, db, bucket, "a"); !slices.Equal(ids, []string{"m1", "v1"}) || latestID != "m1" {
+		t.Errorf("versions = %q, latest %q, want [m1 v1], latest m1", ids, latestID)
+	}
+}
+
+func TestRemoveVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		remove       string
+		wantReleased []string
+		wantVersions []string
+		wantLatest   string
+	}{
+		{
+			name:         "latest version promotes the previous one",
+			remove:       "v3",
+			wantReleased: []string{"a@v3"},
+			wantVersions: []string{"v2", "v1"},
+			wantLatest:   "v2",
+		},
+		{
+			name:         "noncurrent version keeps the latest",
+			remove:       "v2",
+			wantReleased: []string{"a@v2"},
+			wantVersions: []string{"v3", "v1"},
+			wantLatest:   "v3",
+		},
+		{
+			name:         "unknown version is skipped",
+			remove:       "v9",
+			wantReleased: []string{},
+			wantVersions: []string{"v3", "v2", "v1"},
+			wantLatest:   "v3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, bucket := newTestBucket(t)
+			for _, versionID := range []string{"v1", "v2", "v3"} {
+				putFile(t, db, bucket, "a", versionID)
+			}
+
+			released, err := NewFileRepository(db).Remove(bucket.ID, "a", tt.remove)
+			if err != nil {
+				t.Fatalf("Remove() unexpected error: %v", err)
+			}
+
+			if !slices.Equal(released, tt.wantReleased) && len(released)+len(tt.wantReleased) > 0 {
+				t.Errorf("Remove() released %q, want %q", released, tt.wantReleased)
+			}
+			ids, latest := versionIDs(t, db, bucket, "a")
+			if !slices.Equal(ids, tt.wantVersions) || latest != tt.wantLatest {
+				t.Errorf("versions = %q, latest %q, want %q, latest %q", ids, latest, tt.wantVersions, tt.wantLatest)
+			}
+		})
+	}
+}
+
+func TestRemoveDeleteMarkerRestoresObject(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	putFile(t, db, bucket, "a", "v1")
+	putMarker(t, db, bucket, "a", "m1")
+
+	files := NewFileRepository(db)
+	if _, err := files.Remove(bucket.ID, "a", "m1"); err != nil {
+		t.Fatalf("Remove() unexpected error: %v", err)
+	}
+
+	latest, err := files.GetByKey(bucket.ID, "a")
+	if err != nil {
+		t.Fatalf("GetByKey() unexpected error: %v", err)
+	}
+	if latest.VersionID != "v1" || latest.DeleteMarker {
+		t.Errorf("GetByKey() = version %q, DeleteMarker = %v, want v1 and false", latest.VersionID, latest.DeleteMarker)
+	}
+}
+
+func TestListVersionsPaging(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	for _, versionID := range []string{"v1", "v2", "v3"} {
+		putFile(t, db, bucket, "a", versionID)
+	}
+	putFile(t, db, bucket, "b", "v1")
+	putMarker(t, db, bucket, "b", "m1")
+
+	// Paging two entries at a time from the next markers visits every version exactly once.
+	buckets := NewBucketRepository(db)
+	query := model.VersionQuery{MaxKeys: 2}
+	var versions []string
+	for {
+		listing, err := buckets.ListVersions(bucket.ID, query)
+		if err != nil {
+			t.Fatalf("ListVersions() unexpected error: %v", err)
+		}
+		for _, version := range listing.Versions {
+			versions = append(versions, version.Key+"@"+version.VersionID)
+		}
+		if !listing.IsTruncated {
+			break
+		}
+		query.KeyMarker, query.VersionIDMarker = listing.NextKeyMarker, listing.NextVersionIDMarker
+	}
+
+	if want := []string{"a@v3", "a@v2", "a@v1", "b@m1", "b@v1"}; !slices.Equal(versions, want) {
+		t.Errorf("versions = %q, want %q", versions, want)
+	}
+
+	_, err := buckets.ListVersions(bucket.ID, model.VersionQuery{KeyMarker: "a", VersionIDMarker: "v9", MaxKeys: 2})
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ListVersions() with an unknown version ID marker error = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func TestPutCopySharesBlob(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	source := putFile(t, db, bucket, "a", model.NullVersionID)
+
+	files := NewFileRepository(db)
+	copied := &model.File{BucketID: uint(bucket.ID), Key: "b", VersionID: model.NullVersionID, BlobRef: source.BlobRef, CreatedAt: time.Now()}
+	if _, err := files.PutCopy(copied); err != nil {
+		t.Fatalf("PutCopy() unexpected error: %v", err)
+	}
+
+	// The blob is released only once neither the source nor the copy references it.
+	released, err := files.Remove(bucket.ID, "a", model.NullVersionID)
+	if err != nil {
+		t.Fatalf("Remove(a) unexpected error: %v", err)
+	}
+	if len(released) != 0 {
+		t.Errorf("Remove(a) released %q while the copy still references it", released)
+	}
+
+	released, err = files.Remove(bucket.ID, "b", model.NullVersionID)
+	if err != nil {
+		t.Fatalf("Remove(b) unexpected error: %v", err)
+	}
+	if !slices.Equal(released, []string{source.BlobRef}) {
+		t.Errorf("Remove(b) released %q, want %q", released, source.BlobRef)
+	}
+
+	orphan := &model.File{BucketID: uint(bucket.ID), Key: "c", VersionID: model.NullVersionID, BlobRef: source.BlobRef, CreatedAt: time.Now()}
+	if _, err := files.PutCopy(orphan); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("PutCopy() of a released blob error = %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	putFile(t, db, bucket, "a", "v1")
+	putFile(t, db, bucket, "a", "v2")
+	putFile(t, db, bucket, "b", "v1")
+
+	marker := &model.File{BucketID: uint(bucket.ID), Key: "b", VersionID: "m1", DeleteMarker: true, CreatedAt: time.Now()}
+	versions := []model.ObjectIdentifier{{Key: "a", VersionID: "v2"}, {Key: "a", VersionID: "v9"}}
+	released, err := NewFileRepository(db).RemoveAll(bucket.ID, versions, []*model.File{marker})
+	if err != nil {
+		t.Fatalf("RemoveAll() unexpected error: %v", err)
+	}
+
+	if !slices.Equal(released, []string{"a@v2"}) {
+		t.Errorf("RemoveAll() released %q, want [a@v2]", released)
+	}
+	if ids, latest := versionIDs(t, db, bucket, "a"); !slices.Equal(ids, []string{"v1"}) || latest != "v1" {
+		t.Errorf("versions of a = %q, latest %q, want [v1], latest v1", ids, latest)
+	}
+	if ids, latest := versionIDs(t, db, bucket, "b"); !slices.Equal(ids, []string{"m1", "v1"}) || latest != "m1" {
+		t.Errorf("versions of b = %q, latest %q, want [m1 v1], latest m1", ids, latest)
+	}
+}
+
+func TestRemoveAllRollsBackOnError(t *testing.T) {
+	db, bucket := newTestBucket(t)
+	putFile(t, db, bucket, "a", "v1")
+
+	// A trigger rejecting delete markers makes the removal fail after deleting the version.
+	marker := &model.File{BucketID: uint(bucket.ID), Key: "b", VersionID: "m1", DeleteMarker: true, CreatedAt: time.Now()}
+	if _, err := db.Exec("CREATE TRIGGER fail_markers BEFORE INSERT ON files WHEN NEW.delete_marker BEGIN SELECT RAISE(ABORT, 'rejected'); END"); err != nil {
+		t.Fatal(err)
+	}
+
+	versions := []model.ObjectIdentifier{{Key: "a", VersionID: "v1"}}
+	if _, err := NewFileRepository(db).RemoveAll(bucket.ID, versions, []*model.File{marker}); err == nil {
+		t.Fatal("RemoveAll() error = nil, want the marker insertion error")
+	}
+
+	if ids, _ := versionIDs(t, db, bucket, "a"); !slices.Equal(ids, []string{"v1"}) {
+		t.Errorf("versions of a = %q after a failed RemoveAll(), want [v1]", ids)
+	}
+}
@@ -0,0 +1,234 @@
+package impl
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+)
+
+// LifecycleRepository handles the lifecycle rules of buckets in the database.
+type lifecycleRepository struct {
+	db *sql.DB
+}
+
+// NewLifecycleRepository creates a new LifecycleRepository with the given database connection.
+func NewLifecycleRepository(db *sql.DB) repository.LifecycleRepository {
+	return &lifecycleRepository{db: db}
+}
+
+// Put replaces the lifecycle rules of a bucket, with their tag filters, in a single transaction.
+// Returns an error if the replacement fails.
+func (lr *lifecycleRepository) Put(bucketID int, rules []model.LifecycleRule) error {
+	tx, err := lr.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteLifecycleRules(tx, bucketID); err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		var expirationDate sql.NullTime
+		if !rule.ExpirationDate.IsZero() {
+			expirationDate = sql.NullTime{Time: rule.ExpirationDate.UTC(), Valid: true}
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO lifecycle_rules (
+				bucket_id, rule_id, status, prefix, expiration_days, expiration_date, expired_object_delete_marker,
+				noncurrent_days, newer_noncurrent_versions, abort_incomplete_upload_days
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			bucketID,
+			rule.ID,
+			rule.Status,
+			rule.Prefix,
+			rule.ExpirationDays,
+			expirationDate,
+			rule.ExpiredObjectDeleteMarker,
+			rule.NoncurrentDays,
+			rule.NewerNoncurrentVersions,
+			rule.AbortIncompleteUploadDays,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting lifecycle rule DB row: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error reading lifecycle rule DB row ID: %w", err)
+		}
+
+		for name, value := range rule.Tags {
+			_, err := tx.Exec("INSERT INTO lifecycle_rule_tags (rule_id, name, value) VALUES (?, ?, ?)", id, name, value)
+			if err != nil {
+				return fmt.Errorf("error inserting lifecycle rule tag DB row: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lifecycle rules: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves the lifecycle rules of a bucket, in the order they were configured.
+// Returns repository.ErrNotFound if the bucket has no lifecycle rules, or an error if the query fails.
+func (lr *lifecycleRepository) Get(bucketID int) ([]model.LifecycleRule, error) {
+	rows, err := lr.db.Query(`
+		SELECT id, rule_id, status, prefix, expiration_days, expiration_date, expired_object_delete_marker,
+			noncurrent_days, newer_noncurrent_versions, abort_incomplete_upload_days
+		FROM lifecycle_rules
+		WHERE bucket_id = ?
+		ORDER BY id`, bucketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lifecycle rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]model.LifecycleRule, 0)
+	positions := make(map[int]int)
+	for rows.Next() {
+		var id int
+		var rule model.LifecycleRule
+		var expirationDate sql.NullTime
+
+		err := rows.Scan(
+			&id,
+			&rule.ID,
+			&rule.Status,
+			&rule.Prefix,
+			&rule.ExpirationDays,
+			&expirationDate,
+			&rule.ExpiredObjectDeleteMarker,
+			&rule.NoncurrentDays,
+			&rule.NewerNoncurrentVersions,
+			&rule.AbortIncompleteUploadDays,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in lifecycle rules iteration: %w", err)
+		}
+		if expirationDate.Valid {
+			rule.ExpirationDate = expirationDate.Time.UTC()
+		}
+
+		positions[id] = len(rules)
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	if len(rules) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	if err := lr.queryRuleTags(bucketID, rules, positions); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// queryRuleTags fills the tag filters of the rules of a bucket, positions mapping the ID of each rule row
+// to its index in rules.
+func (lr *lifecycleRepository) queryRuleTags(bucketID int, rules []model.LifecycleRule, positions map[int]int) error {
+	rows, err := lr.db.Query(`
+		SELECT t.rule_id, t.name, t.value
+		FROM lifecycle_rule_tags t
+		JOIN lifecycle_rules r ON r.id = t.rule_id
+		WHERE r.bucket_id = ?`, bucketID)
+	if err != nil {
+		return fmt.Errorf("failed to query lifecycle rule tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name, value string
+		if err := rows.Scan(&id, &name, &value); err != nil {
+			return fmt.Errorf("error scanning lifecycle rule tag DB row: %w", err)
+		}
+
+		rule := &rules[positions[id]]
+		if rule.Tags == nil {
+			rule.Tags = make(map[string]string)
+		}
+		rule.Tags[name] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes the lifecycle rules of a bucket, if it has any.
+// Returns an error if the deletion fails.
+func (lr *lifecycleRepository) Remove(bucketID int) error {
+	tx, err := lr.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteLifecycleRules(tx, bucketID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit lifecycle rules removal: %w", err)
+	}
+
+	return nil
+}
+
+// ListBuckets retrieves the buckets having lifecycle rules, sorted by name.
+// Returns an error if the query fails.
+func (lr *lifecycleRepository) ListBuckets() ([]model.Bucket, error) {
+	rows, err := lr.db.Query(`
+		SELECT id, name, url, created_at, COALESCE(versioning, '')
+		FROM buckets
+		WHERE EXISTS (SELECT 1 FROM lifecycle_rules WHERE bucket_id = buckets.id)
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buckets with lifecycle rules: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]model.Bucket, 0)
+	for rows.Next() {
+		var bucket model.Bucket
+		if err := rows.Scan(&bucket.ID, &bucket.Name, &bucket.Url, &bucket.CreatedAt, &bucket.Versioning); err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in buckets iteration: %w", err)
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// deleteLifecycleRules deletes the lifecycle rules of a bucket and their tags within the given transaction.
+func deleteLifecycleRules(tx *sql.Tx, bucketID int) error {
+	_, err := tx.Exec("DELETE FROM lifecycle_rule_tags WHERE rule_id IN (SELECT id FROM lifecycle_rules WHERE bucket_id = ?)", bucketID)
+	if err != nil {
+		return fmt.Errorf("failed to remove lifecycle rule tags: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM lifecycle_rules WHERE bucket_id = ?", bucketID); err != nil {
+		return fmt.Errorf("failed to remove lifecycle rules: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package impl
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+)
+
+// Metadata tables, each holding one name/value row per header of the metadata of its owner record.
+const (
+	fileMetadataTable      = "file_metadata"
+	multipartMetadataTable = "multipart_metadata"
+)
+
+// metadataOwners maps each metadata table to the column referencing its owner record.
+var metadataOwners = map[string]string{
+	fileMetadataTable:      "file_id",
+	multipartMetadataTable: "multipart_id",
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// insertMetadata stores the metadata of the record ownerID as header rows of the given table,
+// within the given transaction.
+func insertMetadata(tx *sql.Tx, table string, ownerID int, metadata model.Metadata) error {
+	query := fmt.Sprintf("INSERT INTO %s (%s, name, value) VALUES (?, ?, ?)", table, metadataOwners[table])
+
+	for name, values := range metadata.Header() {
+		for _, value := range values {
+			if _, err := tx.Exec(query, ownerID, name, value); err != nil {
+				return fmt.Errorf("error inserting metadata DB row into %s: %w", table, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteMetadata deletes, within the given transaction, the metadata rows of the given table
+// whose owner is selected by ownerQuery, a query returning owner IDs.
+func deleteMetadata(tx *sql.Tx, table string, ownerQuery string, args ...any) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", table, metadataOwners[table], ownerQuery)
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("error deleting metadata DB rows from %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// queryMetadata retrieves the metadata of the record ownerID from the given table.
+// Returns an error if the query fails.
+func queryMetadata(q queryer, table string, ownerID int) (model.Metadata, error) {
+	rows, err := q.Query(fmt.Sprintf("SELECT name, value FROM %s WHERE %s = ?", table, metadataOwners[table]), ownerID)
+	if err != nil {
+		return model.Metadata{}, fmt.Errorf("failed to query metadata from %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	header := make(http.Header)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return model.Metadata{}, fmt.Errorf("error scanning metadata DB row: %w", err)
+		}
+		header.Add(name, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return model.Metadata{}, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return model.ParseMetadata(header), nil
+}
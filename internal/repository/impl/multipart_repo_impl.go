@@ -0,0 +1,323 @@
+// Package impl provides concrete implementations of repositories.
+package impl
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/repository"
+)
+
+// MultipartRepository handles multipart uploads and their parts in the database.
+type multipartRepository struct {
+	db *sql.DB
+}
+
+// NewMultipartRepository creates a new MultipartRepository with the given database connection.
+func NewMultipartRepository(db *sql.DB) repository.MultipartRepository {
+	return &multipartRepository{db: db}
+}
+
+// New inserts a new multipart upload, with its metadata, into the database and sets its generated ID.
+// Returns an error if the insertion fails.
+func (mr *multipartRepository) New(upload *model.MultipartUpload) error {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO multipart_uploads (upload_id, bucket_id, key, content_type, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		upload.UploadID,
+		upload.BucketID,
+		upload.Key,
+		upload.ContentType,
+		upload.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting multipart upload DB row: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error reading multipart upload DB row ID: %w", err)
+	}
+	upload.ID = int(id)
+
+	if err := insertMetadata(tx, multipartMetadataTable, upload.ID, upload.Metadata); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit multipart upload insertion: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUploadID retrieves a multipart upload by its client facing upload ID.
+// Returns the upload model, repository.ErrNotFound if it does not exist, or an error if scanning fails.
+func (mr *multipartRepository) GetByUploadID(uploadID string) (*model.MultipartUpload, error) {
+	row := mr.db.QueryRow(`
+		SELECT id, upload_id, bucket_id, key, content_type, created_at
+		FROM multipart_uploads WHERE upload_id = ?`, uploadID)
+	var upload model.MultipartUpload
+
+	err := row.Scan(&upload.ID, &upload.UploadID, &upload.BucketID, &upload.Key, &upload.ContentType, &upload.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("error scanning multipart upload DB row: %w", err)
+	}
+
+	if upload.Metadata, err = queryMetadata(mr.db, multipartMetadataTable, upload.ID); err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// List retrieves the in-progress multipart uploads of a bucket matching the given query,
+// sorted by key and then by initiation order.
+// Returns a page of at most query.MaxUploads uploads, or an error if the query fails.
+func (mr *multipartRepository) List(bucketID int, query model.UploadQuery) (*model.UploadListing, error) {
+	rows, err := mr.db.Query(`
+		SELECT id, upload_id, bucket_id, key, content_type, created_at
+		FROM multipart_uploads
+		WHERE bucket_id = ?1
+			AND key >= ?2 AND (?3 = '' OR key < ?3)
+			AND (key > ?4 OR (?5 <> '' AND key = ?4 AND id > (
+				SELECT id FROM multipart_uploads WHERE upload_id = ?5
+			)))
+		ORDER BY key, id
+		LIMIT ?6`,
+		bucketID,
+		query.Prefix,
+		prefixUpperBound(query.Prefix),
+		query.KeyMarker,
+		query.UploadIDMarker,
+		query.MaxUploads+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+	}
+	defer rows.Close()
+
+	listing := &model.UploadListing{Uploads: make([]model.MultipartUpload, 0)}
+	for rows.Next() {
+		if len(listing.Uploads) == query.MaxUploads {
+			listing.IsTruncated = true
+			break
+		}
+
+		var upload model.MultipartUpload
+		err := rows.Scan(&upload.ID, &upload.UploadID, &upload.BucketID, &upload.Key, &upload.ContentType, &upload.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in multipart uploads iteration: %w", err)
+		}
+		listing.Uploads = append(listing.Uploads, upload)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return listing, nil
+}
+
+// Remove deletes a multipart upload and all of its parts.
+// Returns the references of the blobs no longer used by any file or part, which the caller
+// must delete from the BlobStore, or an error if the deletion fails.
+func (mr *multipartRepository) Remove(multipartID int) ([]string, error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	released, err := removeMultipartUpload(tx, multipartID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit multipart upload removal: %w", err)
+	}
+
+	return released, nil
+}
+
+// PutPart inserts a part into its multipart upload, replacing any part with the same number.
+// Returns the references of the blobs no longer used by any file or part (the data of a replaced part),
+// which the caller must delete from the BlobStore, or an error if the insertion fails.
+func (mr *multipartRepository) PutPart(part *model.Part) ([]string, error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	refs, err := queryBlobRefs(tx, `
+		SELECT blob_ref FROM multipart_parts
+		WHERE multipart_id = ? AND part_number = ?`, part.MultipartID, part.PartNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO multipart_parts (multipart_id, part_number, blob_ref, etag, size, last_modified)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(multipart_id, part_number) DO UPDATE SET
+			blob_ref = excluded.blob_ref,
+			etag = excluded.etag,
+			size = excluded.size,
+			last_modified = excluded.last_modified`,
+		part.MultipartID,
+		part.PartNumber,
+		part.BlobRef,
+		part.ETag,
+		part.Size,
+		part.LastModified,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting part DB row into multipart_parts: %w", err)
+	}
+
+	released, err := releasedBlobs(tx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit part upload: %w", err)
+	}
+
+	return released, nil
+}
+
+// ListParts retrieves the parts of a multipart upload numbered after partNumberMarker, without their data.
+// Returns a page of at most maxParts parts sorted by part number, or an error if the query fails.
+func (mr *multipartRepository) ListParts(multipartID int, partNumberMarker int, maxParts int) (*model.PartListing, error) {
+	rows, err := mr.db.Query(`
+		SELECT id, multipart_id, part_number, etag, size, last_modified
+		FROM multipart_parts
+		WHERE multipart_id = ? AND part_number > ?
+		ORDER BY part_number
+		LIMIT ?`,
+		multipartID,
+		partNumberMarker,
+		maxParts+1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list multipart parts: %w", err)
+	}
+	defer rows.Close()
+
+	listing := &model.PartListing{Parts: make([]model.Part, 0)}
+	for rows.Next() {
+		if len(listing.Parts) == maxParts {
+			listing.IsTruncated = true
+			break
+		}
+
+		var part model.Part
+		if err := rows.Scan(&part.ID, &part.MultipartID, &part.PartNumber, &part.ETag, &part.Size, &part.LastModified); err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in multipart parts iteration: %w", err)
+		}
+		listing.Parts = append(listing.Parts, part)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return listing, nil
+}
+
+// GetParts retrieves every part of a multipart upload, including its blob reference, sorted by part number.
+// Returns the parts or an error if the query fails.
+func (mr *multipartRepository) GetParts(multipartID int) ([]model.Part, error) {
+	rows, err := mr.db.Query(`
+		SELECT id, multipart_id, part_number, COALESCE(blob_ref, ''), etag, size, last_modified
+		FROM multipart_parts
+		WHERE multipart_id = ?
+		ORDER BY part_number`, multipartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get multipart parts: %w", err)
+	}
+	defer rows.Close()
+
+	parts := make([]model.Part, 0)
+	for rows.Next() {
+		var part model.Part
+		err := rows.Scan(&part.ID, &part.MultipartID, &part.PartNumber, &part.BlobRef, &part.ETag, &part.Size, &part.LastModified)
+		if err != nil {
+			return nil, fmt.Errorf("error converting DB row to model in multipart parts iteration: %w", err)
+		}
+		parts = append(parts, part)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return parts, nil
+}
+
+// Complete atomically stores the object assembled from a multipart upload, with its metadata,
+// as the latest version of its key, as FileRepository.Put does, and removes the upload together
+// with its parts. Returns the references of the blobs no longer used by any file or part (the data
+// of the parts and of a replaced "null" version), which the caller must delete from the BlobStore,
+// or an error if any step fails, in which case nothing is changed.
+func (mr *multipartRepository) Complete(upload *model.MultipartUpload, file *model.File) ([]string, error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	replaced, err := putVersion(tx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := removeMultipartUpload(tx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	released = append(released, replaced...)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit multipart upload completion: %w", err)
+	}
+
+	return released, nil
+}
+
+// removeMultipartUpload deletes a multipart upload and its parts within the given transaction.
+// Returns the references of the part blobs no longer used by any file or part.
+func removeMultipartUpload(tx *sql.Tx, multipartID int) ([]string, error) {
+	refs, err := queryBlobRefs(tx, "SELECT blob_ref FROM multipart_parts WHERE multipart_id = ?", multipartID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM multipart_parts WHERE multipart_id = ?", multipartID); err != nil {
+		return nil, fmt.Errorf("error deleting part DB rows from multipart_parts: %w", err)
+	}
+
+	if err := deleteMetadata(tx, multipartMetadataTable, "?", multipartID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM multipart_uploads WHERE id = ?", multipartID); err != nil {
+		return nil, fmt.Errorf("error deleting multipart upload DB row: %w", err)
+	}
+
+	return releasedBlobs(tx, refs)
+}
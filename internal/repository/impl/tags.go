@@ -0,0 +1,105 @@
+package impl
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Tag tables, each holding one name/value row per tag of its owner record.
+const (
+	fileTagsTable   = "file_tags"
+	bucketTagsTable = "bucket_tags"
+)
+
+// tagOwners maps each tag table to the column referencing its owner record.
+var tagOwners = map[string]string{
+	fileTagsTable:   "file_id",
+	bucketTagsTable: "bucket_id",
+}
+
+// insertTags stores the tags of the record ownerID as rows of the given table, within the given transaction.
+func insertTags(tx *sql.Tx, table string, ownerID int, tags map[string]string) error {
+	query := fmt.Sprintf("INSERT INTO %s (%s, name, value) VALUES (?, ?, ?)", table, tagOwners[table])
+
+	for name, value := range tags {
+		if _, err := tx.Exec(query, ownerID, name, value); err != nil {
+			return fmt.Errorf("error inserting tag DB row into %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteTags deletes, within the given transaction, the tag rows of the given table
+// whose owner is selected by ownerQuery, a query returning owner IDs.
+func deleteTags(tx *sql.Tx, table string, ownerQuery string, args ...any) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", table, tagOwners[table], ownerQuery)
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("error deleting tag DB rows from %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// replaceTags replaces the tags of the record ownerID in the given table with tags, in a single
+// transaction, after checking that the record still exists in ownerTable.
+// Returns false if it does not, or an error if the replacement fails.
+func replaceTags(db *sql.DB, table string, ownerTable string, ownerID int, tags map[string]string) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = ?)", ownerTable), ownerID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if %s row exists: %w", ownerTable, err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	if err := deleteTags(tx, table, "?", ownerID); err != nil {
+		return false, err
+	}
+
+	if err := insertTags(tx, table, ownerID, tags); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit tags of %s: %w", ownerTable, err)
+	}
+
+	return true, nil
+}
+
+// queryTags retrieves the tags of the record ownerID from the given table, nil if it has none.
+// Returns an error if the query fails.
+func queryTags(q queryer, table string, ownerID int) (map[string]string, error) {
+	rows, err := q.Query(fmt.Sprintf("SELECT name, value FROM %s WHERE %s = ?", table, tagOwners[table]), ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags from %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var tags map[string]string
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("error scanning tag DB row: %w", err)
+		}
+
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[name] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error in DB rows scanning: %w", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/bonifacio-pedro/s3ego/internal/model"
+
+// LifecycleRepository interface for decoupling code
+type LifecycleRepository interface {
+	Put(bucketID int, rules []model.LifecycleRule) error
+	Get(bucketID int) ([]model.LifecycleRule, error)
+	Remove(bucketID int) error
+	ListBuckets() ([]model.Bucket, error)
+}
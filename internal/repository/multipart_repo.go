@@ -0,0 +1,15 @@
+package repository
+
+import "github.com/bonifacio-pedro/s3ego/internal/model"
+
+// MultipartRepository interface for decoupling code
+type MultipartRepository interface {
+	New(upload *model.MultipartUpload) error
+	GetByUploadID(uploadID string) (*model.MultipartUpload, error)
+	List(bucketID int, query model.UploadQuery) (*model.UploadListing, error)
+	Remove(multipartID int) ([]string, error)
+	PutPart(part *model.Part) ([]string, error)
+	ListParts(multipartID int, partNumberMarker int, maxParts int) (*model.PartListing, error)
+	GetParts(multipartID int) ([]model.Part, error)
+	Complete(upload *model.MultipartUpload, file *model.File) ([]string, error)
+}
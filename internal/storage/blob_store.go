@@ -0,0 +1,26 @@
+// Package storage defines where the bytes of objects and multipart upload parts are stored,
+// separately from the metadata kept in the SQLite database.
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrBlobNotFound is returned by blob stores when no blob exists for the given reference.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobInfo describes a stored blob.
+type BlobInfo struct {
+	Ref  string // Opaque reference of the blob, saved with the metadata of its object or part
+	Size int64  // Size of the blob in bytes
+}
+
+// BlobStore interface for decoupling code.
+// Blobs are immutable: Put always stores a new blob under a new reference.
+type BlobStore interface {
+	Put(r io.Reader) (BlobInfo, error)
+	Get(ref string) (io.ReadSeekCloser, error)
+	Stat(ref string) (BlobInfo, error)
+	Delete(ref string) error
+}
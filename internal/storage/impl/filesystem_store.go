@@ -0,0 +1,130 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
+)
+
+// FileSystemBlobStore keeps every blob in its own file under a root directory,
+// sharded by the first two characters of the reference ("root/ab/ab12...").
+type fileSystemBlobStore struct {
+	root string
+}
+
+// NewFileSystemBlobStore creates a BlobStore keeping blobs in files under root, creating the directory if missing.
+// Returns an error if the directory cannot be created.
+func NewFileSystemBlobStore(root string) (storage.BlobStore, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	return &fileSystemBlobStore{root: root}, nil
+}
+
+// Put copies r until EOF into a new blob file. The data is written to a temporary file first
+// and renamed into place, so a failed write never leaves a partial blob behind.
+// Returns the blob reference and size, or an error if reading or writing fails.
+func (fs *fileSystemBlobStore) Put(r io.Reader) (storage.BlobInfo, error) {
+	tmp, err := os.CreateTemp(filepath.Join(fs.root, "tmp"), "blob-*")
+	if err != nil {
+		return storage.BlobInfo{}, fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return storage.BlobInfo{}, fmt.Errorf("error writing blob data: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return storage.BlobInfo{}, fmt.Errorf("error writing blob data: %w", err)
+	}
+
+	ref := newBlobRef()
+	path := fs.path(ref)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return storage.BlobInfo{}, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return storage.BlobInfo{}, fmt.Errorf("failed to store blob file: %w", err)
+	}
+
+	return storage.BlobInfo{Ref: ref, Size: size}, nil
+}
+
+// Get opens the blob file with the given reference for reading.
+// Returns storage.ErrBlobNotFound if it does not exist.
+func (fs *fileSystemBlobStore) Get(ref string) (io.ReadSeekCloser, error) {
+	if !validBlobRef(ref) {
+		return nil, storage.ErrBlobNotFound
+	}
+
+	file, err := os.Open(fs.path(ref))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, storage.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Stat returns the size of the blob file with the given reference.
+// Returns storage.ErrBlobNotFound if it does not exist.
+func (fs *fileSystemBlobStore) Stat(ref string) (storage.BlobInfo, error) {
+	if !validBlobRef(ref) {
+		return storage.BlobInfo{}, storage.ErrBlobNotFound
+	}
+
+	info, err := os.Stat(fs.path(ref))
+	if errors.Is(err, os.ErrNotExist) {
+		return storage.BlobInfo{}, storage.ErrBlobNotFound
+	}
+	if err != nil {
+		return storage.BlobInfo{}, fmt.Errorf("failed to stat blob file: %w", err)
+	}
+
+	return storage.BlobInfo{Ref: ref, Size: info.Size()}, nil
+}
+
+// Delete removes the blob file with the given reference. Deleting a missing blob succeeds.
+func (fs *fileSystemBlobStore) Delete(ref string) error {
+	if !validBlobRef(ref) {
+		return nil
+	}
+
+	if err := os.Remove(fs.path(ref)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob file: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the location of the blob file with the given reference.
+func (fs *fileSystemBlobStore) path(ref string) string {
+	return filepath.Join(fs.root, ref[:2], ref)
+}
+
+// validBlobRef reports whether ref has the format generated by newBlobRef, which also
+// guarantees it cannot address a file outside of the blob directory.
+func validBlobRef(ref string) bool {
+	if len(ref) != 32 {
+		return false
+	}
+
+	for _, c := range ref {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
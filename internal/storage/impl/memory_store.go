@@ -0,0 +1,93 @@
+// Package impl provides concrete implementations of blob stores.
+package impl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/bonifacio-pedro/s3ego/internal/storage"
+	"github.com/google/uuid"
+)
+
+// MemoryBlobStore keeps blobs in memory, so they are lost when the process exits.
+type memoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewMemoryBlobStore creates a new, empty BlobStore kept in memory.
+func NewMemoryBlobStore() storage.BlobStore {
+	return &memoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+// Put reads r until EOF and stores its bytes as a new blob.
+// Returns the blob reference and size, or an error if reading fails.
+func (ms *memoryBlobStore) Put(r io.Reader) (storage.BlobInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return storage.BlobInfo{}, fmt.Errorf("error reading blob data: %w", err)
+	}
+
+	ref := newBlobRef()
+
+	ms.mu.Lock()
+	ms.blobs[ref] = data
+	ms.mu.Unlock()
+
+	return storage.BlobInfo{Ref: ref, Size: int64(len(data))}, nil
+}
+
+// Get opens the blob with the given reference for reading.
+// Returns storage.ErrBlobNotFound if it does not exist.
+func (ms *memoryBlobStore) Get(ref string) (io.ReadSeekCloser, error) {
+	ms.mu.RLock()
+	data, ok := ms.blobs[ref]
+	ms.mu.RUnlock()
+
+	if !ok {
+		return nil, storage.ErrBlobNotFound
+	}
+
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+// Stat returns the size of the blob with the given reference.
+// Returns storage.ErrBlobNotFound if it does not exist.
+func (ms *memoryBlobStore) Stat(ref string) (storage.BlobInfo, error) {
+	ms.mu.RLock()
+	data, ok := ms.blobs[ref]
+	ms.mu.RUnlock()
+
+	if !ok {
+		return storage.BlobInfo{}, storage.ErrBlobNotFound
+	}
+
+	return storage.BlobInfo{Ref: ref, Size: int64(len(data))}, nil
+}
+
+// Delete removes the blob with the given reference. Deleting a missing blob succeeds.
+func (ms *memoryBlobStore) Delete(ref string) error {
+	ms.mu.Lock()
+	delete(ms.blobs, ref)
+	ms.mu.Unlock()
+
+	return nil
+}
+
+// nopCloser adds a no-op Close method to an in-memory io.ReadSeeker.
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+// Close does nothing.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// newBlobRef generates a new random blob reference of 32 hexadecimal characters.
+func newBlobRef() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+)
+
+// maxChunkSize bounds the size of a single aws-chunked chunk held in memory.
+const maxChunkSize = 16 * 1024 * 1024
+
+// emptySHA256 is the hex SHA256 of an empty payload, part of every chunk string to sign.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// chunkSigner verifies the chained signatures of a STREAMING-AWS4-HMAC-SHA256-PAYLOAD body.
+type chunkSigner struct {
+	key      []byte
+	amzDate  string
+	scope    string
+	previous string // Signature of the previous chunk, seeded with the request signature
+}
+
+// verify checks the signature of the next chunk and advances the chain.
+func (s *chunkSigner) verify(chunkSignature string, data []byte) bool {
+	dataHash := sha256.Sum256(data)
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		s.amzDate,
+		s.scope,
+		s.previous,
+		emptySHA256,
+		hex.EncodeToString(dataHash[:]),
+	}, "\n")
+
+	expected := hex.EncodeToString(hmacSHA256(s.key, toSign))
+	if !hmac.Equal([]byte(expected), []byte(chunkSignature)) {
+		return false
+	}
+
+	s.previous = chunkSignature
+	return true
+}
+
+// decodeChunkedPayload replaces an aws-chunked request body ("STREAMING-*" x-amz-content-sha256
+// or "aws-chunked" Content-Encoding) with a reader of the decoded object bytes, verifying every
+// chunk signature when signer is not nil. Other bodies are left untouched.
+func decodeChunkedPayload(r *http.Request, signer *chunkSigner) error {
+	contentEncoding := r.Header.Get("Content-Encoding")
+	if !strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), streamingPrefix) && !strings.Contains(contentEncoding, "aws-chunked") {
+		return nil
+	}
+
+	if decoded := r.Header.Get("x-amz-decoded-content-length"); decoded != "" {
+		length, err := strconv.ParseInt(decoded, 10, 64)
+		if err != nil {
+			return domain.ErrInvalidArgument.WithMessage("Invalid x-amz-decoded-content-length")
+		}
+		r.ContentLength = length
+		r.Header.Set("Content-Length", decoded)
+	}
+
+	encodings := make([]string, 0)
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		if encoding = strings.TrimSpace(encoding); encoding != "" && encoding != "aws-chunked" {
+			encodings = append(encodings, encoding)
+		}
+	}
+	if len(encodings) == 0 {
+		r.Header.Del("Content-Encoding")
+	} else {
+		r.Header.Set("Content-Encoding", strings.Join(encodings, ","))
+	}
+
+	r.Body = &chunkedReader{body: r.Body, reader: bufio.NewReader(r.Body), signer: signer}
+	return nil
+}
+
+// chunkedReader decodes an aws-chunked body ("size[;chunk-signature=sig]\r\ndata\r\n...")
+// into the raw object bytes, skipping any trailing headers after the final chunk.
+type chunkedReader struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	signer *chunkSigner
+	chunk  []byte
+	err    error
+}
+
+// Read returns the decoded bytes of the current chunk, loading the next one when it is exhausted.
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	for len(cr.chunk) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		cr.chunk, cr.err = cr.nextChunk()
+	}
+
+	n := copy(p, cr.chunk)
+	cr.chunk = cr.chunk[n:]
+	return n, nil
+}
+
+// Close closes the underlying request body.
+func (cr *chunkedReader) Close() error {
+	return cr.body.Close()
+}
+
+// nextChunk reads and verifies the next chunk, returning io.EOF after the final empty chunk.
+func (cr *chunkedReader) nextChunk() ([]byte, error) {
+	header, err := cr.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	sizeField, extension, _ := strings.Cut(header, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return nil, domain.ErrIncompleteBody.WithMessage("Invalid aws-chunked chunk header")
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(cr.reader, data); err != nil {
+		return nil, domain.ErrIncompleteBody
+	}
+
+	if cr.signer != nil {
+		chunkSignature, ok := strings.CutPrefix(extension, "chunk-signature=")
+		if !ok || !cr.signer.verify(chunkSignature, data) {
+			return nil, domain.ErrSignatureDoesNotMatch
+		}
+	}
+
+	if size == 0 {
+		// Skip the trailing headers (e.g. x-amz-checksum-crc32) up to the final empty line.
+		for {
+			line, err := cr.readLine()
+			if err != nil || line == "" {
+				return nil, io.EOF
+			}
+		}
+	}
+
+	if line, err := cr.readLine(); err != nil || line != "" {
+		return nil, domain.ErrIncompleteBody.WithMessage("Invalid aws-chunked chunk terminator")
+	}
+
+	return data, nil
+}
+
+// readLine reads a CRLF terminated line without its terminator.
+func (cr *chunkedReader) readLine() (string, error) {
+	line, err := cr.reader.ReadString('\n')
+	if err != nil {
+		return "", domain.ErrIncompleteBody
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// hashVerifyingReader computes the SHA256 of a body as it is read and fails the final read
+// if it does not match the x-amz-content-sha256 the request was signed with.
+type hashVerifyingReader struct {
+	body     io.ReadCloser
+	hash     hash.Hash
+	expected string
+}
+
+// newHashVerifyingReader wraps body so its SHA256 is checked against the expected hex digest.
+func newHashVerifyingReader(body io.ReadCloser, expected string) io.ReadCloser {
+	return &hashVerifyingReader{body: body, hash: sha256.New(), expected: strings.ToLower(expected)}
+}
+
+// Read reads from the body, returning domain.ErrContentSHA256Mismatch instead of io.EOF on a mismatch.
+func (hr *hashVerifyingReader) Read(p []byte) (int, error) {
+	n, err := hr.body.Read(p)
+	hr.hash.Write(p[:n])
+
+	if err == io.EOF && hex.EncodeToString(hr.hash.Sum(nil)) != hr.expected {
+		return n, domain.ErrContentSHA256Mismatch
+	}
+
+	return n, err
+}
+
+// Close closes the underlying request body.
+func (hr *hashVerifyingReader) Close() error {
+	return hr.body.Close()
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+)
+
+// Chunk signatures of the streaming upload example of the S3 SigV4 documentation: 64 KiB then
+// 1 KiB of 'a', and the final empty chunk, chained from the seed signature of the request.
+const (
+	docsSeedSignature = "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9"
+	docsChunk1        = "ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648"
+	docsChunk2        = "0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497"
+	docsFinalChunk    = "b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9"
+)
+
+// docsChunkedBody returns the aws-chunked body of the streaming upload example, with the given
+// chunk signatures.
+func docsChunkedBody(signatures [3]string) string {
+	return fmt.Sprintf("10000;chunk-signature=%s\r\n%s\r\n400;chunk-signature=%s\r\n%s\r\n0;chunk-signature=%s\r\n\r\n",
+		signatures[0], strings.Repeat("a", 65536), signatures[1], strings.Repeat("a", 1024), signatures[2])
+}
+
+// docsStreamingPut returns the PutObject request of the streaming upload example.
+func docsStreamingPut(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/examplebucket/chunkObject.txt", strings.NewReader(body))
+	r.Host = "s3.amazonaws.com"
+	r.Header.Set("x-amz-date", "20130524T000000Z")
+	r.Header.Set("x-amz-storage-class", "REDUCED_REDUNDANCY")
+	r.Header.Set("x-amz-content-sha256", streamingSignedPayload)
+	r.Header.Set("Content-Encoding", "aws-chunked")
+	r.Header.Set("x-amz-decoded-content-length", "66560")
+	r.Header.Set("Content-Length", "66824")
+	r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+docsAccessKey+"/20130524/us-east-1/s3/aws4_request,"+
+		"SignedHeaders=content-encoding;content-length;host;x-amz-content-sha256;x-amz-date;x-amz-decoded-content-length;x-amz-storage-class,"+
+		"Signature="+docsSeedSignature)
+	return r
+}
+
+func TestAuthenticateStreamingPayload(t *testing.T) {
+	credentials := map[string]string{docsAccessKey: docsSecretKey}
+	want := strings.Repeat("a", 66560)
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{name: "documentation example", body: docsChunkedBody([3]string{docsChunk1, docsChunk2, docsFinalChunk})},
+		{name: "tampered chunk", body: strings.Replace(docsChunkedBody([3]string{docsChunk1, docsChunk2, docsFinalChunk}), "a\r\n400", "b\r\n400", 1), wantErr: domain.ErrSignatureDoesNotMatch},
+		{name: "chunks reordered", body: docsChunkedBody([3]string{docsChunk2, docsChunk1, docsFinalChunk}), wantErr: domain.ErrSignatureDoesNotMatch},
+		{name: "final chunk signature missing", body: strings.Replace(docsChunkedBody([3]string{docsChunk1, docsChunk2, docsFinalChunk}), "0;chunk-signature="+docsFinalChunk, "0", 1), wantErr: domain.ErrSignatureDoesNotMatch},
+		{name: "truncated body", body: docsChunkedBody([3]string{docsChunk1, docsChunk2, docsFinalChunk})[:66000], wantErr: domain.ErrIncompleteBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := docsStreamingPut(tt.body)
+			if err := authenticate(r, credentials, docsNow); err != nil {
+				t.Fatalf("authenticate() unexpected error: %v", err)
+			}
+
+			data, err := io.ReadAll(r.Body)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("reading the body: error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reading the body: unexpected error: %v", err)
+			}
+
+			if string(data) != want {
+				t.Errorf("decoded %d bytes, want %d bytes of 'a'", len(data), len(want))
+			}
+			if r.ContentLength != 66560 || r.Header.Get("Content-Encoding") != "" {
+				t.Errorf("ContentLength = %d, Content-Encoding = %q, want 66560 and none", r.ContentLength, r.Header.Get("Content-Encoding"))
+			}
+		})
+	}
+}
+
+func TestChunkedReaderUnsigned(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr error
+	}{
+		{name: "chunks", body: "5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n", want: "hello world"},
+		{name: "trailing checksum", body: "5\r\nhello\r\n0\r\nx-amz-checksum-crc32:NhCmhg==\r\n\r\n", want: "hello"},
+		{name: "invalid size", body: "zz\r\nhello\r\n0\r\n\r\n", wantErr: domain.ErrIncompleteBody},
+		{name: "missing terminator", body: "5\r\nhelloX\r\n0\r\n\r\n", wantErr: domain.ErrIncompleteBody},
+		{name: "short chunk", body: "a\r\nhello", wantErr: domain.ErrIncompleteBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/bkt/a.txt", strings.NewReader(tt.body))
+			r.Header.Set("x-amz-content-sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+			r.Header.Set("Content-Encoding", "aws-chunked,gzip")
+			if err := decodeChunkedPayload(r, nil); err != nil {
+				t.Fatalf("decodeChunkedPayload() unexpected error: %v", err)
+			}
+			if encoding := r.Header.Get("Content-Encoding"); encoding != "gzip" {
+				t.Errorf("Content-Encoding = %q, want %q", encoding, "gzip")
+			}
+
+			data, err := io.ReadAll(r.Body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reading the body: error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(data, []byte(tt.want)) {
+				t.Errorf("body = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
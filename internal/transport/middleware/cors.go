@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// corsVary lists the request headers CORS responses depend on, so caches keep them apart.
+const corsVary = "Origin, Access-Control-Request-Headers, Access-Control-Request-Method"
+
+// CORSMiddleware is a Gin middleware applying the CORS rules of buckets to the requests
+// addressed to them, as S3 does.
+//
+// Preflight requests (OPTIONS with Origin and Access-Control-Request-Method headers) are answered
+// directly: with 200 OK and the Access-Control-Allow-* headers of the first rule of the bucket
+// allowing the origin, method and Access-Control-Request-Headers, or with 403 AccessForbidden if
+// none does. Other requests carrying an Origin header get the Access-Control-* headers of the first
+// rule allowing their origin and method, and are processed whether or not a rule allows them.
+//
+// It must run before SigV4Middleware, since browsers never sign preflight requests.
+func CORSMiddleware(service domain.CORSService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			answerPreflight(c, service)
+			return
+		}
+
+		if origin := c.GetHeader("Origin"); origin != "" {
+			if rule, err := service.Match(c.Param("bucket"), origin, c.Request.Method, nil); err == nil {
+				writeCORSHeaders(c, rule, origin)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// answerPreflight answers a CORS preflight request with the headers of the rule allowing it.
+// Returns domain.ErrBadRequest if the Origin or Access-Control-Request-Method header is missing,
+// or the errors of CORSService.Match.
+func answerPreflight(c *gin.Context, service domain.CORSService) {
+	origin := c.GetHeader("Origin")
+	method := c.GetHeader("Access-Control-Request-Method")
+	switch {
+	case origin == "":
+		response.Error(c, domain.ErrBadRequest.WithMessage("Insufficient information. Origin request header needed."))
+		return
+	case method == "":
+		response.Error(c, domain.ErrBadRequest.WithMessage("Invalid Access-Control-Request-Method: null"))
+		return
+	}
+
+	headers := parseRequestHeaders(c.GetHeader("Access-Control-Request-Headers"))
+	rule, err := service.Match(c.Param("bucket"), origin, method, headers)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	writeCORSHeaders(c, rule, origin)
+	if len(headers) > 0 {
+		c.Header("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+
+	c.AbortWithStatus(http.StatusOK)
+}
+
+// writeCORSHeaders sets the Access-Control-* headers granted by rule to a request from origin.
+// A rule allowing every origin ("*") answers with a wildcard origin and without credentials.
+func writeCORSHeaders(c *gin.Context, rule *model.CORSRule, origin string) {
+	if slices.Contains(rule.AllowedOrigins, "*") {
+		c.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+
+	c.Header("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+	if len(rule.ExposeHeaders) > 0 {
+		c.Header("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+	}
+	if rule.MaxAgeSeconds > 0 {
+		c.Header("Access-Control-Max-Age", strconv.Itoa(rule.MaxAgeSeconds))
+	}
+	c.Header("Vary", corsVary)
+}
+
+// parseRequestHeaders splits an Access-Control-Request-Headers header into lowercase header names.
+func parseRequestHeaders(header string) []string {
+	headers := make([]string, 0)
+	for _, name := range strings.Split(header, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			headers = append(headers, name)
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,323 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPostFieldsSize bounds the total size of the form fields preceding the file of a POST upload, as S3 does.
+const maxPostFieldsSize = 20 * 1024
+
+// postFormKey is the Gin context key under which SigV4Middleware stores the fields of a POST upload.
+const postFormKey = "s3ego.postForm"
+
+// postPolicyExemptFields are the form fields that need no condition in the policy of a POST upload.
+var postPolicyExemptFields = map[string]bool{
+	"policy":          true,
+	"x-amz-signature": true,
+	"file":            true,
+}
+
+// postPolicy is a decoded POST policy document: its expiration and the conditions the form must meet.
+type postPolicy struct {
+	expiration  time.Time
+	conditions  []policyCondition
+	lengthRange bool  // Whether the policy has a content-length-range condition
+	minLength   int64 // Minimum file size allowed by content-length-range
+	maxLength   int64 // Maximum file size allowed by content-length-range
+}
+
+// policyCondition is an "eq" or "starts-with" condition of a POST policy on a form field.
+type policyCondition struct {
+	operator string // "eq" or "starts-with"
+	field    string // Lowercase form field name, without the leading "$"
+	value    string
+}
+
+// lengthRangeReader reads the file of a POST upload, failing with domain.ErrEntityTooLarge as soon as
+// it exceeds maxLength bytes, and with domain.ErrEntityTooSmall at its end if it is shorter than minLength.
+type lengthRangeReader struct {
+	reader    io.Reader
+	minLength int64
+	maxLength int64
+	read      int64
+}
+
+// Read reads from the file, checking its size against the content-length-range condition.
+func (lr *lengthRangeReader) Read(p []byte) (int, error) {
+	n, err := lr.reader.Read(p)
+	lr.read += int64(n)
+
+	if lr.read > lr.maxLength {
+		return n, domain.ErrEntityTooLarge.WithMessage("Your proposed upload exceeds the maximum allowed size of %d bytes", lr.maxLength)
+	}
+
+	if errors.Is(err, io.EOF) && lr.read < lr.minLength {
+		return n, domain.ErrEntityTooSmall.WithMessage("Your proposed upload is smaller than the minimum allowed size of %d bytes", lr.minLength)
+	}
+
+	return n, err
+}
+
+// PostForm returns the fields of the browser-based POST upload decoded by SigV4Middleware, keyed by
+// lowercase name, with ${filename} already replaced in the key field, or nil if the request is not one.
+// The request body then holds the content of the uploaded file.
+func PostForm(c *gin.Context) map[string]string {
+	value, _ := c.Get(postFormKey)
+	fields, _ := value.(map[string]string)
+	return fields
+}
+
+// isPostUpload reports whether the request is a browser-based POST Object upload:
+// a multipart/form-data POST addressed to a bucket, without a query string. Requests naming a
+// subresource, such as "POST /{bucket}?delete", are other operations, which the policy of a
+// form does not authorize, so they must carry their own signature.
+func isPostUpload(c *gin.Context) bool {
+	if c.Request.Method != http.MethodPost || strings.TrimPrefix(c.Param("key"), "/") != "" || c.Request.URL.RawQuery != "" {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	return mediaType == "multipart/form-data"
+}
+
+// decodePostForm reads the form fields of a browser-based POST upload up to its file, verifies the
+// signature of its policy when credentials is not empty, and checks the policy conditions at now.
+// The request body is then replaced with the content of the file, whose size is checked against
+// the content-length-range condition as it is read, and the fields are stored for PostForm.
+func decodePostForm(c *gin.Context, credentials map[string]string, now time.Time) error {
+	fields, file, err := readPostForm(c.Request)
+	if err != nil {
+		return err
+	}
+
+	if fields["key"] == "" {
+		return domain.ErrInvalidArgument.WithMessage("Bucket POST must contain a field named 'key'.  If it is specified, please check the order of the fields.")
+	}
+	fields["key"] = strings.ReplaceAll(fields["key"], "${filename}", file.FileName())
+
+	if len(credentials) > 0 {
+		if err := verifyPostSignature(fields, credentials); err != nil {
+			return err
+		}
+	}
+
+	var body io.Reader = file
+	if fields["policy"] != "" {
+		policy, err := parsePostPolicy(fields["policy"])
+		if err != nil {
+			return err
+		}
+
+		if err := policy.check(c.Param("bucket"), fields, now); err != nil {
+			return err
+		}
+
+		if policy.lengthRange {
+			body = &lengthRangeReader{reader: file, minLength: policy.minLength, maxLength: policy.maxLength}
+		}
+	}
+
+	c.Request.Body = io.NopCloser(body)
+	c.Set(postFormKey, fields)
+	return nil
+}
+
+// readPostForm reads the form fields of a POST upload until its "file" field, which S3 requires to be the last one.
+// Returns the fields keyed by lowercase name and the file part, positioned at the start of its content.
+func readPostForm(r *http.Request) (map[string]string, *multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, domain.ErrMalformedPOSTRequest
+	}
+
+	fields := make(map[string]string)
+	remaining := int64(maxPostFieldsSize)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, nil, domain.ErrInvalidArgument.WithMessage("POST requires exactly one file upload per request.")
+		}
+		if err != nil {
+			return nil, nil, domain.ErrMalformedPOSTRequest
+		}
+
+		name := strings.ToLower(part.FormName())
+		if name == "file" {
+			return fields, part, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, remaining+1))
+		if err != nil {
+			return nil, nil, domain.ErrMalformedPOSTRequest
+		}
+
+		remaining -= int64(len(value))
+		if remaining < 0 {
+			return nil, nil, domain.ErrMaxPostPreDataLengthExceeded
+		}
+
+		if name != "" {
+			fields[name] = string(value)
+		}
+	}
+}
+
+// verifyPostSignature checks the x-amz-signature field of a POST upload, the SigV4 signature
+// of its base64 policy, with the secret of the access key in x-amz-credential.
+func verifyPostSignature(fields map[string]string, credentials map[string]string) error {
+	if fields["policy"] == "" || fields["x-amz-signature"] == "" {
+		return domain.ErrAccessDenied.WithMessage("Bucket POST must contain a signed policy: the 'policy' and 'x-amz-signature' fields are required")
+	}
+
+	if algorithm := fields["x-amz-algorithm"]; algorithm != signingAlgorithm {
+		return domain.ErrInvalidArgument.WithMessage("POST form field x-amz-algorithm only supports \"%s\"", signingAlgorithm)
+	}
+
+	sig, err := parseCredential(fields["x-amz-credential"])
+	if err != nil {
+		return domain.ErrInvalidArgument.WithMessage("POST form field x-amz-credential is mal-formed; expecting \"<YOUR-AKID>/YYYYMMDD/REGION/SERVICE/aws4_request\".")
+	}
+
+	secretKey, ok := credentials[sig.accessKey]
+	if !ok {
+		return domain.ErrInvalidAccessKeyID
+	}
+
+	expected := hex.EncodeToString(hmacSHA256(signingKey(secretKey, sig), fields["policy"]))
+	if !hmac.Equal([]byte(expected), []byte(fields["x-amz-signature"])) {
+		return domain.ErrSignatureDoesNotMatch
+	}
+
+	return nil
+}
+
+// parsePostPolicy decodes a base64 POST policy document: its RFC 3339 expiration and its conditions,
+// which are {"field": "value"} exact matches, ["eq" | "starts-with", "$field", "value"] arrays,
+// or a ["content-length-range", min, max] array.
+// Returns domain.ErrInvalidPolicyDocument if the document is malformed.
+func parsePostPolicy(encoded string) (*postPolicy, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid Base64 Encoding.")
+	}
+
+	var document struct {
+		Expiration string            `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	if err := json.Unmarshal(decoded, &document); err != nil {
+		return nil, domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid JSON.")
+	}
+
+	policy := &postPolicy{}
+	policy.expiration, err = time.Parse(time.RFC3339, document.Expiration)
+	if err != nil {
+		return nil, domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid 'expiration' value: '%s'", document.Expiration)
+	}
+
+	for _, raw := range document.Conditions {
+		if err := policy.addCondition(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// addCondition parses a single condition of a POST policy document and adds it to the policy.
+// Returns domain.ErrInvalidPolicyDocument if the condition is malformed.
+func (p *postPolicy) addCondition(raw json.RawMessage) error {
+	var exact map[string]string
+	if err := json.Unmarshal(raw, &exact); err == nil {
+		for field, value := range exact {
+			p.conditions = append(p.conditions, policyCondition{operator: "eq", field: strings.ToLower(field), value: value})
+		}
+		return nil
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(raw, &elements); err != nil || len(elements) != 3 {
+		return domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid Condition: %s", raw)
+	}
+
+	var operator string
+	if err := json.Unmarshal(elements[0], &operator); err != nil {
+		return domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid Condition: %s", raw)
+	}
+
+	switch operator = strings.ToLower(operator); operator {
+	case "eq", "starts-with":
+		var field, value string
+		if json.Unmarshal(elements[1], &field) != nil || json.Unmarshal(elements[2], &value) != nil || !strings.HasPrefix(field, "$") {
+			return domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid Condition: %s", raw)
+		}
+		p.conditions = append(p.conditions, policyCondition{operator: operator, field: strings.ToLower(field[1:]), value: value})
+	case "content-length-range":
+		minLength, minErr := strconv.ParseInt(strings.Trim(string(elements[1]), `"`), 10, 64)
+		maxLength, maxErr := strconv.ParseInt(strings.Trim(string(elements[2]), `"`), 10, 64)
+		if minErr != nil || maxErr != nil || minLength < 0 || maxLength < minLength {
+			return domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid content-length-range: %s", raw)
+		}
+		p.lengthRange, p.minLength, p.maxLength = true, minLength, maxLength
+	default:
+		return domain.ErrInvalidPolicyDocument.WithMessage("Invalid Policy: Invalid Condition operator: %s", operator)
+	}
+
+	return nil
+}
+
+// check verifies that the policy has not expired at now and that the form fields of an upload to
+// bucket meet every condition. As in S3, every field but policy, x-amz-signature, file and the
+// x-ignore-* fields must be covered by a condition.
+// Returns domain.ErrAccessDenied describing the first violation.
+func (p *postPolicy) check(bucket string, fields map[string]string, now time.Time) error {
+	if now.After(p.expiration) {
+		return domain.ErrAccessDenied.WithMessage("Invalid according to Policy: Policy expired.")
+	}
+
+	covered := make(map[string]bool, len(p.conditions))
+	for _, condition := range p.conditions {
+		value := fields[condition.field]
+		if condition.field == "bucket" {
+			value = bucket
+		}
+
+		matches := value == condition.value
+		if condition.operator == "starts-with" {
+			matches = strings.HasPrefix(value, condition.value)
+		}
+		if !matches {
+			return domain.ErrAccessDenied.WithMessage("Invalid according to Policy: Policy Condition failed: [\"%s\", \"$%s\", \"%s\"]", condition.operator, condition.field, condition.value)
+		}
+		covered[condition.field] = true
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !covered[name] && !postPolicyExemptFields[name] && !strings.HasPrefix(name, "x-ignore-") {
+			return domain.ErrAccessDenied.WithMessage("Invalid according to Policy: Extra input fields: %s", name)
+		}
+	}
+
+	return nil
+}
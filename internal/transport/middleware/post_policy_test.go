@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+var policyNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func encodePolicy(document string) string {
+	return base64.StdEncoding.EncodeToString([]byte(document))
+}
+
+func TestParsePostPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoded   string
+		wantErr   error
+		wantConds int
+		wantRange [2]int64
+	}{
+		{
+			name:      "exact, eq and starts-with conditions",
+			encoded:   encodePolicy(`{"expiration":"2026-03-02T00:00:00Z","conditions":[{"bucket":"bkt"},["eq","$Content-Type","text/plain"],["starts-with","$key","uploads/"]]}`),
+			wantConds: 3,
+		},
+		{
+			name:      "content-length-range with string bounds",
+			encoded:   encodePolicy(`{"expiration":"2026-03-02T00:00:00Z","conditions":[["content-length-range","1","1024"]]}`),
+			wantRange: [2]int64{1, 1024},
+		},
+		{name: "invalid base64", encoded: "not base64!", wantErr: domain.ErrInvalidPolicyDocument},
+		{name: "invalid JSON", encoded: encodePolicy(`{"expiration":`), wantErr: domain.ErrInvalidPolicyDocument},
+		{name: "invalid expiration", encoded: encodePolicy(`{"expiration":"tomorrow","conditions":[]}`), wantErr: domain.ErrInvalidPolicyDocument},
+		{name: "unknown operator", encoded: encodePolicy(`{"expiration":"2026-03-02T00:00:00Z","conditions":[["ends-with","$key","x"]]}`), wantErr: domain.ErrInvalidPolicyDocument},
+		{name: "field without $", encoded: encodePolicy(`{"expiration":"2026-03-02T00:00:00Z","conditions":[["eq","key","x"]]}`), wantErr: domain.ErrInvalidPolicyDocument},
+		{name: "short condition", encoded: encodePolicy(`{"expiration":"2026-03-02T00:00:00Z","conditions":[["eq","$key"]]}`), wantErr: domain.ErrInvalidPolicyDocument},
+		{name: "inverted length range", encoded: encodePolicy(`{"expiration":"2026-03-02T00:00:00Z","conditions":[["content-length-range",10,1]]}`), wantErr: domain.ErrInvalidPolicyDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parsePostPolicy(tt.encoded)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parsePostPolicy() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePostPolicy() unexpected error: %v", err)
+			}
+
+			if len(policy.conditions) != tt.wantConds {
+				t.Errorf("conditions = %d, want %d", len(policy.conditions), tt.wantConds)
+			}
+			if got := [2]int64{policy.minLength, policy.maxLength}; got != tt.wantRange {
+				t.Errorf("length range = %v, want %v", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestPostPolicyCheck(t *testing.T) {
+	policy, err := parsePostPolicy(encodePolicy(`{
+		"expiration": "2026-03-01T13:00:00Z",
+		"conditions": [
+			{"bucket": "bkt"},
+			["starts-with", "$key", "uploads/"],
+			["eq", "$Content-Type", "text/plain"],
+			["starts-with", "$x-amz-meta-tag", ""],
+			{"x-amz-algorithm": "AWS4-HMAC-SHA256"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("parsePostPolicy() unexpected error: %v", err)
+	}
+
+	valid := func() map[string]string {
+		return map[string]string{
+			"key":             "uploads/a.txt",
+			"content-type":    "text/plain",
+			"x-amz-meta-tag":  "anything",
+			"x-amz-algorithm": "AWS4-HMAC-SHA256",
+			"policy":          "ignored",
+			"x-amz-signature": "ignored",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		bucket  string
+		now     time.Time
+		change  func(fields map[string]string)
+		wantErr bool
+	}{
+		{name: "all conditions hold", bucket: "bkt", now: policyNow},
+		{name: "x-ignore fields need no condition", bucket: "bkt", now: policyNow, change: func(f map[string]string) { f["x-ignore-note"] = "x" }},
+		{name: "expired policy", bucket: "bkt", now: policyNow.Add(2 * time.Hour), wantErr: true},
+		{name: "other bucket", bucket: "other", now: policyNow, wantErr: true},
+		{name: "key outside the prefix", bucket: "bkt", now: policyNow, change: func(f map[string]string) { f["key"] = "secret/important" }, wantErr: true},
+		{name: "eq mismatch", bucket: "bkt", now: policyNow, change: func(f map[string]string) { f["content-type"] = "text/html" }, wantErr: true},
+		{name: "missing field of an eq condition", bucket: "bkt", now: policyNow, change: func(f map[string]string) { delete(f, "content-type") }, wantErr: true},
+		{name: "field without a condition", bucket: "bkt", now: policyNow, change: func(f map[string]string) { f["acl"] = "public-read" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := valid()
+			if tt.change != nil {
+				tt.change(fields)
+			}
+
+			err := policy.check(tt.bucket, fields, tt.now)
+			if tt.wantErr && !errors.Is(err, domain.ErrAccessDenied) {
+				t.Fatalf("check() error = %v, want AccessDenied", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("check() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLengthRangeReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "within range", data: "hello"},
+		{name: "too small", data: "hi", wantErr: domain.ErrEntityTooSmall},
+		{name: "too large", data: "hello world", wantErr: domain.ErrEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &lengthRangeReader{reader: bytes.NewReader([]byte(tt.data)), minLength: 3, maxLength: 8}
+			_, err := new(bytes.Buffer).ReadFrom(reader)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("read error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// signedPostForm builds a POST upload form with a policy limited to keys under "uploads/", signed
+// with secret, holding the given key and file content.
+func signedPostForm(t *testing.T, secret string, key string, content string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	policy := encodePolicy(`{"expiration":"2026-03-01T13:00:00Z","conditions":[{"bucket":"bkt"},["starts-with","$key","uploads/"],{"x-amz-algorithm":"AWS4-HMAC-SHA256"},{"x-amz-credential":"AKID/20260301/us-east-1/s3/aws4_request"}]}`)
+	sig := &signature{date: "20260301", region: "us-east-1", service: "s3"}
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	fields := [][2]string{
+		{"key", key},
+		{"x-amz-algorithm", signingAlgorithm},
+		{"x-amz-credential", "AKID/20260301/us-east-1/s3/aws4_request"},
+		{"policy", policy},
+		{"x-amz-signature", hex.EncodeToString(hmacSHA256(signingKey(secret, sig), policy))},
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := writer.CreateFormFile("file", "delete.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte(content))
+	writer.Close()
+
+	return body, writer.FormDataContentType()
+}
+
+func TestSigV4MiddlewarePostRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	credentials := map[string]string{"AKID": "secret"}
+	deleteBody := `<Delete><Object><Key>secret/important</Key></Object></Delete>`
+
+	tests := []struct {
+		name       string
+		target     string
+		key        string
+		secret     string
+		wantStatus int
+		wantForm   bool
+	}{
+		{name: "upload to the bucket", target: "/bkt", key: "uploads/a.txt", secret: "secret", wantStatus: http.StatusOK, wantForm: true},
+		{name: "DeleteObjects is not authorized by a policy", target: "/bkt?delete", key: "uploads/a.txt", secret: "secret", wantStatus: http.StatusForbidden},
+		{name: "other subresources are not authorized by a policy", target: "/bkt?uploads", key: "uploads/a.txt", secret: "secret", wantStatus: http.StatusForbidden},
+		{name: "object URL is not a POST upload", target: "/bkt/uploads/a.txt", key: "uploads/a.txt", secret: "secret", wantStatus: http.StatusForbidden},
+		{name: "key outside the policy", target: "/bkt", key: "secret/important", secret: "secret", wantStatus: http.StatusForbidden},
+		{name: "wrong signature", target: "/bkt", key: "uploads/a.txt", secret: "other", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached := false
+			var form map[string]string
+
+			router := gin.New()
+			s3 := router.Group("/", SigV4Middleware(credentials, clock.NewFake(policyNow)))
+			handler := func(c *gin.Context) {
+				reached = true
+				form = PostForm(c)
+				c.Status(http.StatusOK)
+			}
+			s3.POST("/:bucket", handler)
+			s3.POST("/:bucket/*key", handler)
+
+			body, contentType := signedPostForm(t, tt.secret, tt.key, deleteBody)
+			req := httptest.NewRequest(http.MethodPost, tt.target, body)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if reached != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("handler reached = %v, want %v", reached, tt.wantStatus == http.StatusOK)
+			}
+			if (form != nil) != tt.wantForm {
+				t.Errorf("PostForm() = %v, want a form %v", form, tt.wantForm)
+			}
+			if tt.wantForm && form["key"] != tt.key {
+				t.Errorf("PostForm()[key] = %q, want %q", form["key"], tt.key)
+			}
+		})
+	}
+}
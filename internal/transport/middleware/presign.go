@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+)
+
+// PresignURL builds a path-style presigned URL for method on the object key of bucket, served at endpoint
+// (e.g. "http://localhost:7777"), signed with the given access key pair at now and valid for expires from then.
+//
+// The URL carries the SigV4 query-string signature validated by SigV4Middleware, signing only the
+// host header and leaving the payload unsigned, the way AWS SDK presigners do.
+//
+// Returns the presigned URL, or an error if the endpoint is not a valid URL or expires is not
+// between one second and seven days.
+func PresignURL(method string, endpoint string, bucket string, key string, accessKey string, secretKey string, expires time.Duration, now time.Time) (string, error) {
+	if expires < time.Second || expires > maxPresignExpiry {
+		return "", domain.ErrInvalidArgument.WithMessage("Presigned URLs must expire between one second and seven days")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q: expected scheme and host", endpoint)
+	}
+
+	segments := strings.Split(bucket+"/"+key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + strings.Join(segments, "/")
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	now = now.UTC()
+	sig := &signature{accessKey: accessKey, date: now.Format("20060102"), region: "us-east-1", service: "s3"}
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", signingAlgorithm)
+	query.Set("X-Amz-Credential", sig.accessKey+"/"+sig.scope())
+	query.Set("X-Amz-Date", now.Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	u.RawQuery = canonicalQuery(query)
+
+	r, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid presigned request: %w", err)
+	}
+
+	canonical := canonicalRequest(r, []string{"host"}, unsignedPayload)
+	signed := hmacSHA256(signingKey(secretKey, sig), stringToSign(now.Format(amzDateFormat), sig.scope(), canonical))
+	u.RawQuery += "&X-Amz-Signature=" + hex.EncodeToString(signed)
+
+	return u.String(), nil
+}
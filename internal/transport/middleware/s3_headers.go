@@ -4,6 +4,7 @@ package middleware
 import (
 	"crypto/md5"
 	"fmt"
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"time"
@@ -12,15 +13,16 @@ import (
 // S3HeadersMiddleware is a Gin middleware that adds typical S3-like headers
 // to every HTTP response. It emulates the behavior of Amazon S3 by injecting
 // headers such as x-amz-request-id, x-amz-id-2, Date, and Server.
+// The Date header is the time of the given clock.
 //
 // This middleware is useful for simulating real AWS S3 responses in local
 // development and testing environments.
-func S3HeadersMiddleware() gin.HandlerFunc {
+func S3HeadersMiddleware(clock clock.Clock) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Server", "s3ego/1.0")
 		c.Header("x-amz-request-id", generateRequestID())
 		c.Header("x-amz-id-2", generateAMZID2())
-		c.Header("Date", time.Now().UTC().Format(time.RFC1123))
+		c.Header("Date", clock.Now().UTC().Format(time.RFC1123))
 
 		c.Next()
 	}
@@ -0,0 +1,391 @@
+package middleware
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	signingAlgorithm       = "AWS4-HMAC-SHA256"
+	amzDateFormat          = "20060102T150405Z"
+	unsignedPayload        = "UNSIGNED-PAYLOAD"
+	streamingSignedPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingSignedTrailer = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	streamingPrefix        = "STREAMING-"
+	maxClockSkew           = 15 * time.Minute
+	maxPresignExpiry       = 7 * 24 * time.Hour
+)
+
+// signature holds the parsed components of an AWS Signature Version 4, sent either in the
+// Authorization header or in the query string of a presigned URL.
+type signature struct {
+	accessKey     string
+	date          string // Credential scope date, as YYYYMMDD
+	region        string
+	service       string
+	signedHeaders []string
+	signature     string
+	amzDate       time.Time // Signing time of the request
+	payloadHash   string    // Declared SHA256 of the payload, or UNSIGNED-PAYLOAD / STREAMING-*
+}
+
+// scope returns the credential scope of the signature ("date/region/service/aws4_request").
+func (s *signature) scope() string {
+	return fmt.Sprintf("%s/%s/%s/aws4_request", s.date, s.region, s.service)
+}
+
+// SigV4Middleware is a Gin middleware that authenticates requests signed with
+// AWS Signature Version 4, either in the Authorization header or in the query string
+// of a presigned URL (X-Amz-Algorithm, X-Amz-Credential, X-Amz-Date, X-Amz-Expires,
+// X-Amz-SignedHeaders and X-Amz-Signature).
+//
+// credentials maps access key IDs to their secret access keys. Requests signed with an unknown
+// key, a wrong signature or a timestamp more than 15 minutes away from the server clock are
+// rejected with the InvalidAccessKeyId, SignatureDoesNotMatch and RequestTimeTooSkewed S3 errors,
+// and expired presigned URLs with AccessDenied.
+// Payloads are verified against x-amz-content-sha256, which may also be UNSIGNED-PAYLOAD or
+// STREAMING-AWS4-HMAC-SHA256-PAYLOAD, in which case every aws-chunked chunk signature is verified.
+//
+// Browser-based POST uploads ("POST /{bucket}" multipart forms, without a query string) are
+// authenticated instead by the x-amz-signature of their policy field, whose conditions must hold.
+// Their form fields are made available to the handler through PostForm, and their request body is
+// replaced with the uploaded file.
+//
+// When credentials is empty, authentication is disabled, but aws-chunked request bodies
+// are still decoded so streaming uploads store the actual object bytes, and POST upload
+// policies are still enforced.
+//
+// Request times, presigned URL and POST policy expirations are checked against the time of clock.
+func SigV4Middleware(credentials map[string]string, clock clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := clock.Now()
+
+		var err error
+		switch {
+		case isPostUpload(c):
+			err = decodePostForm(c, credentials, now)
+		case len(credentials) == 0:
+			err = decodeChunkedPayload(c.Request, nil)
+		default:
+			err = authenticate(c.Request, credentials, now)
+		}
+
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate verifies the header or presigned query signature of the request and wraps
+// its body so the payload is verified as it is read. now is the time the request is received at.
+func authenticate(r *http.Request, credentials map[string]string, now time.Time) error {
+	var (
+		sig *signature
+		err error
+	)
+
+	switch {
+	case r.Header.Get("Authorization") != "":
+		sig, err = parseSignedHeaders(r, now)
+	case r.URL.Query().Has("X-Amz-Algorithm"):
+		sig, err = parsePresignedQuery(r, now)
+	default:
+		return domain.ErrAccessDenied
+	}
+	if err != nil {
+		return err
+	}
+
+	secretKey, ok := credentials[sig.accessKey]
+	if !ok {
+		return domain.ErrInvalidAccessKeyID
+	}
+
+	key := signingKey(secretKey, sig)
+	amzDate := sig.amzDate.Format(amzDateFormat)
+	canonical := canonicalRequest(r, sig.signedHeaders, sig.payloadHash)
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign(amzDate, sig.scope(), canonical)))
+
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return domain.ErrSignatureDoesNotMatch
+	}
+
+	switch {
+	case sig.payloadHash == unsignedPayload:
+		return nil
+	case sig.payloadHash == streamingSignedPayload || sig.payloadHash == streamingSignedTrailer:
+		return decodeChunkedPayload(r, &chunkSigner{
+			key:      key,
+			amzDate:  amzDate,
+			scope:    sig.scope(),
+			previous: sig.signature,
+		})
+	case strings.HasPrefix(sig.payloadHash, streamingPrefix):
+		return decodeChunkedPayload(r, nil)
+	default:
+		r.Body = newHashVerifyingReader(r.Body, sig.payloadHash)
+		return nil
+	}
+}
+
+// parseSignedHeaders parses the signature of a request signed in the Authorization header
+// and checks that its x-amz-date or Date header is within the allowed clock skew of now.
+func parseSignedHeaders(r *http.Request, now time.Time) (*signature, error) {
+	sig, err := parseAuthorization(r.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+
+	sig.amzDate, err = requestTime(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if skew := now.Sub(sig.amzDate); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, domain.ErrRequestTimeTooSkewed
+	}
+
+	if sig.amzDate.Format("20060102") != sig.date {
+		return nil, domain.ErrAuthorizationHeaderMalformed.WithMessage("The authorization header is malformed; Invalid credential date. Date is not the same as X-Amz-Date.")
+	}
+
+	sig.payloadHash = r.Header.Get("x-amz-content-sha256")
+	if sig.payloadHash == "" {
+		return nil, domain.ErrInvalidRequest.WithMessage("Missing required header for this request: x-amz-content-sha256")
+	}
+
+	return sig, nil
+}
+
+// parsePresignedQuery parses the signature of a presigned URL and checks that it has not expired at now.
+// The payload of presigned requests is unsigned unless X-Amz-Content-Sha256 is part of the query.
+func parsePresignedQuery(r *http.Request, now time.Time) (*signature, error) {
+	query := r.URL.Query()
+
+	if algorithm := query.Get("X-Amz-Algorithm"); algorithm != signingAlgorithm {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("X-Amz-Algorithm only supports \"%s\"", signingAlgorithm)
+	}
+
+	sig, err := parseCredential(query.Get("X-Amz-Credential"))
+	if err != nil {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("Error parsing the X-Amz-Credential parameter; the Credential is mal-formed; expecting \"<YOUR-AKID>/YYYYMMDD/REGION/SERVICE/aws4_request\".")
+	}
+
+	sig.amzDate, err = time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("X-Amz-Date must be in the ISO8601 Long Format \"yyyyMMdd'T'HHmmss'Z'\"")
+	}
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("X-Amz-Expires should be a number")
+	}
+	if expires < 1 {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("X-Amz-Expires must be at least 1 second")
+	}
+	if time.Duration(expires)*time.Second > maxPresignExpiry {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("X-Amz-Expires must be less than a week (in seconds) that is; 604800")
+	}
+
+	if sig.amzDate.Format("20060102") != sig.date {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("Invalid credential date \"%s\". This date is not the same as X-Amz-Date: \"%s\".", sig.date, sig.amzDate.Format("20060102"))
+	}
+
+	if now.After(sig.amzDate.Add(time.Duration(expires) * time.Second)) {
+		return nil, domain.ErrAccessDenied.WithMessage("Request has expired")
+	}
+	if sig.amzDate.After(now.Add(maxClockSkew)) {
+		return nil, domain.ErrAccessDenied.WithMessage("Request is not valid yet")
+	}
+
+	signedHeaders := query.Get("X-Amz-SignedHeaders")
+	sig.signature = query.Get("X-Amz-Signature")
+	if signedHeaders == "" || sig.signature == "" {
+		return nil, domain.ErrAuthorizationQueryParameters.WithMessage("Query-string authentication version 4 requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters.")
+	}
+	sig.signedHeaders = strings.Split(signedHeaders, ";")
+
+	sig.payloadHash = query.Get("X-Amz-Content-Sha256")
+	if sig.payloadHash == "" {
+		sig.payloadHash = unsignedPayload
+	}
+
+	return sig, nil
+}
+
+// parseAuthorization parses an "AWS4-HMAC-SHA256 Credential=..., SignedHeaders=..., Signature=..." header.
+func parseAuthorization(header string) (*signature, error) {
+	algorithm, fields, _ := strings.Cut(header, " ")
+	if algorithm != signingAlgorithm {
+		return nil, domain.ErrAuthorizationHeaderMalformed.WithMessage("Unsupported authorization type, please use %s", signingAlgorithm)
+	}
+
+	values := make(map[string]string, 3)
+	for _, field := range strings.Split(fields, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		values[name] = value
+	}
+
+	sig, err := parseCredential(values["Credential"])
+	if err != nil {
+		return nil, err
+	}
+
+	if values["SignedHeaders"] == "" || values["Signature"] == "" {
+		return nil, domain.ErrAuthorizationHeaderMalformed
+	}
+	sig.signedHeaders = strings.Split(values["SignedHeaders"], ";")
+	sig.signature = values["Signature"]
+
+	return sig, nil
+}
+
+// parseCredential parses a credential ("AKID/20130524/us-east-1/s3/aws4_request") into a signature.
+func parseCredential(credential string) (*signature, error) {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return nil, domain.ErrAuthorizationHeaderMalformed.WithMessage("The authorization header is malformed; the Credential is mal-formed; expecting \"<YOUR-AKID>/YYYYMMDD/REGION/SERVICE/aws4_request\".")
+	}
+
+	return &signature{accessKey: parts[0], date: parts[1], region: parts[2], service: parts[3]}, nil
+}
+
+// requestTime returns the signing time of the request, from the x-amz-date or Date header.
+func requestTime(r *http.Request) (time.Time, error) {
+	if amzDate := r.Header.Get("x-amz-date"); amzDate != "" {
+		parsed, err := time.Parse(amzDateFormat, amzDate)
+		if err != nil {
+			return time.Time{}, domain.ErrAccessDenied.WithMessage("AWS authentication requires a valid Date or x-amz-date header")
+		}
+		return parsed, nil
+	}
+
+	parsed, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, domain.ErrAccessDenied.WithMessage("AWS authentication requires a valid Date or x-amz-date header")
+	}
+
+	return parsed.UTC(), nil
+}
+
+// canonicalRequest builds the SigV4 canonical request of r over the given signed headers.
+func canonicalRequest(r *http.Request, signedHeaders []string, payloadHash string) string {
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	if path == "" {
+		path = r.URL.EscapedPath()
+	}
+
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		headers.WriteString(name)
+		headers.WriteByte(':')
+		headers.WriteString(canonicalHeaderValue(r, name))
+		headers.WriteByte('\n')
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		path,
+		canonicalQuery(r.URL.Query()),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalHeaderValue returns the trimmed, comma joined values of a signed header.
+// Go moves the Host header out of the header map, so it is read from the request itself.
+func canonicalHeaderValue(r *http.Request, name string) string {
+	switch name {
+	case "host":
+		return r.Host
+	case "content-length":
+		if r.Header.Get("Content-Length") == "" {
+			return strconv.FormatInt(r.ContentLength, 10)
+		}
+	}
+
+	values := r.Header.Values(name)
+	for i, value := range values {
+		values[i] = strings.Join(strings.Fields(value), " ")
+	}
+
+	return strings.Join(values, ",")
+}
+
+// canonicalQuery returns the query parameters URI encoded and sorted by encoded name, then value,
+// leaving out the signature of presigned requests.
+func canonicalQuery(query url.Values) string {
+	params := make([][2]string, 0, len(query))
+	for name, values := range query {
+		if name == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, [2]string{uriEncode(name), uriEncode(value)})
+		}
+	}
+	// Sorting "name=value" strings would put "a-b=1" before "a=2", as '-' sorts before '='.
+	slices.SortFunc(params, func(a, b [2]string) int {
+		return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+	})
+
+	encoded := make([]string, len(params))
+	for i, param := range params {
+		encoded[i] = param[0] + "=" + param[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// uriEncode percent encodes every byte except the unreserved characters, as SigV4 requires.
+func uriEncode(value string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			encoded.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+
+	return encoded.String()
+}
+
+// stringToSign builds the SigV4 string to sign of a canonical request.
+func stringToSign(amzDate string, scope string, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	return strings.Join([]string{signingAlgorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+}
+
+// signingKey derives the SigV4 signing key of a secret for the scope of a signature.
+func signingKey(secretKey string, sig *signature) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), sig.date)
+	key = hmacSHA256(key, sig.region)
+	key = hmacSHA256(key, sig.service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the given key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
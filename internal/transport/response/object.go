@@ -0,0 +1,63 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// VersionHeader sets the x-amz-version-id header to the version of file when it is Versioned.
+// As in S3, the header is left out for buckets whose versioning was never enabled.
+func VersionHeader(c *gin.Context, file *model.File) {
+	if file.Versioned {
+		c.Header("x-amz-version-id", file.VersionID)
+	}
+}
+
+// ObjectHeaders sets the headers of a download response for file: its metadata, ETag,
+// Last-Modified, Content-Type, version and Content-Length, with Content-Range describing the
+// selected range, nil for the whole file, and x-amz-mp-parts-count when a part was requested.
+func ObjectHeaders(c *gin.Context, file *model.File, selected *model.ByteRange, partNumber int) {
+	for name, values := range file.Metadata.Header() {
+		c.Header(name, values[0])
+	}
+
+	c.Header("ETag", fmt.Sprintf(`"%s"`, file.ETag))
+	c.Header("Last-Modified", file.LastModified.UTC().Format(http.TimeFormat))
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(file.Size, 10))
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("x-amz-storage-class", "STANDARD")
+	VersionHeader(c, file)
+
+	if partNumber != 0 {
+		c.Header("x-amz-mp-parts-count", strconv.Itoa(file.PartsCount()))
+	}
+	if selected != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", selected.Start, selected.End, file.Size))
+		c.Header("Content-Length", strconv.FormatInt(selected.Length(), 10))
+	}
+}
+
+// ReadError writes the error of a download request, with the Content-Range of an unsatisfiable
+// range, the ETag and Last-Modified headers of a 304 Not Modified response, or the
+// x-amz-delete-marker and x-amz-version-id headers when the version read is a delete marker.
+func ReadError(c *gin.Context, err error, file *model.File) {
+	switch {
+	case file.DeleteMarker:
+		c.Header("x-amz-delete-marker", "true")
+		VersionHeader(c, file)
+	case errors.Is(err, domain.ErrInvalidRange):
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", file.Size))
+	case errors.Is(err, domain.ErrNotModified):
+		c.Header("ETag", fmt.Sprintf(`"%s"`, file.ETag))
+		c.Header("Last-Modified", file.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	Error(c, err)
+}
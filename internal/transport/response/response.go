@@ -0,0 +1,114 @@
+// Package response provides helpers to write S3-compatible XML responses and errors.
+package response
+
+import (
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// statusCodes maps S3 error codes to the HTTP status code AWS returns for them.
+var statusCodes = map[string]int{
+	domain.ErrNoSuchBucket.Code:                 http.StatusNotFound,
+	domain.ErrNoSuchKey.Code:                    http.StatusNotFound,
+	domain.ErrBucketAlreadyExists.Code:          http.StatusConflict,
+	domain.ErrBucketNotEmpty.Code:               http.StatusConflict,
+	domain.ErrInvalidBucketName.Code:            http.StatusBadRequest,
+	domain.ErrInvalidArgument.Code:              http.StatusBadRequest,
+	domain.ErrInvalidRequest.Code:               http.StatusBadRequest,
+	domain.ErrIncompleteBody.Code:               http.StatusBadRequest,
+	domain.ErrNotImplemented.Code:               http.StatusNotImplemented,
+	domain.ErrMalformedXML.Code:                 http.StatusBadRequest,
+	domain.ErrNoSuchUpload.Code:                 http.StatusNotFound,
+	domain.ErrInvalidPart.Code:                  http.StatusBadRequest,
+	domain.ErrInvalidPartOrder.Code:             http.StatusBadRequest,
+	domain.ErrAccessDenied.Code:                 http.StatusForbidden,
+	domain.ErrInvalidAccessKeyID.Code:           http.StatusForbidden,
+	domain.ErrSignatureDoesNotMatch.Code:        http.StatusForbidden,
+	domain.ErrRequestTimeTooSkewed.Code:         http.StatusForbidden,
+	domain.ErrAuthorizationHeaderMalformed.Code: http.StatusBadRequest,
+	domain.ErrContentSHA256Mismatch.Code:        http.StatusBadRequest,
+	domain.ErrAuthorizationQueryParameters.Code: http.StatusBadRequest,
+	domain.ErrEntityTooSmall.Code:               http.StatusBadRequest,
+	domain.ErrInvalidRange.Code:                 http.StatusRequestedRangeNotSatisfiable,
+	domain.ErrInvalidPartNumber.Code:            http.StatusRequestedRangeNotSatisfiable,
+	domain.ErrPreconditionFailed.Code:           http.StatusPreconditionFailed,
+	domain.ErrNotModified.Code:                  http.StatusNotModified,
+	domain.ErrMetadataTooLarge.Code:             http.StatusBadRequest,
+	domain.ErrBadDigest.Code:                    http.StatusBadRequest,
+	domain.ErrInvalidDigest.Code:                http.StatusBadRequest,
+	domain.ErrNoSuchVersion.Code:                http.StatusNotFound,
+	domain.ErrKeyTooLong.Code:                   http.StatusBadRequest,
+	domain.ErrEntityTooLarge.Code:               http.StatusBadRequest,
+	domain.ErrMalformedPOSTRequest.Code:         http.StatusBadRequest,
+	domain.ErrInvalidPolicyDocument.Code:        http.StatusBadRequest,
+	domain.ErrMaxPostPreDataLengthExceeded.Code: http.StatusBadRequest,
+	domain.ErrMethodNotAllowed.Code:             http.StatusMethodNotAllowed,
+	domain.ErrIllegalVersioningConfig.Code:      http.StatusBadRequest,
+	domain.ErrNoSuchLifecycleConfig.Code:        http.StatusNotFound,
+	domain.ErrNoSuchTagSet.Code:                 http.StatusNotFound,
+	domain.ErrInvalidTag.Code:                   http.StatusBadRequest,
+	domain.ErrNoSuchCORSConfig.Code:             http.StatusNotFound,
+	domain.ErrCORSForbidden.Code:                http.StatusForbidden,
+	domain.ErrBadRequest.Code:                   http.StatusBadRequest,
+}
+
+// errorDocument is the XML error document returned by S3.
+type errorDocument struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource"`
+	RequestID string   `xml:"RequestId"`
+}
+
+// XML serializes v as an XML document, prefixed with the standard XML header,
+// and writes it with the given status code.
+func XML(c *gin.Context, status int, v any) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		Error(c, err)
+		return
+	}
+
+	c.Data(status, "application/xml", append([]byte(xml.Header), body...))
+}
+
+// Error writes err as an S3 XML error document.
+//
+// Domain errors are written with their S3 code and the matching HTTP status;
+// any other error is logged and reported as a 500 InternalError, as AWS does.
+// domain.ErrNotModified is written as an empty 304 Not Modified response.
+// The RequestId is the x-amz-request-id set by middleware.S3HeadersMiddleware.
+func Error(c *gin.Context, err error) {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		log.Printf("[S3EGO] INTERNAL ERROR: %s %s: %s", c.Request.Method, c.Request.URL.Path, err)
+		domainErr = &domain.Error{Code: "InternalError", Message: "We encountered an internal error. Please try again."}
+	}
+
+	status, ok := statusCodes[domainErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	// A 304 response carries no body, only the headers already set by the handler
+	if status == http.StatusNotModified {
+		c.AbortWithStatus(status)
+		return
+	}
+
+	body, _ := xml.Marshal(errorDocument{
+		Code:      domainErr.Code,
+		Message:   domainErr.Message,
+		Resource:  c.Request.URL.Path,
+		RequestID: c.Writer.Header().Get("x-amz-request-id"),
+	})
+
+	c.Abort()
+	c.Data(status, "application/xml", append([]byte(xml.Header), body...))
+}
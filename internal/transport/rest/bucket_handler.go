@@ -3,8 +3,11 @@ package rest
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,13 +24,13 @@ func NewBucketHandler(service domain.BucketService) *BucketHandler {
 // Create handles POST requests to create a new bucket.
 // It expects a bucket name as a URL parameter "name".
 // Returns HTTP 201 Created with the bucket URL on success,
-// or HTTP 400 Bad Request if an error occurs.
+// or an S3 XML error document if an error occurs.
 func (bh *BucketHandler) Create(c *gin.Context) {
 	bucketName := c.Param("name")
 
 	buckerUrl, err := bh.service.New(bucketName)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, err)
 		return
 	}
 
@@ -37,41 +40,82 @@ func (bh *BucketHandler) Create(c *gin.Context) {
 	})
 }
 
-// FindAllFiles handles GET requests to list all files in a bucket.
-// It expects the bucket name as a URL parameter "bucket".
-// Returns HTTP 200 OK with the list of file keys on success,
-// or HTTP 400 Bad Request if an error occurs.
+// FindAllFiles handles GET requests to list the files in a bucket.
+// It expects the bucket name as a URL parameter "bucket", and accepts the prefix, delimiter,
+// start-after and max-keys query parameters of S3 listings.
+// Returns HTTP 200 OK with a page of file keys and common prefixes on success, with
+// NextStartAfter continuing the listing when it is truncated,
+// or an S3 XML error document if an error occurs.
 func (bh *BucketHandler) FindAllFiles(c *gin.Context) {
 	bucketName := c.Param("bucket")
+	query, err := parseListQuery(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
 
-	files, err := bh.service.FindAllFiles(bucketName)
+	listing, err := bh.service.ListFiles(bucketName, query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, err)
 		return
 	}
 
+	keys := make([]string, 0, len(listing.Files))
+	for _, file := range listing.Files {
+		keys = append(keys, file.Key)
+	}
+
 	// Default S3 Headers
 	c.Header("x-amz-bucket-region", "us-east-1") // Default region
 	c.Header("Content-Type", "application/json")
 
-	c.JSON(http.StatusOK, gin.H{
-		"Name":        bucketName,
-		"Contents":    files,
-		"MaxKeys":     1000,
-		"IsTruncated": false,
-	})
+	result := gin.H{
+		"Name":           bucketName,
+		"Prefix":         query.Prefix,
+		"Delimiter":      query.Delimiter,
+		"Contents":       keys,
+		"CommonPrefixes": listing.CommonPrefixes,
+		"MaxKeys":        query.MaxKeys,
+		"IsTruncated":    listing.IsTruncated,
+	}
+	if listing.IsTruncated {
+		result["NextStartAfter"] = listing.LastEntry
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseListQuery reads the prefix, delimiter, start-after and max-keys query parameters of a file
+// listing. max-keys defaults to model.MaxListKeys and is capped to it.
+// Returns domain.ErrInvalidArgument if max-keys is not a non-negative integer.
+func parseListQuery(c *gin.Context) (model.ListQuery, error) {
+	maxKeys := model.MaxListKeys
+	if raw, ok := c.GetQuery("max-keys"); ok {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return model.ListQuery{}, domain.ErrInvalidArgument.WithMessage("Provided max-keys not an integer or within integer range")
+		}
+		maxKeys = min(value, model.MaxListKeys)
+	}
+
+	return model.ListQuery{
+		Prefix:     c.Query("prefix"),
+		Delimiter:  c.Query("delimiter"),
+		StartAfter: c.Query("start-after"),
+		MaxKeys:    maxKeys,
+	}, nil
 }
 
 // Delete handles DELETE requests to remove a bucket.
 // It expects the bucket name as a URL parameter "bucket".
 // Returns HTTP 204 No Content on successful deletion,
-// or HTTP 400 Bad Request if an error occurs.
+// or an S3 XML error document if an error occurs.
 func (bh *BucketHandler) Remove(c *gin.Context) {
 	bucketName := c.Param("bucket")
 
 	err := bh.service.Remove(bucketName)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, err)
 		return
 	}
 
@@ -2,15 +2,12 @@
 package rest
 
 import (
-	"crypto/md5"
-	"fmt"
-	"io"
-	"mime/multipart"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,42 +22,69 @@ func NewFileHandler(service domain.FileService) *FileHandler {
 }
 
 // Get handles GET requests to download a file from a bucket.
-// It expects the bucket name as URL parameter "bucket" and the file key as "key".
-// Returns HTTP 200 OK with file data on success,
-// or HTTP 400 Bad Request if an error occurs.
+// It expects the bucket name as URL parameter "bucket" and the file key as "key", and reads the
+// current version of the file, or the version given by the versionId query parameter.
+// A single byte range can be requested with the Range header, or a part of a file
+// assembled by a multipart upload with the partNumber query parameter. The If-Match,
+// If-None-Match, If-Modified-Since and If-Unmodified-Since headers are honored.
+// Returns HTTP 200 OK with file data streamed from storage, or HTTP 206 Partial Content
+// with the requested range on success, HTTP 304 Not Modified or HTTP 412 Precondition Failed
+// if a condition does not hold, or an S3 XML error document if an error occurs.
 func (fh *FileHandler) Get(c *gin.Context) {
 	bucketName := c.Param("bucket")
 	key := strings.TrimPrefix(c.Param("key"), "/")
+	options := model.ParseGetOptions(c.Request.Header, c.Request.URL.Query())
 
-	fileData, fileModel, err := fh.service.Get(bucketName, key)
+	fileData, fileModel, selected, err := fh.service.GetRange(bucketName, key, options)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.ReadError(c, err, &fileModel)
 		return
 	}
+	defer fileData.Close()
 
-	// S3 Default Headers
-	etag := fmt.Sprintf(`"%x"`, md5.Sum(fileData))
-	c.Header("ETag", etag)
-	c.Header("Last-Modified", fileModel.LastModified.UTC().Format(time.RFC1123))
-	c.Header("Content-Length", fmt.Sprintf("%d", len(fileData)))
-	c.Header("Content-Type", fileModel.ContentType)
-	c.Header("Accept-Ranges", "bytes")
-	c.Header("x-amz-storage-class", "STANDARD")
+	response.ObjectHeaders(c, &fileModel, selected, options.PartNumber)
+	if selected == nil {
+		c.DataFromReader(http.StatusOK, fileModel.Size, fileModel.ContentType, fileData, nil)
+		return
+	}
 
-	c.Data(http.StatusOK, fileModel.ContentType, fileData)
+	c.DataFromReader(http.StatusPartialContent, selected.Length(), fileModel.ContentType, fileData, nil)
+}
+
+// Head handles HEAD requests for a file of a bucket.
+// It accepts the same parameters and headers as Get and returns the same status
+// and headers without a body, reading only the file metadata.
+func (fh *FileHandler) Head(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	options := model.ParseGetOptions(c.Request.Header, c.Request.URL.Query())
+
+	fileModel, selected, err := fh.service.Head(bucketName, key, options)
+	if err != nil {
+		response.ReadError(c, err, &fileModel)
+		return
+	}
+
+	response.ObjectHeaders(c, &fileModel, selected, options.PartNumber)
+	if selected == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	c.Status(http.StatusPartialContent)
 }
 
 // Remove handles DELETE requests to delete a file from a bucket.
 // It expects the bucket name as URL parameter "bucket" and the file key as "key".
 // Returns HTTP 204 No Content on success,
-// or HTTP 400 Bad Request if an error occurs.
+// or an S3 XML error document if an error occurs.
 func (fh *FileHandler) Remove(c *gin.Context) {
 	bucketName := c.Param("bucket")
 	key := strings.TrimPrefix(c.Param("key"), "/")
 
 	err := fh.service.Remove(bucketName, key)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, err)
 		return
 	}
 
@@ -69,53 +93,81 @@ func (fh *FileHandler) Remove(c *gin.Context) {
 
 // New handles POST requests to upload a new file to a bucket.
 // It expects the bucket name as URL parameter "bucket" and a form file with key "file".
+// The file is stored under the optional form field "key", or under its file name if the field is absent.
+// The x-amz-meta-* headers of the request are stored as the user-defined metadata of the file.
 // Returns HTTP 201 Created with the file key and bucket name on success,
-// or HTTP 400 Bad Request / 500 Internal Server Error if an error occurs.
+// or an S3 XML error document if an error occurs.
 func (fh *FileHandler) New(c *gin.Context) {
 	bucketName := c.Param("bucket")
 
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required, put 'file' in form"})
+		response.Error(c, domain.ErrInvalidArgument.WithMessage("file is required, put 'file' in form"))
 		return
 	}
 
-	fileData, err := getFileData(fileHeader)
+	fileData, err := fileHeader.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+		response.Error(c, err)
 		return
 	}
+	defer fileData.Close()
+
+	key := c.PostForm("key")
+	if key == "" {
+		key = fileHeader.Filename
+	}
 
-	fileKey, fileEtag, err := fh.service.Upload(bucketName, fileData, fileHeader.Filename)
+	options := model.PutOptions{Metadata: model.Metadata{User: model.ParseMetadata(c.Request.Header).User}}
+
+	file, err := fh.service.UploadWithOptions(bucketName, fileData, key, options)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, err)
 		return
 	}
 
-	// S3 Default Headers
-	c.Header("ETag", fileEtag)
-	c.Header("x-amz-version-id", "null")
-	c.Header("x-amz-storage-class", "STANDARD")
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "File uploaded successfully",
-		"key":     fileKey,
-		"bucket":  bucketName,
-		"etag":    fileEtag,
-	})
+	writeUploaded(c, bucketName, file)
 }
 
-// getFileData reads all bytes from the uploaded file header.
-func getFileData(fileHeader *multipart.FileHeader) ([]byte, error) {
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, err
+// Put handles PUT requests to upload a file to a bucket from the raw request body.
+// It expects the bucket name as URL parameter "bucket" and the target key as "key".
+// As with S3 PutObject, the Content-Type header is stored as the file content type (detected from
+// the data when absent), the x-amz-meta-* headers and the Cache-Control, Content-Disposition,
+// Content-Encoding, Content-Language and Expires headers as the file metadata,
+// and the conditional headers If-None-Match: * and If-Match are honored.
+// Returns HTTP 201 Created with the file key and bucket name on success,
+// or an S3 XML error document if an error occurs.
+func (fh *FileHandler) Put(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	options := model.PutOptions{
+		Preconditions: model.ParsePreconditions(c.Request.Header, ""),
+		ContentType:   c.GetHeader("Content-Type"),
+		Metadata:      model.ParseMetadata(c.Request.Header),
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
+	file, err := fh.service.UploadWithOptions(bucketName, c.Request.Body, key, options)
 	if err != nil {
-		return nil, err
+		response.Error(c, err)
+		return
 	}
-	return data, nil
+
+	writeUploaded(c, bucketName, file)
+}
+
+// writeUploaded writes the response of a successful upload: the S3 default headers
+// and HTTP 201 Created with the file key, bucket name, ETag and version ID.
+func writeUploaded(c *gin.Context, bucketName string, file model.File) {
+	// S3 Default Headers
+	c.Header("ETag", file.ETag)
+	response.VersionHeader(c, &file)
+	c.Header("x-amz-storage-class", "STANDARD")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "File uploaded successfully",
+		"key":        file.Key,
+		"bucket":     bucketName,
+		"etag":       file.ETag,
+		"version_id": file.VersionID,
+	})
 }
@@ -2,16 +2,30 @@
 package routes
 
 import (
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
 	"github.com/bonifacio-pedro/s3ego/internal/transport/middleware"
 	"github.com/bonifacio-pedro/s3ego/internal/transport/rest"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/s3api"
 	"github.com/gin-gonic/gin"
 )
 
 // Router wraps the Gin engine and the HTTP handlers for buckets and files.
 type Router struct {
-	rg            *gin.Engine
-	bucketHandler *rest.BucketHandler
-	fileHandler   *rest.FileHandler
+	rg                 *gin.Engine
+	bucketHandler      *rest.BucketHandler
+	fileHandler        *rest.FileHandler
+	s3BucketHandler    *s3api.BucketHandler
+	s3ObjectHandler    *s3api.ObjectHandler
+	s3MultipartHandler *s3api.MultipartHandler
+	s3LifecycleHandler *s3api.LifecycleHandler
+	s3TaggingHandler   *s3api.TaggingHandler
+	s3CORSHandler      *s3api.CORSHandler
+	corsService        domain.CORSService
+	credentials        map[string]string
+	clock              clock.Clock
 }
 
 // NewRouter creates a new Router instance with the provided Gin engine and handlers.
@@ -20,24 +34,166 @@ type Router struct {
 //   - rg: the Gin engine instance to register routes on.
 //   - bucketHandler: handler responsible for bucket-related endpoints.
 //   - fileHandler: handler responsible for file-related endpoints.
+//   - s3BucketHandler: handler responsible for S3 protocol bucket requests.
+//   - s3ObjectHandler: handler responsible for S3 protocol object requests.
+//   - s3MultipartHandler: handler responsible for S3 protocol multipart upload requests.
+//   - s3LifecycleHandler: handler responsible for S3 protocol bucket lifecycle requests.
+//   - s3TaggingHandler: handler responsible for S3 protocol object and bucket tagging requests.
+//   - s3CORSHandler: handler responsible for S3 protocol bucket CORS configuration requests.
+//   - corsService: service evaluating cross-origin requests against the CORS rules of buckets.
+//   - credentials: access key IDs and secrets accepted by the S3 API, empty to disable authentication.
+//   - clock: the clock stamping the Date header and checking request times and expirations.
 //
 // Returns a pointer to the newly created Router.
-func NewRouter(rg *gin.Engine, bucketHandler *rest.BucketHandler, fileHandler *rest.FileHandler) *Router {
-	return &Router{rg: rg, bucketHandler: bucketHandler, fileHandler: fileHandler}
+func NewRouter(
+	rg *gin.Engine,
+	bucketHandler *rest.BucketHandler,
+	fileHandler *rest.FileHandler,
+	s3BucketHandler *s3api.BucketHandler,
+	s3ObjectHandler *s3api.ObjectHandler,
+	s3MultipartHandler *s3api.MultipartHandler,
+	s3LifecycleHandler *s3api.LifecycleHandler,
+	s3TaggingHandler *s3api.TaggingHandler,
+	s3CORSHandler *s3api.CORSHandler,
+	corsService domain.CORSService,
+	credentials map[string]string,
+	clock clock.Clock,
+) *Router {
+	return &Router{
+		rg:                 rg,
+		bucketHandler:      bucketHandler,
+		fileHandler:        fileHandler,
+		s3BucketHandler:    s3BucketHandler,
+		s3ObjectHandler:    s3ObjectHandler,
+		s3MultipartHandler: s3MultipartHandler,
+		s3LifecycleHandler: s3LifecycleHandler,
+		s3TaggingHandler:   s3TaggingHandler,
+		s3CORSHandler:      s3CORSHandler,
+		corsService:        corsService,
+		credentials:        credentials,
+		clock:              clock,
+	}
 }
 
 // RegisterRoutes configure S3HeadersMiddleware and
 // registers all HTTP routes/endpoints for the bucket and file handlers.
 //
 // It sets up routes for creating buckets, listing files, deleting buckets and files,
-// uploading files, and retrieving files from the bucket emulator.
+// uploading files, and retrieving files from the bucket emulator, followed by
+// the path-style S3 REST API ("/", "/{bucket}" and "/{bucket}/{key}"), which is
+// authenticated by SigV4Middleware when credentials are configured. CORSMiddleware runs first,
+// answering the unsigned preflight requests of browsers and adding the Access-Control-* headers
+// of the bucket CORS rules to the other requests.
 func (ro *Router) RegisterRoutes() {
-	ro.rg.Use(middleware.S3HeadersMiddleware())
+	ro.rg.Use(middleware.S3HeadersMiddleware(ro.clock))
 
 	ro.rg.POST("/bucket-emulator/new-bucket/:name", ro.bucketHandler.Create)
 	ro.rg.GET("/bucket-emulator/list-files/:bucket", ro.bucketHandler.FindAllFiles)
 	ro.rg.DELETE("/bucket-emulator/remove-bucket/:bucket", ro.bucketHandler.Remove)
 	ro.rg.DELETE("/bucket-emulator/remove-file/:bucket/*key", ro.fileHandler.Remove)
 	ro.rg.POST("/bucket-emulator/upload-file/:bucket", ro.fileHandler.New)
+	ro.rg.PUT("/bucket-emulator/upload-file/:bucket/*key", ro.fileHandler.Put)
 	ro.rg.GET("/bucket-emulator/get-file/:bucket/*key", ro.fileHandler.Get)
+	ro.rg.HEAD("/bucket-emulator/get-file/:bucket/*key", ro.fileHandler.Head)
+
+	s3 := ro.rg.Group("/", middleware.CORSMiddleware(ro.corsService), middleware.SigV4Middleware(ro.credentials, ro.clock))
+	s3.GET("/", ro.s3BucketHandler.List)
+
+	// S3 selects the operation of a request by its method and subresource query parameters.
+	putBucket := bySubresource(ro.s3BucketHandler.Create,
+		on("versioning", ro.s3BucketHandler.PutVersioning),
+		on("lifecycle", ro.s3LifecycleHandler.Put),
+		on("tagging", ro.s3TaggingHandler.PutBucket),
+		on("cors", ro.s3CORSHandler.Put),
+	)
+	headBucket := ro.s3BucketHandler.Head
+	getBucket := bySubresource(ro.s3BucketHandler.ListObjects,
+		on("uploads", ro.s3MultipartHandler.ListUploads),
+		on("versioning", ro.s3BucketHandler.GetVersioning),
+		on("versions", ro.s3BucketHandler.ListVersions),
+		on("lifecycle", ro.s3LifecycleHandler.Get),
+		on("tagging", ro.s3TaggingHandler.GetBucket),
+		on("cors", ro.s3CORSHandler.Get),
+	)
+	postBucket := bySubresource(ro.s3ObjectHandler.Post,
+		on("delete", ro.s3ObjectHandler.DeleteObjects),
+	)
+	deleteBucket := bySubresource(ro.s3BucketHandler.Remove,
+		on("lifecycle", ro.s3LifecycleHandler.Remove),
+		on("tagging", ro.s3TaggingHandler.RemoveBucket),
+		on("cors", ro.s3CORSHandler.Remove),
+	)
+
+	putObject := bySubresource(ro.s3ObjectHandler.Put,
+		on("uploadId", ro.s3MultipartHandler.UploadPart),
+		on("tagging", ro.s3TaggingHandler.PutObject),
+	)
+	headObject := ro.s3ObjectHandler.Head
+	getObject := bySubresource(ro.s3ObjectHandler.Get,
+		on("uploadId", ro.s3MultipartHandler.ListParts),
+		on("tagging", ro.s3TaggingHandler.GetObject),
+	)
+	postObject := bySubresource(s3api.NotImplemented,
+		on("uploads", ro.s3MultipartHandler.Create),
+		on("uploadId", ro.s3MultipartHandler.Complete),
+	)
+	deleteObject := bySubresource(ro.s3ObjectHandler.Remove,
+		on("uploadId", ro.s3MultipartHandler.Abort),
+		on("tagging", ro.s3TaggingHandler.RemoveObject),
+	)
+
+	s3.PUT("/:bucket", putBucket)
+	s3.HEAD("/:bucket", headBucket)
+	s3.GET("/:bucket", getBucket)
+	s3.POST("/:bucket", postBucket)
+	s3.DELETE("/:bucket", deleteBucket)
+
+	s3.PUT("/:bucket/*key", objectOrBucket(putObject, putBucket))
+	s3.HEAD("/:bucket/*key", objectOrBucket(headObject, headBucket))
+	s3.GET("/:bucket/*key", objectOrBucket(getObject, getBucket))
+	s3.POST("/:bucket/*key", objectOrBucket(postObject, postBucket))
+	s3.DELETE("/:bucket/*key", objectOrBucket(deleteObject, deleteBucket))
+
+	// Preflight requests are answered by CORSMiddleware and never reach these handlers.
+	s3.OPTIONS("/:bucket", s3api.NotImplemented)
+	s3.OPTIONS("/:bucket/*key", s3api.NotImplemented)
+}
+
+// subresource associates an S3 subresource query parameter (e.g. "uploads") with its handler.
+type subresource struct {
+	param   string
+	handler gin.HandlerFunc
+}
+
+// on creates a subresource routing requests carrying the given query parameter to handler.
+func on(param string, handler gin.HandlerFunc) subresource {
+	return subresource{param: param, handler: handler}
+}
+
+// bySubresource dispatches a request to the handler of the first subresource whose query
+// parameter is present, or to defaultHandler when none is.
+func bySubresource(defaultHandler gin.HandlerFunc, subresources ...subresource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, sub := range subresources {
+			if _, ok := c.GetQuery(sub.param); ok {
+				sub.handler(c)
+				return
+			}
+		}
+
+		defaultHandler(c)
+	}
+}
+
+// objectOrBucket dispatches a "/:bucket/*key" request to objectHandler, or to bucketHandler
+// when the key is empty (e.g. "/mybucket/"), which S3 treats as a bucket request.
+func objectOrBucket(objectHandler gin.HandlerFunc, bucketHandler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.TrimPrefix(c.Param("key"), "/") == "" {
+			bucketHandler(c)
+			return
+		}
+
+		objectHandler(c)
+	}
 }
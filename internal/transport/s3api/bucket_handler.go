@@ -0,0 +1,406 @@
+// Package s3api provides HTTP handlers implementing the Amazon S3 REST protocol
+// in path style, so unmodified AWS SDK clients can talk to the emulator.
+package s3api
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxListBuckets is the largest max-buckets value accepted by ListBuckets.
+const maxListBuckets = 10000
+
+// NotImplemented answers requests for S3 operations the emulator does not support
+// with a 501 NotImplemented error.
+func NotImplemented(c *gin.Context) {
+	response.Error(c, domain.ErrNotImplemented)
+}
+
+// BucketHandler handles S3 requests addressed to a bucket ("/{bucket}").
+type BucketHandler struct {
+	service domain.BucketService
+}
+
+// NewBucketHandler creates a new BucketHandler with the given BucketService.
+func NewBucketHandler(service domain.BucketService) *BucketHandler {
+	return &BucketHandler{service: service}
+}
+
+// Create handles CreateBucket requests ("PUT /{bucket}").
+// Returns HTTP 200 OK with the bucket location on success.
+func (bh *BucketHandler) Create(c *gin.Context) {
+	bucketName := c.Param("bucket")
+
+	if _, err := bh.service.New(bucketName); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("Location", "/"+bucketName)
+	c.Status(http.StatusOK)
+}
+
+// List handles ListBuckets requests ("GET /").
+// It supports the prefix, max-buckets and continuation-token query parameters.
+// Returns HTTP 200 OK with a ListAllMyBucketsResult document.
+func (bh *BucketHandler) List(c *gin.Context) {
+	query := model.BucketQuery{Prefix: c.Query("prefix")}
+
+	if maxBuckets, ok := c.GetQuery("max-buckets"); ok {
+		parsed, err := strconv.Atoi(maxBuckets)
+		if err != nil || parsed < 1 || parsed > maxListBuckets {
+			response.Error(c, domain.ErrInvalidArgument.WithMessage("max-buckets must be an integer between 1 and %d", maxListBuckets))
+			return
+		}
+		query.MaxBuckets = parsed
+	}
+
+	if token := c.Query("continuation-token"); token != "" {
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			response.Error(c, domain.ErrInvalidArgument.WithMessage("The continuation token provided is incorrect"))
+			return
+		}
+		query.StartAfter = string(decoded)
+	}
+
+	listing, err := bh.service.List(query)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := listAllMyBucketsResult{
+		Xmlns:   s3Namespace,
+		Owner:   defaultOwner,
+		Buckets: make([]bucketEntry, 0, len(listing.Buckets)),
+		Prefix:  query.Prefix,
+	}
+
+	for _, bucket := range listing.Buckets {
+		result.Buckets = append(result.Buckets, bucketEntry{
+			Name:         bucket.Name,
+			CreationDate: bucket.CreatedAt.UTC().Format(timeFormat),
+			BucketRegion: region,
+		})
+	}
+
+	if listing.IsTruncated {
+		lastBucket := listing.Buckets[len(listing.Buckets)-1].Name
+		result.ContinuationToken = base64.StdEncoding.EncodeToString([]byte(lastBucket))
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// Head handles HeadBucket requests ("HEAD /{bucket}").
+// Returns HTTP 200 OK if the bucket exists, or a NoSuchBucket error otherwise.
+func (bh *BucketHandler) Head(c *gin.Context) {
+	bucketName := c.Param("bucket")
+
+	exists, err := bh.service.Exists(bucketName)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if !exists {
+		response.Error(c, domain.ErrNoSuchBucket)
+		return
+	}
+
+	c.Header("x-amz-bucket-region", region)
+	c.Status(http.StatusOK)
+}
+
+// ListObjects handles ListObjects requests ("GET /{bucket}"), answering with the
+// ListObjectsV2 document when "list-type=2" is set and with the original ListObjects one otherwise.
+// It supports the prefix, delimiter, max-keys, start-after, continuation-token, marker
+// and encoding-type query parameters.
+// Returns HTTP 200 OK with a ListBucketResult document.
+func (bh *BucketHandler) ListObjects(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	isV2 := c.Query("list-type") == "2"
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	encoding, err := parseKeyEncoding(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	continuationToken := c.Query("continuation-token")
+	if isV2 {
+		query.StartAfter = c.Query("start-after")
+		if continuationToken != "" {
+			decoded, err := base64.StdEncoding.DecodeString(continuationToken)
+			if err != nil {
+				response.Error(c, domain.ErrInvalidArgument.WithMessage("The continuation token provided is incorrect"))
+				return
+			}
+			query.StartAfter = string(decoded)
+		}
+	} else {
+		query.StartAfter = c.Query("marker")
+	}
+
+	listing, err := bh.service.ListFiles(bucketName, query)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	contents := make([]objectContent, 0, len(listing.Files))
+	for _, file := range listing.Files {
+		contents = append(contents, objectContent{
+			Key:          encoding.encode(file.Key),
+			LastModified: file.LastModified.UTC().Format(timeFormat),
+			ETag:         quoteETag(file.ETag),
+			Size:         file.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+
+	prefixes := make([]commonPrefix, 0, len(listing.CommonPrefixes))
+	for _, prefix := range listing.CommonPrefixes {
+		prefixes = append(prefixes, commonPrefix{Prefix: encoding.encode(prefix)})
+	}
+
+	if !isV2 {
+		result := listBucketResultV1{
+			Xmlns:          s3Namespace,
+			Name:           bucketName,
+			Prefix:         encoding.encode(query.Prefix),
+			Marker:         encoding.encode(query.StartAfter),
+			Delimiter:      encoding.encode(query.Delimiter),
+			MaxKeys:        query.MaxKeys,
+			IsTruncated:    listing.IsTruncated,
+			EncodingType:   string(encoding),
+			Contents:       contents,
+			CommonPrefixes: prefixes,
+		}
+		if listing.IsTruncated && query.Delimiter != "" {
+			result.NextMarker = encoding.encode(listing.LastEntry)
+		}
+
+		response.XML(c, http.StatusOK, result)
+		return
+	}
+
+	result := listBucketResult{
+		Xmlns:             s3Namespace,
+		Name:              bucketName,
+		Prefix:            encoding.encode(query.Prefix),
+		Delimiter:         encoding.encode(query.Delimiter),
+		MaxKeys:           query.MaxKeys,
+		KeyCount:          listing.Count(),
+		IsTruncated:       listing.IsTruncated,
+		ContinuationToken: continuationToken,
+		StartAfter:        encoding.encode(c.Query("start-after")),
+		EncodingType:      string(encoding),
+		Contents:          contents,
+		CommonPrefixes:    prefixes,
+	}
+	if listing.IsTruncated {
+		result.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(listing.LastEntry))
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// ListVersions handles ListObjectVersions requests ("GET /{bucket}?versions").
+// It supports the prefix, delimiter, max-keys, key-marker, version-id-marker and encoding-type
+// query parameters.
+// Returns HTTP 200 OK with a ListVersionsResult document listing the Version and DeleteMarker
+// entries, by key and newest first.
+func (bh *BucketHandler) ListVersions(c *gin.Context) {
+	bucketName := c.Param("bucket")
+
+	listQuery, err := parseListQuery(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	encoding, err := parseKeyEncoding(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	query := model.VersionQuery{
+		Prefix:          listQuery.Prefix,
+		Delimiter:       listQuery.Delimiter,
+		KeyMarker:       c.Query("key-marker"),
+		VersionIDMarker: c.Query("version-id-marker"),
+		MaxKeys:         listQuery.MaxKeys,
+	}
+
+	listing, err := bh.service.ListVersions(bucketName, query)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := listVersionsResult{
+		Xmlns:               s3Namespace,
+		Name:                bucketName,
+		Prefix:              encoding.encode(query.Prefix),
+		KeyMarker:           encoding.encode(query.KeyMarker),
+		VersionIDMarker:     query.VersionIDMarker,
+		NextKeyMarker:       encoding.encode(listing.NextKeyMarker),
+		NextVersionIDMarker: listing.NextVersionIDMarker,
+		Delimiter:           encoding.encode(query.Delimiter),
+		MaxKeys:             query.MaxKeys,
+		IsTruncated:         listing.IsTruncated,
+		EncodingType:        string(encoding),
+		Versions:            make([]versionEntry, 0, len(listing.Versions)),
+		CommonPrefixes:      make([]commonPrefix, 0, len(listing.CommonPrefixes)),
+	}
+
+	for _, file := range listing.Versions {
+		entry := versionEntry{
+			XMLName:      xml.Name{Local: "Version"},
+			Key:          encoding.encode(file.Key),
+			VersionID:    file.VersionID,
+			IsLatest:     file.IsLatest,
+			LastModified: file.LastModified.UTC().Format(timeFormat),
+			Owner:        defaultOwner,
+		}
+		if file.DeleteMarker {
+			entry.XMLName.Local = "DeleteMarker"
+		} else {
+			entry.ETag = quoteETag(file.ETag)
+			entry.Size = &file.Size
+			entry.StorageClass = "STANDARD"
+		}
+		result.Versions = append(result.Versions, entry)
+	}
+
+	for _, prefix := range listing.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: encoding.encode(prefix)})
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// GetVersioning handles GetBucketVersioning requests ("GET /{bucket}?versioning").
+// Returns HTTP 200 OK with a VersioningConfiguration document, without a Status if versioning
+// was never enabled on the bucket.
+func (bh *BucketHandler) GetVersioning(c *gin.Context) {
+	status, err := bh.service.GetVersioning(c.Param("bucket"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.XML(c, http.StatusOK, versioningConfiguration{Xmlns: s3Namespace, Status: string(status)})
+}
+
+// PutVersioning handles PutBucketVersioning requests ("PUT /{bucket}?versioning"), enabling
+// or suspending the versioning of the bucket with the Status of the VersioningConfiguration body.
+// MFA delete is not supported.
+// Returns HTTP 200 OK on success.
+func (bh *BucketHandler) PutVersioning(c *gin.Context) {
+	var request versioningConfiguration
+	if err := xml.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	if request.MfaDelete == "Enabled" {
+		response.Error(c, domain.ErrNotImplemented.WithMessage("MFA delete is not supported"))
+		return
+	}
+
+	if err := bh.service.SetVersioning(c.Param("bucket"), model.VersioningStatus(request.Status)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// parseListQuery reads the prefix, delimiter and max-keys query parameters shared by both
+// ListObjects versions. max-keys defaults to model.MaxListKeys.
+func parseListQuery(c *gin.Context) (model.ListQuery, error) {
+	maxKeys, err := parseIntQuery(c, "max-keys", model.MaxListKeys, model.MaxListKeys)
+	if err != nil {
+		return model.ListQuery{}, err
+	}
+
+	return model.ListQuery{
+		Prefix:    c.Query("prefix"),
+		Delimiter: c.Query("delimiter"),
+		MaxKeys:   maxKeys,
+	}, nil
+}
+
+// keyEncoding is the encoding-type of a listing request, applied to the keys and prefixes of its result.
+type keyEncoding string
+
+// parseKeyEncoding reads the encoding-type query parameter of a listing request.
+// Returns domain.ErrInvalidArgument unless it is empty or "url".
+func parseKeyEncoding(c *gin.Context) (keyEncoding, error) {
+	encoding := keyEncoding(c.Query("encoding-type"))
+	if encoding != "" && encoding != "url" {
+		return "", domain.ErrInvalidArgument.WithMessage("Invalid Encoding Method specified in Request")
+	}
+
+	return encoding, nil
+}
+
+// encode returns a key or prefix as listed in the result: URL encoded, keeping its slashes,
+// with the "url" encoding, and unchanged otherwise.
+func (e keyEncoding) encode(key string) string {
+	if e == "url" {
+		return strings.ReplaceAll(url.QueryEscape(key), "%2F", "/")
+	}
+
+	return key
+}
+
+// parseIntQuery reads an optional non-negative integer query parameter, returning defaultValue
+// if it is absent and capping it to maxValue, as S3 does for its paging parameters.
+// Returns domain.ErrInvalidArgument if the value is not a non-negative integer.
+func parseIntQuery(c *gin.Context, name string, defaultValue int, maxValue int) (int, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, domain.ErrInvalidArgument.WithMessage("Provided %s not an integer or within integer range", name)
+	}
+
+	return min(value, maxValue), nil
+}
+
+// Remove handles DeleteBucket requests ("DELETE /{bucket}").
+// As in S3, only empty buckets can be deleted.
+// Returns HTTP 204 No Content on success.
+func (bh *BucketHandler) Remove(c *gin.Context) {
+	bucketName := c.Param("bucket")
+
+	if err := bh.service.RemoveEmpty(bucketName); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
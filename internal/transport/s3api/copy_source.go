@@ -0,0 +1,76 @@
+package s3api
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// parseCopySource parses an x-amz-copy-source header ("/bucket/key" or "bucket/key",
+// URL-encoded and optionally followed by "?versionId=...") into its bucket, key and version ID,
+// which is empty when no version is given.
+func parseCopySource(header string) (string, string, string, error) {
+	source, query, _ := strings.Cut(header, "?")
+
+	decoded, err := url.PathUnescape(source)
+	if err != nil {
+		return "", "", "", domain.ErrInvalidArgument.WithMessage("Invalid copy source encoding")
+	}
+
+	bucketName, key, ok := strings.Cut(strings.TrimPrefix(decoded, "/"), "/")
+	if !ok || bucketName == "" || key == "" {
+		return "", "", "", domain.ErrInvalidArgument.WithMessage("Copy Source must mention the source bucket and key: sourcebucket/sourcekey")
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", "", domain.ErrInvalidArgument.WithMessage("Invalid copy source encoding")
+	}
+
+	return bucketName, key, params.Get("versionId"), nil
+}
+
+// parseDirective reads a COPY or REPLACE directive header of a CopyObject request,
+// such as x-amz-metadata-directive. Returns model.DirectiveCopy if the header is absent,
+// or domain.ErrInvalidArgument if it holds another value.
+func parseDirective(c *gin.Context, header string) (model.Directive, error) {
+	switch directive := model.Directive(c.GetHeader(header)); directive {
+	case "":
+		return model.DirectiveCopy, nil
+	case model.DirectiveCopy, model.DirectiveReplace:
+		return directive, nil
+	default:
+		return "", domain.ErrInvalidArgument.WithMessage("Unknown %s: %s", header, directive)
+	}
+}
+
+// parseCopySourceRange parses an x-amz-copy-source-range header ("bytes=first-last").
+// Returns nil if the header is empty.
+func parseCopySourceRange(header string) (*model.ByteRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	invalid := domain.ErrInvalidArgument.WithMessage("The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy")
+
+	first, last, ok := strings.Cut(strings.TrimPrefix(header, "bytes="), "-")
+	if !ok || !strings.HasPrefix(header, "bytes=") {
+		return nil, invalid
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return nil, invalid
+	}
+
+	return &model.ByteRange{Start: start, End: end}, nil
+}
@@ -0,0 +1,112 @@
+package s3api
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCORSRequestSize bounds the XML body of a PutBucketCors request, as S3 does.
+const maxCORSRequestSize = 64 * 1024
+
+// CORSHandler handles S3 requests addressed to the CORS configuration of a bucket ("/{bucket}?cors").
+type CORSHandler struct {
+	service domain.CORSService
+}
+
+// NewCORSHandler creates a new CORSHandler with the given CORSService.
+func NewCORSHandler(service domain.CORSService) *CORSHandler {
+	return &CORSHandler{service: service}
+}
+
+// Put handles PutBucketCors requests ("PUT /{bucket}?cors"), replacing the CORS rules of the bucket
+// with the rules of the CORSConfiguration body. The body is checked against its Content-MD5 header when set.
+// Returns HTTP 200 OK on success.
+func (ch *CORSHandler) Put(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(requestBody(c), maxCORSRequestSize+1))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	if len(body) > maxCORSRequestSize {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	if err := verifyContentMD5(c.GetHeader("Content-MD5"), body); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	var request corsConfiguration
+	if err := xml.Unmarshal(body, &request); err != nil {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	rules := make([]model.CORSRule, 0, len(request.Rules))
+	for _, entry := range request.Rules {
+		rule := model.CORSRule{
+			ID:             entry.ID,
+			AllowedOrigins: entry.AllowedOrigins,
+			AllowedMethods: entry.AllowedMethods,
+			AllowedHeaders: entry.AllowedHeaders,
+			ExposeHeaders:  entry.ExposeHeaders,
+		}
+		if entry.MaxAgeSeconds != nil {
+			rule.MaxAgeSeconds = *entry.MaxAgeSeconds
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := ch.service.Put(c.Param("bucket"), rules); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Get handles GetBucketCors requests ("GET /{bucket}?cors").
+// Returns HTTP 200 OK with a CORSConfiguration document, or a NoSuchCORSConfiguration error
+// if the bucket has no CORS rules.
+func (ch *CORSHandler) Get(c *gin.Context) {
+	rules, err := ch.service.Get(c.Param("bucket"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := corsConfiguration{Xmlns: s3Namespace, Rules: make([]corsRule, 0, len(rules))}
+	for _, rule := range rules {
+		entry := corsRule{
+			ID:             rule.ID,
+			AllowedHeaders: rule.AllowedHeaders,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedOrigins: rule.AllowedOrigins,
+			ExposeHeaders:  rule.ExposeHeaders,
+		}
+		if rule.MaxAgeSeconds > 0 {
+			entry.MaxAgeSeconds = &rule.MaxAgeSeconds
+		}
+		result.Rules = append(result.Rules, entry)
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// Remove handles DeleteBucketCors requests ("DELETE /{bucket}?cors").
+// Returns HTTP 204 No Content on success, whether or not the bucket had CORS rules.
+func (ch *CORSHandler) Remove(c *gin.Context) {
+	if err := ch.service.Remove(c.Param("bucket")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,275 @@
+package s3api
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxLifecycleRequestSize bounds the XML body of a PutBucketLifecycleConfiguration request:
+// 1000 rules with their filters and actions.
+const maxLifecycleRequestSize = 2 * 1024 * 1024
+
+// LifecycleHandler handles S3 requests addressed to the lifecycle configuration of a bucket ("/{bucket}?lifecycle").
+type LifecycleHandler struct {
+	service domain.LifecycleService
+}
+
+// NewLifecycleHandler creates a new LifecycleHandler with the given LifecycleService.
+func NewLifecycleHandler(service domain.LifecycleService) *LifecycleHandler {
+	return &LifecycleHandler{service: service}
+}
+
+// Put handles PutBucketLifecycleConfiguration requests ("PUT /{bucket}?lifecycle"), replacing the
+// lifecycle rules of the bucket with the rules of the LifecycleConfiguration body. The body is checked
+// against its Content-MD5 header when set. Transitions and object size filters are not supported.
+// Returns HTTP 200 OK on success.
+func (lh *LifecycleHandler) Put(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(requestBody(c), maxLifecycleRequestSize+1))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	if len(body) > maxLifecycleRequestSize {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	if err := verifyContentMD5(c.GetHeader("Content-MD5"), body); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	var request lifecycleConfiguration
+	if err := xml.Unmarshal(body, &request); err != nil {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	rules := make([]model.LifecycleRule, 0, len(request.Rules))
+	for _, entry := range request.Rules {
+		rule, err := parseLifecycleRule(entry)
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := lh.service.Put(c.Param("bucket"), rules); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Get handles GetBucketLifecycleConfiguration requests ("GET /{bucket}?lifecycle").
+// Returns HTTP 200 OK with a LifecycleConfiguration document, or a NoSuchLifecycleConfiguration
+// error if the bucket has no lifecycle rules.
+func (lh *LifecycleHandler) Get(c *gin.Context) {
+	rules, err := lh.service.Get(c.Param("bucket"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := lifecycleConfiguration{Xmlns: s3Namespace, Rules: make([]lifecycleRule, 0, len(rules))}
+	for _, rule := range rules {
+		result.Rules = append(result.Rules, formatLifecycleRule(rule))
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// Remove handles DeleteBucketLifecycle requests ("DELETE /{bucket}?lifecycle").
+// Returns HTTP 204 No Content on success, whether or not the bucket had lifecycle rules.
+func (lh *LifecycleHandler) Remove(c *gin.Context) {
+	if err := lh.service.Remove(c.Param("bucket")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseLifecycleRule converts a rule of a LifecycleConfiguration document into a model.LifecycleRule.
+// Returns domain.ErrMalformedXML or domain.ErrInvalidArgument if the rule elements are inconsistent,
+// or domain.ErrNotImplemented if it uses transitions or object size filters.
+func parseLifecycleRule(entry lifecycleRule) (model.LifecycleRule, error) {
+	rule := model.LifecycleRule{ID: entry.ID, Status: model.LifecycleStatus(entry.Status)}
+
+	if len(entry.Transitions) > 0 || len(entry.NoncurrentVersionTransitions) > 0 {
+		return rule, domain.ErrNotImplemented.WithMessage("Lifecycle transitions are not supported")
+	}
+
+	if err := parseLifecycleFilter(entry, &rule); err != nil {
+		return rule, err
+	}
+
+	if expiration := entry.Expiration; expiration != nil {
+		set := 0
+		if expiration.Days != nil {
+			days, err := positiveDays(expiration.Days, "Days", "Expiration")
+			if err != nil {
+				return rule, err
+			}
+			rule.ExpirationDays = days
+			set++
+		}
+		if expiration.Date != "" {
+			date, err := time.Parse(time.RFC3339, expiration.Date)
+			if err != nil {
+				return rule, domain.ErrInvalidArgument.WithMessage("'Date' must be in ISO 8601 format")
+			}
+			rule.ExpirationDate = date
+			set++
+		}
+		if expiration.ExpiredObjectDeleteMarker != nil {
+			rule.ExpiredObjectDeleteMarker = *expiration.ExpiredObjectDeleteMarker
+			set++
+		}
+		if set != 1 {
+			return rule, domain.ErrMalformedXML.WithMessage("Expiration must specify exactly one of Days, Date or ExpiredObjectDeleteMarker")
+		}
+	}
+
+	if noncurrent := entry.NoncurrentVersionExpiration; noncurrent != nil {
+		days, err := positiveDays(noncurrent.NoncurrentDays, "NoncurrentDays", "NoncurrentVersionExpiration")
+		if err != nil {
+			return rule, err
+		}
+		rule.NoncurrentDays = days
+
+		if noncurrent.NewerNoncurrentVersions != nil {
+			if *noncurrent.NewerNoncurrentVersions <= 0 {
+				return rule, domain.ErrInvalidArgument.WithMessage("'NewerNoncurrentVersions' for NoncurrentVersionExpiration action must be a positive integer")
+			}
+			rule.NewerNoncurrentVersions = *noncurrent.NewerNoncurrentVersions
+		}
+	}
+
+	if abort := entry.AbortIncompleteMultipartUpload; abort != nil {
+		days, err := positiveDays(abort.DaysAfterInitiation, "DaysAfterInitiation", "AbortIncompleteMultipartUpload")
+		if err != nil {
+			return rule, err
+		}
+		rule.AbortIncompleteUploadDays = days
+	}
+
+	return rule, nil
+}
+
+// parseLifecycleFilter sets the prefix and tags of rule from the Filter, or deprecated Prefix, of entry.
+func parseLifecycleFilter(entry lifecycleRule, rule *model.LifecycleRule) error {
+	filter := entry.Filter
+	if filter == nil {
+		if entry.Prefix != nil {
+			rule.Prefix = *entry.Prefix
+		}
+		return nil
+	}
+
+	if entry.Prefix != nil {
+		return domain.ErrMalformedXML.WithMessage("A rule cannot specify both Prefix and Filter")
+	}
+
+	set := 0
+	for _, present := range []bool{filter.Prefix != nil, filter.Tag != nil, filter.And != nil} {
+		if present {
+			set++
+		}
+	}
+	if set > 1 {
+		return domain.ErrMalformedXML.WithMessage("Filter must specify at most one of Prefix, Tag or And")
+	}
+
+	if filter.ObjectSizeGreaterThan != nil || filter.ObjectSizeLessThan != nil ||
+		(filter.And != nil && (filter.And.ObjectSizeGreaterThan != nil || filter.And.ObjectSizeLessThan != nil)) {
+		return domain.ErrNotImplemented.WithMessage("Lifecycle object size filters are not supported")
+	}
+
+	var tags []tag
+	switch {
+	case filter.Prefix != nil:
+		rule.Prefix = *filter.Prefix
+	case filter.Tag != nil:
+		tags = []tag{*filter.Tag}
+	case filter.And != nil:
+		rule.Prefix = filter.And.Prefix
+		tags = filter.And.Tags
+	}
+
+	if len(tags) > 0 {
+		rule.Tags = make(map[string]string, len(tags))
+		for _, t := range tags {
+			if _, ok := rule.Tags[t.Key]; ok {
+				return domain.ErrInvalidRequest.WithMessage("Duplicate Tag Keys are not allowed.")
+			}
+			rule.Tags[t.Key] = t.Value
+		}
+	}
+
+	return nil
+}
+
+// positiveDays reads the number of days of a lifecycle action, which must be set and positive.
+// Returns domain.ErrInvalidArgument otherwise.
+func positiveDays(days *int, element string, action string) (int, error) {
+	if days == nil || *days <= 0 {
+		return 0, domain.ErrInvalidArgument.WithMessage("'%s' for %s action must be a positive integer", element, action)
+	}
+
+	return *days, nil
+}
+
+// formatLifecycleRule converts a model.LifecycleRule into a rule of a LifecycleConfiguration document,
+// always describing the objects it applies to with a Filter.
+func formatLifecycleRule(rule model.LifecycleRule) lifecycleRule {
+	entry := lifecycleRule{ID: rule.ID, Status: string(rule.Status), Filter: &lifecycleFilter{}}
+
+	tags := make([]tag, 0, len(rule.Tags))
+	for key, value := range rule.Tags {
+		tags = append(tags, tag{Key: key, Value: value})
+	}
+	slices.SortFunc(tags, func(a, b tag) int { return strings.Compare(a.Key, b.Key) })
+
+	switch {
+	case len(tags) == 0:
+		entry.Filter.Prefix = &rule.Prefix
+	case len(tags) == 1 && rule.Prefix == "":
+		entry.Filter.Tag = &tags[0]
+	default:
+		entry.Filter.And = &lifecycleAnd{Prefix: rule.Prefix, Tags: tags}
+	}
+
+	switch {
+	case rule.ExpirationDays > 0:
+		entry.Expiration = &lifecycleExpiration{Days: &rule.ExpirationDays}
+	case !rule.ExpirationDate.IsZero():
+		entry.Expiration = &lifecycleExpiration{Date: rule.ExpirationDate.UTC().Format(timeFormat)}
+	case rule.ExpiredObjectDeleteMarker:
+		entry.Expiration = &lifecycleExpiration{ExpiredObjectDeleteMarker: &rule.ExpiredObjectDeleteMarker}
+	}
+
+	if rule.NoncurrentDays > 0 {
+		entry.NoncurrentVersionExpiration = &noncurrentVersionExpiration{NoncurrentDays: &rule.NoncurrentDays}
+		if rule.NewerNoncurrentVersions > 0 {
+			entry.NoncurrentVersionExpiration.NewerNoncurrentVersions = &rule.NewerNoncurrentVersions
+		}
+	}
+
+	if rule.AbortIncompleteUploadDays > 0 {
+		entry.AbortIncompleteMultipartUpload = &abortIncompleteMultipartUpload{DaysAfterInitiation: &rule.AbortIncompleteUploadDays}
+	}
+
+	return entry
+}
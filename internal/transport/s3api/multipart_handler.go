@@ -0,0 +1,288 @@
+package s3api
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxListParts is the maximum number of parts or uploads returned in a single listing page.
+const maxListParts = 1000
+
+// MultipartHandler handles S3 multipart upload requests.
+type MultipartHandler struct {
+	service domain.MultipartService
+}
+
+// NewMultipartHandler creates a new MultipartHandler with the given MultipartService.
+func NewMultipartHandler(service domain.MultipartService) *MultipartHandler {
+	return &MultipartHandler{service: service}
+}
+
+// Create handles CreateMultipartUpload requests ("POST /{bucket}/{key}?uploads").
+// The Content-Type, x-amz-meta-* and system metadata headers are stored with the final object.
+// Returns HTTP 200 OK with an InitiateMultipartUploadResult document holding the upload ID.
+func (mh *MultipartHandler) Create(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+
+	uploadID, err := mh.service.Create(bucketName, key, c.GetHeader("Content-Type"), model.ParseMetadata(c.Request.Header))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.XML(c, http.StatusOK, initiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   bucketName,
+		Key:      key,
+		UploadID: uploadID,
+	})
+}
+
+// UploadPart handles UploadPart requests ("PUT /{bucket}/{key}?partNumber=N&uploadId=ID"),
+// and UploadPartCopy requests when the x-amz-copy-source header is set.
+// Returns HTTP 200 OK with the part ETag.
+func (mh *MultipartHandler) UploadPart(c *gin.Context) {
+	if c.GetHeader("x-amz-copy-source") != "" {
+		mh.uploadPartCopy(c)
+		return
+	}
+
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+
+	partNumber, err := parsePartNumber(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	etag, err := mh.service.UploadPart(bucketName, key, c.Query("uploadId"), partNumber, requestBody(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("ETag", quoteETag(etag))
+	c.Status(http.StatusOK)
+}
+
+// uploadPartCopy handles UploadPartCopy requests, copying the part data from the object, or the
+// object version, named by x-amz-copy-source, optionally restricted to x-amz-copy-source-range,
+// if it meets the x-amz-copy-source-if-* conditions.
+// Returns HTTP 200 OK with a CopyPartResult document.
+func (mh *MultipartHandler) uploadPartCopy(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+
+	partNumber, err := parsePartNumber(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	sourceBucket, sourceKey, sourceVersionID, err := parseCopySource(c.GetHeader("x-amz-copy-source"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	sourceRange, err := parseCopySourceRange(c.GetHeader("x-amz-copy-source-range"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	part, err := mh.service.UploadPartCopy(
+		bucketName, key, c.Query("uploadId"), partNumber,
+		sourceBucket, sourceKey, sourceVersionID, sourceRange,
+		model.ParsePreconditions(c.Request.Header, "x-amz-copy-source-"),
+	)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if sourceVersionID != "" {
+		c.Header("x-amz-copy-source-version-id", sourceVersionID)
+	}
+
+	response.XML(c, http.StatusOK, copyPartResult{
+		Xmlns:        s3Namespace,
+		ETag:         quoteETag(part.ETag),
+		LastModified: part.LastModified.UTC().Format(timeFormat),
+	})
+}
+
+// ListParts handles ListParts requests ("GET /{bucket}/{key}?uploadId=ID").
+// It supports the part-number-marker and max-parts query parameters.
+// Returns HTTP 200 OK with a ListPartsResult document.
+func (mh *MultipartHandler) ListParts(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+	uploadID := c.Query("uploadId")
+
+	partNumberMarker, err := parseIntQuery(c, "part-number-marker", 0, model.MaxPartNumber)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	maxParts, err := parseIntQuery(c, "max-parts", maxListParts, maxListParts)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	listing, err := mh.service.ListParts(bucketName, key, uploadID, partNumberMarker, maxParts)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := listPartsResult{
+		Xmlns:            s3Namespace,
+		Bucket:           bucketName,
+		Key:              key,
+		UploadID:         uploadID,
+		Initiator:        defaultOwner,
+		Owner:            defaultOwner,
+		StorageClass:     "STANDARD",
+		PartNumberMarker: partNumberMarker,
+		MaxParts:         maxParts,
+		IsTruncated:      listing.IsTruncated,
+		Parts:            make([]partEntry, 0, len(listing.Parts)),
+	}
+
+	for _, part := range listing.Parts {
+		result.Parts = append(result.Parts, partEntry{
+			PartNumber:   part.PartNumber,
+			LastModified: part.LastModified.UTC().Format(timeFormat),
+			ETag:         quoteETag(part.ETag),
+			Size:         part.Size,
+		})
+		result.NextPartNumberMarker = part.PartNumber
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// ListUploads handles ListMultipartUploads requests ("GET /{bucket}?uploads").
+// It supports the prefix, key-marker, upload-id-marker and max-uploads query parameters.
+// Returns HTTP 200 OK with a ListMultipartUploadsResult document.
+func (mh *MultipartHandler) ListUploads(c *gin.Context) {
+	bucketName := c.Param("bucket")
+
+	maxUploads, err := parseIntQuery(c, "max-uploads", maxListParts, maxListParts)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	query := model.UploadQuery{
+		Prefix:         c.Query("prefix"),
+		KeyMarker:      c.Query("key-marker"),
+		UploadIDMarker: c.Query("upload-id-marker"),
+		MaxUploads:     maxUploads,
+	}
+
+	listing, err := mh.service.ListUploads(bucketName, query)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := listMultipartUploadsResult{
+		Xmlns:          s3Namespace,
+		Bucket:         bucketName,
+		KeyMarker:      query.KeyMarker,
+		UploadIDMarker: query.UploadIDMarker,
+		Prefix:         query.Prefix,
+		MaxUploads:     maxUploads,
+		IsTruncated:    listing.IsTruncated,
+		Uploads:        make([]uploadEntry, 0, len(listing.Uploads)),
+	}
+
+	for _, upload := range listing.Uploads {
+		result.Uploads = append(result.Uploads, uploadEntry{
+			Key:          upload.Key,
+			UploadID:     upload.UploadID,
+			Initiator:    defaultOwner,
+			Owner:        defaultOwner,
+			StorageClass: "STANDARD",
+			Initiated:    upload.CreatedAt.UTC().Format(timeFormat),
+		})
+	}
+
+	if listing.IsTruncated {
+		last := listing.Uploads[len(listing.Uploads)-1]
+		result.NextKeyMarker = last.Key
+		result.NextUploadIDMarker = last.UploadID
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// Complete handles CompleteMultipartUpload requests ("POST /{bucket}/{key}?uploadId=ID").
+// Returns HTTP 200 OK with a CompleteMultipartUploadResult document holding the object ETag,
+// and the version of the object in the x-amz-version-id header.
+func (mh *MultipartHandler) Complete(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+
+	var request completeMultipartUpload
+	if err := xml.NewDecoder(c.Request.Body).Decode(&request); err != nil {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	parts := make([]model.CompletedPart, 0, len(request.Parts))
+	for _, part := range request.Parts {
+		parts = append(parts, model.CompletedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	file, err := mh.service.Complete(bucketName, key, c.Query("uploadId"), parts)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.VersionHeader(c, &file)
+	response.XML(c, http.StatusOK, completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: objectLocation(c.Request.Host, bucketName, key),
+		Bucket:   bucketName,
+		Key:      key,
+		ETag:     quoteETag(file.ETag),
+	})
+}
+
+// Abort handles AbortMultipartUpload requests ("DELETE /{bucket}/{key}?uploadId=ID").
+// Returns HTTP 204 No Content on success.
+func (mh *MultipartHandler) Abort(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+
+	if err := mh.service.Abort(bucketName, key, c.Query("uploadId")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parsePartNumber reads the partNumber query parameter.
+func parsePartNumber(c *gin.Context) (int, error) {
+	partNumber, err := strconv.Atoi(c.Query("partNumber"))
+	if err != nil {
+		return 0, domain.ErrInvalidArgument.WithMessage("Part number must be an integer between 1 and %d, inclusive", model.MaxPartNumber)
+	}
+
+	return partNumber, nil
+}
@@ -0,0 +1,420 @@
+package s3api
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/middleware"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeleteRequestSize bounds the XML body of a DeleteObjects request:
+// 1000 keys of up to 1024 bytes each, and their markup.
+const maxDeleteRequestSize = 2 * 1024 * 1024
+
+// ObjectHandler handles S3 requests addressed to an object ("/{bucket}/{key}").
+type ObjectHandler struct {
+	service domain.FileService
+}
+
+// NewObjectHandler creates a new ObjectHandler with the given FileService.
+func NewObjectHandler(service domain.FileService) *ObjectHandler {
+	return &ObjectHandler{service: service}
+}
+
+// Put handles PutObject requests ("PUT /{bucket}/{key}").
+// The request body is streamed into storage as the object data, with the Content-Type header as its
+// content type, detected from the data when absent. The x-amz-meta-* headers and the Cache-Control,
+// Content-Disposition, Content-Encoding, Content-Language and Expires headers are stored as its
+// metadata, and the x-amz-tagging header as its tags. The conditional writes "If-None-Match: *"
+// (create only) and "If-Match: <etag>" (replace only that version) are supported.
+// Requests with the x-amz-copy-source header are CopyObject requests, handled by copyObject.
+// Returns HTTP 200 OK with the object ETag and version ID on success.
+func (oh *ObjectHandler) Put(c *gin.Context) {
+	if c.GetHeader("x-amz-copy-source") != "" {
+		oh.copyObject(c)
+		return
+	}
+
+	tags, err := parseTaggingHeader(c.GetHeader("x-amz-tagging"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+	options := model.PutOptions{
+		Preconditions: model.ParsePreconditions(c.Request.Header, ""),
+		ContentType:   c.GetHeader("Content-Type"),
+		Metadata:      model.ParseMetadata(c.Request.Header),
+		Tags:          tags,
+	}
+
+	file, err := oh.service.UploadWithOptions(bucketName, requestBody(c), key, options)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("ETag", quoteETag(file.ETag))
+	response.VersionHeader(c, &file)
+	c.Status(http.StatusOK)
+}
+
+// copyObject handles CopyObject requests ("PUT /{bucket}/{key}" with x-amz-copy-source), copying
+// the source object, or the source version given by the versionId of x-amz-copy-source, server-side.
+// x-amz-metadata-directive COPY (the default) keeps the source metadata,
+// and REPLACE stores the metadata and Content-Type headers of the request instead; likewise, x-amz-tagging-directive
+// COPY keeps the source tags and REPLACE stores the x-amz-tagging header. The x-amz-copy-source-if-* headers
+// are honored with 412 Precondition Failed.
+// Returns HTTP 200 OK with a CopyObjectResult document on success.
+func (oh *ObjectHandler) copyObject(c *gin.Context) {
+	sourceBucket, sourceKey, sourceVersionID, err := parseCopySource(c.GetHeader("x-amz-copy-source"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	metadataDirective, err := parseDirective(c, "x-amz-metadata-directive")
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	taggingDirective, err := parseDirective(c, "x-amz-tagging-directive")
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	tags, err := parseTaggingHeader(c.GetHeader("x-amz-tagging"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	options := model.CopyOptions{
+		SourceVersionID:   sourceVersionID,
+		SourceConditions:  model.ParsePreconditions(c.Request.Header, "x-amz-copy-source-"),
+		MetadataDirective: metadataDirective,
+		Metadata:          model.ParseMetadata(c.Request.Header),
+		ContentType:       c.GetHeader("Content-Type"),
+		TaggingDirective:  taggingDirective,
+		Tags:              tags,
+	}
+
+	file, err := oh.service.Copy(sourceBucket, sourceKey, c.Param("bucket"), objectKey(c), options)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if sourceVersionID != "" {
+		c.Header("x-amz-copy-source-version-id", sourceVersionID)
+	}
+	response.VersionHeader(c, &file)
+
+	response.XML(c, http.StatusOK, copyObjectResult{
+		Xmlns:        s3Namespace,
+		ETag:         quoteETag(file.ETag),
+		LastModified: file.LastModified.UTC().Format(timeFormat),
+	})
+}
+
+// Post handles browser-based POST Object uploads ("POST /{bucket}" multipart forms), whose
+// fields SigV4Middleware has already authenticated and checked against their policy.
+// The file is stored under the key field, with the Content-Type field as its content type, detected from
+// the data when absent, and the x-amz-meta-* and Cache-Control, Content-Disposition,
+// Content-Encoding, Content-Language and Expires fields as its metadata, and the tags of the Tagging
+// document in the tagging field.
+// Redirects with HTTP 303 See Other to success_action_redirect on success if set, and otherwise
+// returns the status in success_action_status: 200 OK, 201 Created with a PostResponse document,
+// or 204 No Content by default.
+func (oh *ObjectHandler) Post(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	form := middleware.PostForm(c)
+	if form == nil {
+		response.Error(c, domain.ErrMalformedPOSTRequest)
+		return
+	}
+
+	header := make(http.Header, len(form))
+	for name, value := range form {
+		header.Set(name, value)
+	}
+
+	var tags map[string]string
+	if form["tagging"] != "" {
+		parsed, err := parseTagging([]byte(form["tagging"]))
+		if err != nil {
+			response.Error(c, err)
+			return
+		}
+		tags = parsed
+	}
+
+	key := form["key"]
+	options := model.PutOptions{ContentType: form["content-type"], Metadata: model.ParseMetadata(header), Tags: tags}
+	file, err := oh.service.UploadWithOptions(bucketName, requestBody(c), key, options)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	location := objectLocation(c.Request.Host, bucketName, key)
+	c.Header("ETag", quoteETag(file.ETag))
+	response.VersionHeader(c, &file)
+
+	if redirect, err := url.Parse(form["success_action_redirect"]); err == nil && redirect.IsAbs() {
+		query := redirect.Query()
+		query.Set("bucket", bucketName)
+		query.Set("key", key)
+		query.Set("etag", quoteETag(file.ETag))
+		redirect.RawQuery = query.Encode()
+
+		c.Redirect(http.StatusSeeOther, redirect.String())
+		return
+	}
+
+	c.Header("Location", location)
+	switch form["success_action_status"] {
+	case "200":
+		c.Status(http.StatusOK)
+	case "201":
+		response.XML(c, http.StatusCreated, postResponse{
+			Location: location,
+			Bucket:   bucketName,
+			Key:      key,
+			ETag:     quoteETag(file.ETag),
+		})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// Get handles GetObject requests ("GET /{bucket}/{key}"), reading the current version of the object,
+// or the version given by the versionId query parameter. A single byte range can be requested with the Range header, or a part of a multipart
+// object with the partNumber query parameter. The If-Match, If-None-Match, If-Modified-Since
+// and If-Unmodified-Since headers are honored with 412 Precondition Failed and 304 Not Modified.
+// Returns HTTP 200 OK with the object data, streamed from storage, or HTTP 206 Partial Content
+// with the requested range on success, with the number of tags of the version in x-amz-tagging-count.
+func (oh *ObjectHandler) Get(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+	options := model.ParseGetOptions(c.Request.Header, c.Request.URL.Query())
+
+	fileData, fileModel, selected, err := oh.service.GetRange(bucketName, key, options)
+	if err != nil {
+		response.ReadError(c, err, &fileModel)
+		return
+	}
+	defer fileData.Close()
+
+	response.ObjectHeaders(c, &fileModel, selected, options.PartNumber)
+	if len(fileModel.Tags) > 0 {
+		c.Header("x-amz-tagging-count", strconv.Itoa(len(fileModel.Tags)))
+	}
+	if selected == nil {
+		c.DataFromReader(http.StatusOK, fileModel.Size, fileModel.ContentType, fileData, nil)
+		return
+	}
+
+	c.DataFromReader(http.StatusPartialContent, selected.Length(), fileModel.ContentType, fileData, nil)
+}
+
+// Head handles HeadObject requests ("HEAD /{bucket}/{key}").
+// It accepts the same headers and query parameters as Get and returns the same status
+// and headers without a body, reading only the object metadata.
+func (oh *ObjectHandler) Head(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+	options := model.ParseGetOptions(c.Request.Header, c.Request.URL.Query())
+
+	fileModel, selected, err := oh.service.Head(bucketName, key, options)
+	if err != nil {
+		response.ReadError(c, err, &fileModel)
+		return
+	}
+
+	response.ObjectHeaders(c, &fileModel, selected, options.PartNumber)
+	if selected == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	c.Status(http.StatusPartialContent)
+}
+
+// Remove handles DeleteObject requests ("DELETE /{bucket}/{key}"). With the versionId query parameter
+// that version is deleted permanently; otherwise a versioned bucket gets a delete marker.
+// As in S3, deleting a key that does not exist succeeds.
+// Returns HTTP 204 No Content on success, with the x-amz-version-id of the deleted version or of the
+// created delete marker, and x-amz-delete-marker when the version deleted or created is a delete marker.
+func (oh *ObjectHandler) Remove(c *gin.Context) {
+	bucketName := c.Param("bucket")
+	key := objectKey(c)
+
+	deleted, err := oh.service.RemoveVersion(bucketName, key, c.Query("versionId"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if versionID := cmp.Or(deleted.DeleteMarkerVersionID, deleted.VersionID); versionID != "" {
+		c.Header("x-amz-version-id", versionID)
+	}
+	if deleted.DeleteMarker {
+		c.Header("x-amz-delete-marker", "true")
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteObjects handles multi-object delete requests ("POST /{bucket}?delete"), deleting up to
+// 1000 keys, or versions of keys, listed in the XML body at once. The body is checked against its Content-MD5 header when set.
+// In Quiet mode only the keys that could not be deleted are reported.
+// Returns HTTP 200 OK with a DeleteResult document listing the Deleted and Error entries.
+func (oh *ObjectHandler) DeleteObjects(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(requestBody(c), maxDeleteRequestSize+1))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+	if len(body) > maxDeleteRequestSize {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	if err := verifyContentMD5(c.GetHeader("Content-MD5"), body); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	var request deleteRequest
+	if err := xml.Unmarshal(body, &request); err != nil || len(request.Objects) == 0 || len(request.Objects) > model.MaxDeleteObjects {
+		response.Error(c, domain.ErrMalformedXML)
+		return
+	}
+
+	objects := make([]model.ObjectIdentifier, 0, len(request.Objects))
+	for _, object := range request.Objects {
+		objects = append(objects, model.ObjectIdentifier{Key: object.Key, VersionID: object.VersionID})
+	}
+
+	results, err := oh.service.RemoveObjects(c.Param("bucket"), objects)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	result := deleteResult{Xmlns: s3Namespace}
+	for _, deleted := range results {
+		if deleted.Err == nil {
+			if !request.Quiet {
+				result.Deleted = append(result.Deleted, deletedEntry{
+					Key:                   deleted.Key,
+					VersionID:             deleted.VersionID,
+					DeleteMarker:          deleted.DeleteMarker,
+					DeleteMarkerVersionID: deleted.DeleteMarkerVersionID,
+				})
+			}
+			continue
+		}
+
+		var domainErr *domain.Error
+		if !errors.As(deleted.Err, &domainErr) {
+			domainErr = &domain.Error{Code: "InternalError", Message: "We encountered an internal error. Please try again."}
+		}
+		result.Errors = append(result.Errors, deleteError{
+			Key:       deleted.Key,
+			VersionID: deleted.VersionID,
+			Code:      domainErr.Code,
+			Message:   domainErr.Message,
+		})
+	}
+
+	response.XML(c, http.StatusOK, result)
+}
+
+// verifyContentMD5 checks body against a Content-MD5 header, the base64 MD5 digest of the body.
+// An empty header is not checked.
+// Returns domain.ErrInvalidDigest if the header is not a valid digest, or domain.ErrBadDigest if it does not match.
+func verifyContentMD5(header string, body []byte) error {
+	if header == "" {
+		return nil
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(header)
+	if err != nil || len(expected) != md5.Size {
+		return domain.ErrInvalidDigest
+	}
+
+	actual := md5.Sum(body)
+	if !bytes.Equal(expected, actual[:]) {
+		return domain.ErrBadDigest
+	}
+
+	return nil
+}
+
+// objectKey returns the object key from the "*key" path parameter, without the leading slash.
+func objectKey(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("key"), "/")
+}
+
+// requestBody returns the request body for streaming into storage. Read failures are reported
+// as domain.ErrIncompleteBody, unless the body reader failed with a domain error
+// (e.g. a payload signature mismatch).
+func requestBody(c *gin.Context) io.Reader {
+	return &bodyReader{body: c.Request.Body}
+}
+
+// bodyReader translates the read errors of a request body into S3 errors.
+type bodyReader struct {
+	body io.Reader
+}
+
+// Read reads from the request body, replacing any error other than io.EOF
+// and domain errors with domain.ErrIncompleteBody.
+func (br *bodyReader) Read(p []byte) (int, error) {
+	n, err := br.body.Read(p)
+	if err == nil || errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return n, err
+	}
+
+	return n, domain.ErrIncompleteBody
+}
+
+// objectLocation returns the URL of the object key of bucket on host, with every segment of the key escaped.
+func objectLocation(host string, bucket string, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return fmt.Sprintf("http://%s/%s/%s", host, bucket, strings.Join(segments, "/"))
+}
+
+// quoteETag wraps an ETag in double quotes, as S3 returns it.
+func quoteETag(etag string) string {
+	return fmt.Sprintf(`"%s"`, etag)
+}
@@ -0,0 +1,194 @@
+package s3api
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/response"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTaggingRequestSize bounds the XML body of a PutObjectTagging or PutBucketTagging request:
+// 50 tags with the longest keys and values, and their markup.
+const maxTaggingRequestSize = 256 * 1024
+
+// TaggingHandler handles S3 requests addressed to the tag set of an object ("/{bucket}/{key}?tagging")
+// or of a bucket ("/{bucket}?tagging").
+type TaggingHandler struct {
+	bucketService domain.BucketService
+	fileService   domain.FileService
+}
+
+// NewTaggingHandler creates a new TaggingHandler with the given BucketService and FileService.
+func NewTaggingHandler(bucketService domain.BucketService, fileService domain.FileService) *TaggingHandler {
+	return &TaggingHandler{bucketService: bucketService, fileService: fileService}
+}
+
+// PutObject handles PutObjectTagging requests ("PUT /{bucket}/{key}?tagging"), replacing the tag set
+// of the current version of the object, or of the version given by the versionId query parameter,
+// with the TagSet of the Tagging body.
+// Returns HTTP 200 OK with the x-amz-version-id of the tagged version on success.
+func (th *TaggingHandler) PutObject(c *gin.Context) {
+	tags, err := readTagging(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	file, err := th.fileService.PutTagging(c.Param("bucket"), objectKey(c), c.Query("versionId"), tags)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.VersionHeader(c, &file)
+	c.Status(http.StatusOK)
+}
+
+// GetObject handles GetObjectTagging requests ("GET /{bucket}/{key}?tagging") for the current version
+// of the object, or the version given by the versionId query parameter.
+// Returns HTTP 200 OK with a Tagging document, empty if the version has no tags, and its x-amz-version-id.
+func (th *TaggingHandler) GetObject(c *gin.Context) {
+	file, err := th.fileService.GetTagging(c.Param("bucket"), objectKey(c), c.Query("versionId"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.VersionHeader(c, &file)
+	response.XML(c, http.StatusOK, formatTagging(file.Tags))
+}
+
+// RemoveObject handles DeleteObjectTagging requests ("DELETE /{bucket}/{key}?tagging") for the current
+// version of the object, or the version given by the versionId query parameter.
+// Returns HTTP 204 No Content with the x-amz-version-id of the untagged version on success.
+func (th *TaggingHandler) RemoveObject(c *gin.Context) {
+	file, err := th.fileService.RemoveTagging(c.Param("bucket"), objectKey(c), c.Query("versionId"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.VersionHeader(c, &file)
+	c.Status(http.StatusNoContent)
+}
+
+// PutBucket handles PutBucketTagging requests ("PUT /{bucket}?tagging"), replacing the tag set
+// of the bucket with the TagSet of the Tagging body.
+// Returns HTTP 204 No Content on success.
+func (th *TaggingHandler) PutBucket(c *gin.Context) {
+	tags, err := readTagging(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if err := th.bucketService.PutTagging(c.Param("bucket"), tags); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetBucket handles GetBucketTagging requests ("GET /{bucket}?tagging").
+// Returns HTTP 200 OK with a Tagging document, or a NoSuchTagSet error if the bucket has no tags.
+func (th *TaggingHandler) GetBucket(c *gin.Context) {
+	tags, err := th.bucketService.GetTagging(c.Param("bucket"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.XML(c, http.StatusOK, formatTagging(tags))
+}
+
+// RemoveBucket handles DeleteBucketTagging requests ("DELETE /{bucket}?tagging").
+// Returns HTTP 204 No Content on success, whether or not the bucket had tags.
+func (th *TaggingHandler) RemoveBucket(c *gin.Context) {
+	if err := th.bucketService.RemoveTagging(c.Param("bucket")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// readTagging reads the tag set of the Tagging body of a request, checked against its Content-MD5
+// header when set.
+// Returns the errors of parseTagging and verifyContentMD5.
+func readTagging(c *gin.Context) (map[string]string, error) {
+	body, err := io.ReadAll(io.LimitReader(requestBody(c), maxTaggingRequestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxTaggingRequestSize {
+		return nil, domain.ErrMalformedXML
+	}
+
+	if err := verifyContentMD5(c.GetHeader("Content-MD5"), body); err != nil {
+		return nil, err
+	}
+
+	return parseTagging(body)
+}
+
+// parseTagging reads the tag set of a Tagging document, the body of a tagging request or the
+// tagging field of a POST upload form.
+// Returns domain.ErrMalformedXML if the document is not valid, or domain.ErrInvalidTag if it repeats a key.
+func parseTagging(document []byte) (map[string]string, error) {
+	var request tagging
+	if err := xml.Unmarshal(document, &request); err != nil {
+		return nil, domain.ErrMalformedXML
+	}
+
+	tags := make(map[string]string, len(request.TagSet))
+	for _, t := range request.TagSet {
+		if _, ok := tags[t.Key]; ok {
+			return nil, domain.ErrInvalidTag.WithMessage("Cannot provide multiple Tags with the same key")
+		}
+		tags[t.Key] = t.Value
+	}
+
+	return tags, nil
+}
+
+// parseTaggingHeader reads the tag set of an x-amz-tagging header, URL query parameters such as
+// "project=s3ego&team=storage". An empty header holds no tags.
+// Returns domain.ErrInvalidArgument if the header is not URL-encoded, or domain.ErrInvalidTag if it repeats a key.
+func parseTaggingHeader(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	query, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, domain.ErrInvalidArgument.WithMessage("The header 'x-amz-tagging' shall be encoded as UTF-8 then URLEncoded URL query parameters without tag name duplicates.")
+	}
+
+	tags := make(map[string]string, len(query))
+	for key, values := range query {
+		if len(values) > 1 {
+			return nil, domain.ErrInvalidTag.WithMessage("Cannot provide multiple Tags with the same key")
+		}
+		tags[key] = values[0]
+	}
+
+	return tags, nil
+}
+
+// formatTagging converts a tag set into a Tagging document, with its tags sorted by key.
+func formatTagging(tags map[string]string) tagging {
+	result := tagging{Xmlns: s3Namespace, TagSet: make([]tag, 0, len(tags))}
+	for key, value := range tags {
+		result.TagSet = append(result.TagSet, tag{Key: key, Value: value})
+	}
+	slices.SortFunc(result.TagSet, func(a, b tag) int { return strings.Compare(a.Key, b.Key) })
+
+	return result
+}
@@ -0,0 +1,360 @@
+package s3api
+
+import "encoding/xml"
+
+// s3Namespace is the XML namespace used by every S3 response document.
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// timeFormat is the ISO 8601 timestamp layout used in S3 XML documents.
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+// region is the AWS region reported for every bucket.
+const region = "us-east-1"
+
+// defaultOwner is the owner reported for every bucket and object of the emulator.
+var defaultOwner = owner{
+	ID:          "75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a",
+	DisplayName: "s3ego",
+}
+
+// owner identifies the account owning a resource.
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+// listAllMyBucketsResult is the XML document returned by ListBuckets.
+type listAllMyBucketsResult struct {
+	XMLName           xml.Name      `xml:"ListAllMyBucketsResult"`
+	Xmlns             string        `xml:"xmlns,attr"`
+	Owner             owner         `xml:"Owner"`
+	Buckets           []bucketEntry `xml:"Buckets>Bucket"`
+	ContinuationToken string        `xml:"ContinuationToken,omitempty"`
+	Prefix            string        `xml:"Prefix,omitempty"`
+}
+
+// bucketEntry describes a single bucket in a ListBuckets result.
+type bucketEntry struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+	BucketRegion string `xml:"BucketRegion"`
+}
+
+// listBucketResult is the XML document returned by ListObjectsV2.
+type listBucketResult struct {
+	XMLName               xml.Name        `xml:"ListBucketResult"`
+	Xmlns                 string          `xml:"xmlns,attr"`
+	Name                  string          `xml:"Name"`
+	Prefix                string          `xml:"Prefix"`
+	Delimiter             string          `xml:"Delimiter,omitempty"`
+	MaxKeys               int             `xml:"MaxKeys"`
+	KeyCount              int             `xml:"KeyCount"`
+	IsTruncated           bool            `xml:"IsTruncated"`
+	ContinuationToken     string          `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string          `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string          `xml:"StartAfter,omitempty"`
+	EncodingType          string          `xml:"EncodingType,omitempty"`
+	Contents              []objectContent `xml:"Contents"`
+	CommonPrefixes        []commonPrefix  `xml:"CommonPrefixes"`
+}
+
+// listBucketResultV1 is the XML document returned by the original ListObjects operation.
+type listBucketResultV1 struct {
+	XMLName        xml.Name        `xml:"ListBucketResult"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	Name           string          `xml:"Name"`
+	Prefix         string          `xml:"Prefix"`
+	Marker         string          `xml:"Marker"`
+	NextMarker     string          `xml:"NextMarker,omitempty"`
+	Delimiter      string          `xml:"Delimiter,omitempty"`
+	MaxKeys        int             `xml:"MaxKeys"`
+	IsTruncated    bool            `xml:"IsTruncated"`
+	EncodingType   string          `xml:"EncodingType,omitempty"`
+	Contents       []objectContent `xml:"Contents"`
+	CommonPrefixes []commonPrefix  `xml:"CommonPrefixes"`
+}
+
+// objectContent describes a single object entry in a listing.
+type objectContent struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// commonPrefix describes a rolled up "folder" entry in a listing.
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// listVersionsResult is the XML document returned by ListObjectVersions.
+type listVersionsResult struct {
+	XMLName             xml.Name `xml:"ListVersionsResult"`
+	Xmlns               string   `xml:"xmlns,attr"`
+	Name                string   `xml:"Name"`
+	Prefix              string   `xml:"Prefix"`
+	KeyMarker           string   `xml:"KeyMarker"`
+	VersionIDMarker     string   `xml:"VersionIdMarker"`
+	NextKeyMarker       string   `xml:"NextKeyMarker,omitempty"`
+	NextVersionIDMarker string   `xml:"NextVersionIdMarker,omitempty"`
+	Delimiter           string   `xml:"Delimiter,omitempty"`
+	MaxKeys             int      `xml:"MaxKeys"`
+	IsTruncated         bool     `xml:"IsTruncated"`
+	EncodingType        string   `xml:"EncodingType,omitempty"`
+	Versions            []versionEntry
+	CommonPrefixes      []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// versionEntry describes a single object version in a ListObjectVersions result. Its element is
+// named by XMLName, Version or DeleteMarker, so both kinds of entries keep their listing order.
+type versionEntry struct {
+	XMLName      xml.Name
+	Key          string `xml:"Key"`
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool   `xml:"IsLatest"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag,omitempty"`
+	Size         *int64 `xml:"Size,omitempty"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+	Owner        owner  `xml:"Owner"`
+}
+
+// versioningConfiguration is the XML document of PutBucketVersioning and GetBucketVersioning.
+type versioningConfiguration struct {
+	XMLName   xml.Name `xml:"VersioningConfiguration"`
+	Xmlns     string   `xml:"xmlns,attr,omitempty"`
+	Status    string   `xml:"Status,omitempty"`
+	MfaDelete string   `xml:"MfaDelete,omitempty"`
+}
+
+// lifecycleConfiguration is the XML document of PutBucketLifecycleConfiguration and GetBucketLifecycleConfiguration.
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Xmlns   string          `xml:"xmlns,attr,omitempty"`
+	Rules   []lifecycleRule `xml:"Rule"`
+}
+
+// lifecycleRule is a rule of a lifecycle configuration. The deprecated Prefix element is accepted
+// in place of a Filter, and transitions are only decoded to be rejected.
+type lifecycleRule struct {
+	ID                             string                          `xml:"ID,omitempty"`
+	Prefix                         *string                         `xml:"Prefix"`
+	Filter                         *lifecycleFilter                `xml:"Filter"`
+	Status                         string                          `xml:"Status"`
+	Expiration                     *lifecycleExpiration            `xml:"Expiration"`
+	NoncurrentVersionExpiration    *noncurrentVersionExpiration    `xml:"NoncurrentVersionExpiration"`
+	AbortIncompleteMultipartUpload *abortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload"`
+	Transitions                    []struct{}                      `xml:"Transition"`
+	NoncurrentVersionTransitions   []struct{}                      `xml:"NoncurrentVersionTransition"`
+}
+
+// lifecycleFilter selects the objects a lifecycle rule applies to, by a single Prefix or Tag,
+// or by their conjunction in And.
+type lifecycleFilter struct {
+	Prefix                *string       `xml:"Prefix"`
+	Tag                   *tag          `xml:"Tag"`
+	And                   *lifecycleAnd `xml:"And"`
+	ObjectSizeGreaterThan *int64        `xml:"ObjectSizeGreaterThan"`
+	ObjectSizeLessThan    *int64        `xml:"ObjectSizeLessThan"`
+}
+
+// lifecycleAnd combines the prefix and tags an object must all match in a lifecycle filter.
+type lifecycleAnd struct {
+	Prefix                string `xml:"Prefix,omitempty"`
+	Tags                  []tag  `xml:"Tag"`
+	ObjectSizeGreaterThan *int64 `xml:"ObjectSizeGreaterThan"`
+	ObjectSizeLessThan    *int64 `xml:"ObjectSizeLessThan"`
+}
+
+// tagging is the XML document of the PutObjectTagging, GetObjectTagging, PutBucketTagging
+// and GetBucketTagging requests.
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  []tag    `xml:"TagSet>Tag"`
+}
+
+// tag is a key and value pair of a tag set or tag filter.
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+// corsConfiguration is the XML document of the PutBucketCors and GetBucketCors requests.
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Xmlns   string     `xml:"xmlns,attr,omitempty"`
+	Rules   []corsRule `xml:"CORSRule"`
+}
+
+// corsRule is a single rule of a CORS configuration.
+type corsRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  *int     `xml:"MaxAgeSeconds"`
+}
+
+// lifecycleExpiration is the Expiration action of a lifecycle rule, set by exactly one of its elements.
+type lifecycleExpiration struct {
+	Days                      *int   `xml:"Days"`
+	Date                      string `xml:"Date,omitempty"`
+	ExpiredObjectDeleteMarker *bool  `xml:"ExpiredObjectDeleteMarker"`
+}
+
+// noncurrentVersionExpiration is the NoncurrentVersionExpiration action of a lifecycle rule.
+type noncurrentVersionExpiration struct {
+	NoncurrentDays          *int `xml:"NoncurrentDays"`
+	NewerNoncurrentVersions *int `xml:"NewerNoncurrentVersions"`
+}
+
+// abortIncompleteMultipartUpload is the AbortIncompleteMultipartUpload action of a lifecycle rule.
+type abortIncompleteMultipartUpload struct {
+	DaysAfterInitiation *int `xml:"DaysAfterInitiation"`
+}
+
+// initiateMultipartUploadResult is the XML document returned by CreateMultipartUpload.
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+// completeMultipartUpload is the XML request body of CompleteMultipartUpload.
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+// completedPart identifies an uploaded part in a CompleteMultipartUpload request.
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// completeMultipartUploadResult is the XML document returned by CompleteMultipartUpload.
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// postResponse is the XML document returned by a POST Object upload with success_action_status 201.
+type postResponse struct {
+	XMLName  xml.Name `xml:"PostResponse"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// deleteRequest is the XML body of a DeleteObjects request.
+type deleteRequest struct {
+	XMLName xml.Name           `xml:"Delete"`
+	Quiet   bool               `xml:"Quiet"`
+	Objects []objectIdentifier `xml:"Object"`
+}
+
+// objectIdentifier is an object listed in a DeleteObjects request.
+type objectIdentifier struct {
+	Key       string `xml:"Key"`
+	VersionID string `xml:"VersionId"`
+}
+
+// deleteResult is the XML document returned by DeleteObjects.
+type deleteResult struct {
+	XMLName xml.Name       `xml:"DeleteResult"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	Deleted []deletedEntry `xml:"Deleted"`
+	Errors  []deleteError  `xml:"Error"`
+}
+
+// deletedEntry is an object deleted by DeleteObjects.
+type deletedEntry struct {
+	Key                   string `xml:"Key"`
+	VersionID             string `xml:"VersionId,omitempty"`
+	DeleteMarker          bool   `xml:"DeleteMarker,omitempty"`
+	DeleteMarkerVersionID string `xml:"DeleteMarkerVersionId,omitempty"`
+}
+
+// deleteError is an object DeleteObjects failed to delete.
+type deleteError struct {
+	Key       string `xml:"Key"`
+	VersionID string `xml:"VersionId,omitempty"`
+	Code      string `xml:"Code"`
+	Message   string `xml:"Message"`
+}
+
+// copyObjectResult is the XML document returned by CopyObject.
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"`
+}
+
+// copyPartResult is the XML document returned by UploadPartCopy.
+type copyPartResult struct {
+	XMLName      xml.Name `xml:"CopyPartResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	ETag         string   `xml:"ETag"`
+	LastModified string   `xml:"LastModified"`
+}
+
+// listPartsResult is the XML document returned by ListParts.
+type listPartsResult struct {
+	XMLName              xml.Name    `xml:"ListPartsResult"`
+	Xmlns                string      `xml:"xmlns,attr"`
+	Bucket               string      `xml:"Bucket"`
+	Key                  string      `xml:"Key"`
+	UploadID             string      `xml:"UploadId"`
+	Initiator            owner       `xml:"Initiator"`
+	Owner                owner       `xml:"Owner"`
+	StorageClass         string      `xml:"StorageClass"`
+	PartNumberMarker     int         `xml:"PartNumberMarker"`
+	NextPartNumberMarker int         `xml:"NextPartNumberMarker"`
+	MaxParts             int         `xml:"MaxParts"`
+	IsTruncated          bool        `xml:"IsTruncated"`
+	Parts                []partEntry `xml:"Part"`
+}
+
+// partEntry describes a single uploaded part in a ListParts result.
+type partEntry struct {
+	PartNumber   int    `xml:"PartNumber"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+}
+
+// listMultipartUploadsResult is the XML document returned by ListMultipartUploads.
+type listMultipartUploadsResult struct {
+	XMLName            xml.Name      `xml:"ListMultipartUploadsResult"`
+	Xmlns              string        `xml:"xmlns,attr"`
+	Bucket             string        `xml:"Bucket"`
+	KeyMarker          string        `xml:"KeyMarker"`
+	UploadIDMarker     string        `xml:"UploadIdMarker"`
+	NextKeyMarker      string        `xml:"NextKeyMarker"`
+	NextUploadIDMarker string        `xml:"NextUploadIdMarker"`
+	Prefix             string        `xml:"Prefix"`
+	MaxUploads         int           `xml:"MaxUploads"`
+	IsTruncated        bool          `xml:"IsTruncated"`
+	Uploads            []uploadEntry `xml:"Upload"`
+}
+
+// uploadEntry describes a single in-progress upload in a ListMultipartUploads result.
+type uploadEntry struct {
+	Key          string `xml:"Key"`
+	UploadID     string `xml:"UploadId"`
+	Initiator    owner  `xml:"Initiator"`
+	Owner        owner  `xml:"Owner"`
+	StorageClass string `xml:"StorageClass"`
+	Initiated    string `xml:"Initiated"`
+}
@@ -10,31 +10,234 @@
 // that require an S3-like interface without needing access to actual cloud storage.
 //
 // Note: The project is still under active development, and some features are yet to be added,
-// such as advanced bucket policies, authentication, and multipart uploads.
+// such as advanced bucket policies.
 package s3ego
 
 import (
+	"database/sql"
+	"net/http"
+	"slices"
+	"time"
+
 	"github.com/bonifacio-pedro/s3ego/internal/app"
+	"github.com/bonifacio-pedro/s3ego/internal/clock"
 	"github.com/bonifacio-pedro/s3ego/internal/config"
 	"github.com/bonifacio-pedro/s3ego/internal/domain"
+	"github.com/bonifacio-pedro/s3ego/internal/model"
+	"github.com/bonifacio-pedro/s3ego/internal/transport/middleware"
+)
+
+// Models returned and accepted by the bucket and file services, re-exported so
+// library users can name them.
+type (
+	Bucket           = model.Bucket
+	File             = model.File
+	ListQuery        = model.ListQuery
+	Listing          = model.Listing
+	BucketQuery      = model.BucketQuery
+	BucketListing    = model.BucketListing
+	Part             = model.Part
+	CompletedPart    = model.CompletedPart
+	UploadQuery      = model.UploadQuery
+	ByteRange        = model.ByteRange
+	RangeRequest     = model.RangeRequest
+	Preconditions    = model.Preconditions
+	GetOptions       = model.GetOptions
+	PutOptions       = model.PutOptions
+	Metadata         = model.Metadata
+	CopyOptions      = model.CopyOptions
+	Directive        = model.Directive
+	ObjectIdentifier = model.ObjectIdentifier
+	DeleteResult     = model.DeleteResult
+	VersioningStatus = model.VersioningStatus
+	VersionQuery     = model.VersionQuery
+	VersionListing   = model.VersionListing
+	LifecycleRule    = model.LifecycleRule
+	LifecycleStatus  = model.LifecycleStatus
+	LifecycleRun     = model.LifecycleRun
+	CORSRule         = model.CORSRule
+)
+
+// Directives of CopyOptions, re-exported so library users can choose whether a copy
+// keeps the metadata of its source.
+const (
+	DirectiveCopy    = model.DirectiveCopy
+	DirectiveReplace = model.DirectiveReplace
+)
+
+// Versioning states of a bucket and the version ID of unversioned objects, re-exported so
+// library users can enable versioning and address versions.
+const (
+	VersioningEnabled   = model.VersioningEnabled
+	VersioningSuspended = model.VersioningSuspended
+	NullVersionID       = model.NullVersionID
 )
 
+// Statuses of a LifecycleRule, re-exported so library users can configure bucket lifecycles.
+const (
+	LifecycleEnabled  = model.LifecycleEnabled
+	LifecycleDisabled = model.LifecycleDisabled
+)
+
+// Clock tells the emulator the current time, and Timer cancels a call scheduled on it. FakeClock is
+// a Clock tests can set and advance, created with NewFakeClock and configured with WithClock.
+type (
+	Clock     = clock.Clock
+	Timer     = clock.Timer
+	FakeClock = clock.Fake
+)
+
+// NewFakeClock returns a FakeClock stopped at now, which only moves when set or advanced.
+func NewFakeClock(now time.Time) *FakeClock {
+	return clock.NewFake(now)
+}
+
 // S3EGO is the main struct exposing the bucket and file services for the emulator.
 type S3EGO struct {
-	Bucket domain.BucketService
-	File   domain.FileService
+	Bucket    domain.BucketService
+	File      domain.FileService
+	Multipart domain.MultipartService
+	Lifecycle domain.LifecycleService
+	CORS      domain.CORSService
+
+	app         *app.App
+	db          *sql.DB
+	credentials map[string]string
+	clock       clock.Clock
+}
+
+// Option customizes the emulator created by Start.
+type Option func(*app.Config)
+
+// WithMinPartSize sets the minimum size in bytes of every multipart upload part except the last one.
+// S3 requires 5 MiB; tests can lower it to exercise multipart uploads with small payloads.
+func WithMinPartSize(size int64) Option {
+	return func(config *app.Config) {
+		config.MinPartSize = size
+	}
+}
+
+// WithCredentials adds an access key ID and secret access key pair accepted by the S3 API.
+// Once any pair is configured, S3 API requests must be signed with AWS Signature Version 4.
+func WithCredentials(accessKeyID string, secretAccessKey string) Option {
+	return func(config *app.Config) {
+		if config.Credentials == nil {
+			config.Credentials = make(map[string]string)
+		}
+		config.Credentials[accessKeyID] = secretAccessKey
+	}
+}
+
+// WithDataSource stores the emulator data in the SQLite database file at path, created if missing,
+// so it survives restarts. Object data is kept in a "<path>.blobs" directory unless WithBlobDir
+// is given. Without this option all data is kept in memory.
+func WithDataSource(path string) Option {
+	return func(config *app.Config) {
+		config.DataSource = path
+	}
+}
+
+// WithBlobDir stores the data of objects and multipart upload parts as files under dir,
+// while the SQLite database only keeps their metadata.
+func WithBlobDir(dir string) Option {
+	return func(config *app.Config) {
+		config.BlobDir = dir
+	}
 }
 
-// Start initializes the emulator by configuring the in-memory database and
+// WithClock sets the clock the emulator reads the current time from: it stamps the creation and
+// LastModified times of buckets, objects and multipart uploads, the Date header of S3 API responses,
+// and is the time signed requests, presigned URLs and POST policies are checked against.
+// Passing a FakeClock makes these timestamps deterministic, and lets tests expire objects by advancing
+// it instead of waiting days: moving it forward by LifecycleInterval runs a lifecycle pass, which has
+// finished when Advance or Set returns.
+func WithClock(c Clock) Option {
+	return func(config *app.Config) {
+		config.Clock = c
+	}
+}
+
+// WithLifecycleInterval sets how often, measured on the configured clock, the lifecycle rules of every
+// bucket are applied in the background. It defaults to one hour; zero or a negative interval disables
+// the background passes, leaving Lifecycle.Apply to run them.
+func WithLifecycleInterval(interval time.Duration) Option {
+	return func(config *app.Config) {
+		config.LifecycleInterval = interval
+	}
+}
+
+// Start initializes the emulator by configuring the database and
 // creating the application with all its services.
 //
-// Returns a pointer to an S3EGO instance that gives access to the bucket and file services.
-func Start() *S3EGO {
-	db := config.ConfigDatabase()
-	newApp := app.NewApp(db)
+// The given options customize the emulator; without options it matches the limits of Amazon S3.
+//
+// Returns a pointer to an S3EGO instance that gives access to the bucket, file, multipart and lifecycle services.
+func Start(opts ...Option) *S3EGO {
+	appConfig := app.DefaultConfig()
+	for _, opt := range opts {
+		opt(&appConfig)
+	}
+
+	db := config.ConfigDatabase(appConfig.DataSource)
+	blobs := config.ConfigBlobStore(db, appConfig.BlobDirectory())
+	newApp := app.NewApp(db, blobs, appConfig)
 
 	return &S3EGO{
-		Bucket: newApp.BucketService,
-		File:   newApp.FileService,
+		Bucket:      newApp.BucketService,
+		File:        newApp.FileService,
+		Multipart:   newApp.MultipartService,
+		Lifecycle:   newApp.LifecycleService,
+		CORS:        newApp.CORSService,
+		app:         newApp,
+		db:          db,
+		credentials: appConfig.Credentials,
+		clock:       appConfig.Clock,
 	}
 }
+
+// Handler returns the HTTP handler serving both the /bucket-emulator routes and
+// the path-style S3 REST API, so the emulator can be mounted on any server
+// (for example httptest.NewServer) and used by AWS SDK clients.
+func (s *S3EGO) Handler() http.Handler {
+	return s.app.Router
+}
+
+// Close stops applying lifecycle rules and closes the database of the emulator, flushing it to disk
+// when a data source is configured.
+func (s *S3EGO) Close() error {
+	s.app.Close()
+	return s.db.Close()
+}
+
+// PresignGet returns a presigned URL downloading the object key of bucket with GetObject,
+// valid for expires from the current time of the configured clock. endpoint is the base URL the emulator is served at,
+// such as "http://localhost:7777" or the URL of an httptest.Server.
+//
+// Returns the URL, or an error if the endpoint is invalid or expires is not between one second and seven days.
+func (s *S3EGO) PresignGet(endpoint string, bucket string, key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodGet, endpoint, bucket, key, expires)
+}
+
+// PresignPut returns a presigned URL uploading the request body as the object key of bucket
+// with PutObject, valid for expires from the current time of the configured clock. endpoint is the base URL the emulator is served at.
+//
+// Returns the URL, or an error if the endpoint is invalid or expires is not between one second and seven days.
+func (s *S3EGO) PresignPut(endpoint string, bucket string, key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodPut, endpoint, bucket, key, expires)
+}
+
+// presign signs the URL with the configured access key ID that sorts first, or with placeholder
+// credentials when authentication is disabled, since the URL is then accepted without verification.
+func (s *S3EGO) presign(method string, endpoint string, bucket string, key string, expires time.Duration) (string, error) {
+	accessKey, secretKey := "s3ego", "s3ego"
+	if len(s.credentials) > 0 {
+		keys := make([]string, 0, len(s.credentials))
+		for id := range s.credentials {
+			keys = append(keys, id)
+		}
+		accessKey = slices.Min(keys)
+		secretKey = s.credentials[accessKey]
+	}
+
+	return middleware.PresignURL(method, endpoint, bucket, key, accessKey, secretKey, expires, s.clock.Now())
+}
@@ -0,0 +1,579 @@
+package s3ego_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	s3ego "github.com/bonifacio-pedro/s3ego"
+)
+
+const (
+	testAccessKey = "AKID"
+	testSecretKey = "secret"
+)
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// postForm builds a browser-based POST upload form for bucket "bkt" whose policy only allows keys
+// under "uploads/" and the given extra fields, signed at now with the test credentials.
+func postForm(t *testing.T, now time.Time, key string, extra map[string]string, content string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	date := now.UTC().Format("20060102")
+	credential := testAccessKey + "/" + date + "/us-east-1/s3/aws4_request"
+
+	conditions := []string{
+		`{"bucket":"bkt"}`,
+		`["starts-with","$key","uploads/"]`,
+		`{"x-amz-algorithm":"AWS4-HMAC-SHA256"}`,
+		`{"x-amz-credential":"` + credential + `"}`,
+		`{"success_action_status":"201"}`,
+	}
+	for name, value := range extra {
+		conditions = append(conditions, `["eq","$`+name+`",`+quoteJSON(value)+`]`)
+	}
+	document := `{"expiration":"` + now.Add(time.Hour).UTC().Format(time.RFC3339) + `","conditions":[` + strings.Join(conditions, ",") + `]}`
+	policy := base64.StdEncoding.EncodeToString([]byte(document))
+
+	signingKey := hmacSHA256([]byte("AWS4"+testSecretKey), date)
+	signingKey = hmacSHA256(signingKey, "us-east-1")
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	fields := map[string]string{
+		"key":                   key,
+		"x-amz-algorithm":       "AWS4-HMAC-SHA256",
+		"x-amz-credential":      credential,
+		"success_action_status": "201",
+		"policy":                policy,
+		"x-amz-signature":       hex.EncodeToString(hmacSHA256(signingKey, policy)),
+	}
+	for name, value := range extra {
+		fields[name] = value
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := writer.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte(content))
+	writer.Close()
+
+	return body, writer.FormDataContentType()
+}
+
+func quoteJSON(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+func startWithCredentials(t *testing.T) (*s3ego.S3EGO, *httptest.Server, time.Time) {
+	t.Helper()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	emu := s3ego.Start(
+		s3ego.WithCredentials(testAccessKey, testSecretKey),
+		s3ego.WithClock(s3ego.NewFakeClock(now)),
+		s3ego.WithLifecycleInterval(0),
+	)
+	srv := httptest.NewServer(emu.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		emu.Close()
+	})
+
+	if _, err := emu.Bucket.New("bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	return emu, srv, now
+}
+
+func TestPostPolicyDoesNotAuthorizeSubresources(t *testing.T) {
+	emu, srv, now := startWithCredentials(t)
+
+	if _, err := emu.File.UploadWithOptions("bkt", strings.NewReader("keep me"), "secret/important", s3ego.PutOptions{}); err != nil {
+		t.Fatalf("UploadWithOptions() unexpected error: %v", err)
+	}
+
+	deleteRequest := `<Delete><Object><Key>secret/important</Key></Object></Delete>`
+	for _, target := range []string{"/bkt?delete", "/bkt?uploads", "/bkt?tagging", "/bkt?lifecycle"} {
+		body, contentType := postForm(t, now, "uploads/a.txt", nil, deleteRequest)
+		resp, err := http.Post(srv.URL+target, contentType, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST %s status = %d, want %d", target, resp.StatusCode, http.StatusForbidden)
+		}
+	}
+
+	if _, _, err := emu.File.Head("bkt", "secret/important", s3ego.GetOptions{}); err != nil {
+		t.Fatalf("Head() after policy POSTs: %v, want the object to still exist", err)
+	}
+}
+
+func TestPostUploadFields(t *testing.T) {
+	emu, srv, now := startWithCredentials(t)
+
+	key := "uploads/a b?#%.txt"
+	extra := map[string]string{
+		"content-type": "application/x-custom",
+		"tagging":      "<Tagging><TagSet><Tag><Key>team</Key><Value>storage</Value></Tag></TagSet></Tagging>",
+	}
+	body, contentType := postForm(t, now, key, extra, "hello")
+	resp, err := http.Post(srv.URL+"/bkt", contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	wantLocation := srv.URL + "/bkt/uploads/a%20b%3F%23%25.txt"
+	if location := resp.Header.Get("Location"); location != wantLocation {
+		t.Errorf("Location = %q, want %q", location, wantLocation)
+	}
+
+	var result struct {
+		Location string `xml:"Location"`
+		Key      string `xml:"Key"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decoding PostResponse: %v", err)
+	}
+	if result.Location != wantLocation || result.Key != key {
+		t.Errorf("PostResponse = %+v, want Location %q and Key %q", result, wantLocation, key)
+	}
+
+	file, _, err := emu.File.Head("bkt", key, s3ego.GetOptions{})
+	if err != nil {
+		t.Fatalf("Head() unexpected error: %v", err)
+	}
+	if file.ContentType != "application/x-custom" {
+		t.Errorf("ContentType = %q, want %q", file.ContentType, "application/x-custom")
+	}
+	if file.Tags["team"] != "storage" || len(file.Tags) != 1 {
+		t.Errorf("Tags = %v, want map[team:storage]", file.Tags)
+	}
+}
+
+func TestLifecycleExpiryWithFakeClock(t *testing.T) {
+	clock := s3ego.NewFakeClock(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	emu := s3ego.Start(s3ego.WithClock(clock), s3ego.WithLifecycleInterval(time.Hour))
+	t.Cleanup(func() { emu.Close() })
+
+	if _, err := emu.Bucket.New("bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	for _, key := range []string{"logs/a.txt", "data/b.txt"} {
+		if _, _, err := emu.File.Upload("bkt", strings.NewReader("data"), key); err != nil {
+			t.Fatalf("Upload(%q) unexpected error: %v", key, err)
+		}
+	}
+	rules := []s3ego.LifecycleRule{{ID: "logs", Status: s3ego.LifecycleEnabled, Prefix: "logs/", ExpirationDays: 1}}
+	if err := emu.Lifecycle.Put("bkt", rules); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	exists := func(key string) bool {
+		_, _, err := emu.File.Head("bkt", key, s3ego.GetOptions{})
+		return err == nil
+	}
+
+	// The object expires at the first midnight UTC after one day, 2026-01-03T00:00:00Z. Each pass
+	// has finished when Advance returns, so the objects are checked right after it.
+	clock.Advance(37 * time.Hour)
+	if !exists("logs/a.txt") {
+		t.Fatal("logs/a.txt expired before the midnight after its expiration day")
+	}
+
+	clock.Advance(time.Hour)
+	if exists("logs/a.txt") {
+		t.Error("logs/a.txt still exists after its expiry")
+	}
+	if !exists("data/b.txt") {
+		t.Error("data/b.txt expired, but no rule selects it")
+	}
+}
+
+func TestInMemoryInstancesAreIsolated(t *testing.T) {
+	a := s3ego.Start()
+	t.Cleanup(func() { a.Close() })
+	b := s3ego.Start()
+	t.Cleanup(func() { b.Close() })
+
+	if _, err := a.Bucket.New("shared-bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, _, err := a.File.Upload("shared-bkt", strings.NewReader("data"), "a.txt"); err != nil {
+		t.Fatalf("Upload() unexpected error: %v", err)
+	}
+
+	if exists, err := b.Bucket.Exists("shared-bkt"); err != nil || exists {
+		t.Errorf("Exists() on the second instance = %v, %v, want false", exists, err)
+	}
+	if _, err := b.Bucket.New("shared-bkt"); err != nil {
+		t.Fatalf("New() on the second instance unexpected error: %v", err)
+	}
+
+	reader, _, err := a.File.Get("shared-bkt", "a.txt")
+	if err != nil {
+		t.Fatalf("Get() on the first instance unexpected error: %v", err)
+	}
+	defer reader.Close()
+	if data, _ := io.ReadAll(reader); string(data) != "data" {
+		t.Errorf("Get() = %q, want %q", data, "data")
+	}
+}
+
+func TestRESTListFilesPaging(t *testing.T) {
+	emu := s3ego.Start()
+	srv := httptest.NewServer(emu.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		emu.Close()
+	})
+
+	if _, err := emu.Bucket.New("bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	for _, key := range []string{"a.txt", "docs/1.txt", "docs/2.txt", "z.txt"} {
+		if _, _, err := emu.File.Upload("bkt", strings.NewReader("data"), key); err != nil {
+			t.Fatalf("Upload(%q) unexpected error: %v", key, err)
+		}
+	}
+
+	type page struct {
+		Contents       []string
+		CommonPrefixes []string
+		MaxKeys        int
+		IsTruncated    bool
+		NextStartAfter string
+	}
+	list := func(query string) page {
+		resp, err := http.Get(srv.URL + "/bucket-emulator/list-files/bkt?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET list-files?%s status = %d, want 200", query, resp.StatusCode)
+		}
+
+		var p page
+		if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	first := list("delimiter=/&max-keys=2")
+	if strings.Join(first.Contents, ",") != "a.txt" || strings.Join(first.CommonPrefixes, ",") != "docs/" {
+		t.Errorf("first page = %v and %v, want a.txt and docs/", first.Contents, first.CommonPrefixes)
+	}
+	if first.MaxKeys != 2 || !first.IsTruncated || first.NextStartAfter != "docs/" {
+		t.Fatalf("first page MaxKeys = %d, IsTruncated = %v, NextStartAfter = %q, want 2, true and docs/",
+			first.MaxKeys, first.IsTruncated, first.NextStartAfter)
+	}
+
+	second := list("delimiter=/&max-keys=2&start-after=" + first.NextStartAfter)
+	if strings.Join(second.Contents, ",") != "z.txt" || len(second.CommonPrefixes) != 0 || second.IsTruncated {
+		t.Errorf("second page = %v and %v, IsTruncated = %v, want z.txt only", second.Contents, second.CommonPrefixes, second.IsTruncated)
+	}
+
+	if prefixed := list("prefix=docs/"); strings.Join(prefixed.Contents, ",") != "docs/1.txt,docs/2.txt" {
+		t.Errorf("prefix page = %v, want docs/1.txt and docs/2.txt", prefixed.Contents)
+	}
+
+	resp, err := http.Get(srv.URL + "/bucket-emulator/list-files/bkt?max-keys=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET list-files?max-keys=abc status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestMultipartUploadListPartsAndComplete(t *testing.T) {
+	emu := s3ego.Start(s3ego.WithMinPartSize(1))
+	srv := httptest.NewServer(emu.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		emu.Close()
+	})
+
+	if _, err := emu.Bucket.New("bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	// do sends a request to the emulator and decodes its XML response into result, if not nil.
+	do := func(method string, target string, body string, result any) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+target, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			data, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s %s status = %d, want 200: %s", method, target, resp.StatusCode, data)
+		}
+		if result != nil {
+			if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp
+	}
+
+	var initiated struct{ UploadId string }
+	do(http.MethodPost, "/bkt/a%20b.txt?uploads", "", &initiated)
+
+	etags := make([]string, 0, 3)
+	for i, data := range []string{"one", "two", "three"} {
+		resp := do(http.MethodPut, fmt.Sprintf("/bkt/a%%20b.txt?partNumber=%d&uploadId=%s", i+1, initiated.UploadId), data, nil)
+		etags = append(etags, resp.Header.Get("ETag"))
+	}
+
+	type parts struct {
+		NextPartNumberMarker int
+		IsTruncated          bool
+		Parts                []struct{ PartNumber int } `xml:"Part"`
+	}
+	var first, second parts
+	do(http.MethodGet, "/bkt/a%20b.txt?max-parts=2&uploadId="+initiated.UploadId, "", &first)
+	if len(first.Parts) != 2 || !first.IsTruncated || first.NextPartNumberMarker != 2 {
+		t.Fatalf("first ListParts page = %+v, want parts 1 and 2, truncated at 2", first)
+	}
+	do(http.MethodGet, "/bkt/a%20b.txt?part-number-marker=2&uploadId="+initiated.UploadId, "", &second)
+	if len(second.Parts) != 1 || second.Parts[0].PartNumber != 3 || second.IsTruncated {
+		t.Errorf("second ListParts page = %+v, want part 3 only", second)
+	}
+
+	complete := "<CompleteMultipartUpload>"
+	for i, etag := range etags {
+		complete += fmt.Sprintf("<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, etag)
+	}
+	complete += "</CompleteMultipartUpload>"
+
+	var completed struct{ Location, Key string }
+	do(http.MethodPost, "/bkt/a%20b.txt?uploadId="+initiated.UploadId, complete, &completed)
+	if wantLocation := srv.URL + "/bkt/a%20b.txt"; completed.Location != wantLocation || completed.Key != "a b.txt" {
+		t.Errorf("Location = %q, Key = %q, want %q and %q", completed.Location, completed.Key, wantLocation, "a b.txt")
+	}
+
+	reader, _, err := emu.File.Get("bkt", "a b.txt")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	defer reader.Close()
+	if data, _ := io.ReadAll(reader); string(data) != "onetwothree" {
+		t.Errorf("Get() = %q, want %q", data, "onetwothree")
+	}
+}
+
+func TestObjectTaggingHeaders(t *testing.T) {
+	emu := s3ego.Start()
+	srv := httptest.NewServer(emu.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		emu.Close()
+	})
+
+	if _, err := emu.Bucket.New("bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	put := func(key string, tagging string) int {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/bkt/"+key, strings.NewReader("data"))
+		req.Header.Set("x-amz-tagging", tagging)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := put("a.txt", "project=s3ego&team=storage%20team"); status != http.StatusOK {
+		t.Fatalf("PUT with x-amz-tagging status = %d, want 200", status)
+	}
+	tags := make([]string, 0, 11)
+	for i := range 11 {
+		tags = append(tags, fmt.Sprintf("k%d=v", i))
+	}
+	if status := put("b.txt", strings.Join(tags, "&")); status != http.StatusBadRequest {
+		t.Errorf("PUT with 11 tags status = %d, want 400", status)
+	}
+
+	resp, err := http.Get(srv.URL + "/bkt/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if count := resp.Header.Get("x-amz-tagging-count"); count != "2" {
+		t.Errorf("x-amz-tagging-count = %q, want 2", count)
+	}
+
+	resp, err = http.Get(srv.URL + "/bkt/a.txt?tagging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var tagging struct {
+		Tags []struct{ Key, Value string } `xml:"TagSet>Tag"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&tagging); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string, len(tagging.Tags))
+	for _, tag := range tagging.Tags {
+		got[tag.Key] = tag.Value
+	}
+	if len(got) != 2 || got["project"] != "s3ego" || got["team"] != "storage team" {
+		t.Errorf("GetObjectTagging() = %v, want project=s3ego and team=storage team", got)
+	}
+}
+
+func TestCORSPreflightAndHeaders(t *testing.T) {
+	emu := s3ego.Start()
+	srv := httptest.NewServer(emu.Handler())
+	t.Cleanup(func() {
+		srv.Close()
+		emu.Close()
+	})
+
+	if _, err := emu.Bucket.New("bkt"); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, _, err := emu.File.Upload("bkt", strings.NewReader("data"), "a.txt"); err != nil {
+		t.Fatalf("Upload() unexpected error: %v", err)
+	}
+	rules := []s3ego.CORSRule{
+		{AllowedOrigins: []string{"https://*.example.com"}, AllowedMethods: []string{"GET", "PUT"}, AllowedHeaders: []string{"x-amz-*"}, MaxAgeSeconds: 600},
+		{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+	}
+	if err := emu.CORS.Put("bkt", rules); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		headers     map[string]string
+		wantStatus  int
+		wantOrigin  string
+		wantMaxAge  string
+		wantHeaders string
+	}{
+		{
+			name:        "preflight matching the first rule",
+			method:      http.MethodOptions,
+			origin:      "https://app.example.com",
+			headers:     map[string]string{"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "X-Amz-Date, x-amz-content-sha256"},
+			wantStatus:  http.StatusOK,
+			wantOrigin:  "https://app.example.com",
+			wantMaxAge:  "600",
+			wantHeaders: "x-amz-date, x-amz-content-sha256",
+		},
+		{
+			name:       "preflight matching the wildcard rule",
+			method:     http.MethodOptions,
+			origin:     "https://other.org",
+			headers:    map[string]string{"Access-Control-Request-Method": "GET"},
+			wantStatus: http.StatusOK,
+			wantOrigin: "*",
+		},
+		{
+			name:       "preflight of a method no rule allows",
+			method:     http.MethodOptions,
+			origin:     "https://other.org",
+			headers:    map[string]string{"Access-Control-Request-Method": "DELETE"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "preflight of a header no rule allows",
+			method:     http.MethodOptions,
+			origin:     "https://app.example.com",
+			headers:    map[string]string{"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "authorization"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "preflight without a method",
+			method:     http.MethodOptions,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "actual request",
+			method:     http.MethodGet,
+			origin:     "https://app.example.com",
+			wantStatus: http.StatusOK,
+			wantOrigin: "https://app.example.com",
+			wantMaxAge: "600",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+"/bkt/a.txt", nil)
+			req.Header.Set("Origin", tt.origin)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if origin := resp.Header.Get("Access-Control-Allow-Origin"); origin != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", origin, tt.wantOrigin)
+			}
+			if maxAge := resp.Header.Get("Access-Control-Max-Age"); maxAge != tt.wantMaxAge {
+				t.Errorf("Access-Control-Max-Age = %q, want %q", maxAge, tt.wantMaxAge)
+			}
+			if headers := resp.Header.Get("Access-Control-Allow-Headers"); headers != tt.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", headers, tt.wantHeaders)
+			}
+		})
+	}
+}